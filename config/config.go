@@ -7,6 +7,7 @@ import (
 	"net/url"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
 	"github.com/ethereum/go-ethereum/common"
@@ -37,9 +38,10 @@ type LoggerConfig struct {
 }
 
 type DBConfig struct {
+	Dialect    string `toml:"dialect" env:"DB_DIALECT"` // mysql (default if empty), postgres or sqlite
 	Host       string `toml:"host" env:"DB_HOST"`
 	Port       int    `toml:"port" env:"DB_PORT"`
-	Database   string `toml:"database" env:"DB_DATABASE"`
+	Database   string `toml:"database" env:"DB_DATABASE"` // Database name, file name for sqlite (":memory:" for in-memory)
 	Username   string `toml:"username" env:"DB_USERNAME"`
 	Password   string `toml:"password" env:"DB_PASSWORD"`
 	LogQueries bool   `toml:"log_queries"`
@@ -55,6 +57,40 @@ type ChainConfig struct {
 	// use private_key_file instead
 	PrivateKey     string `toml:"private_key" env:"PRIVATE_KEY"`
 	PrivateKeyFile string `toml:"private_key_file" env:"PRIVATE_KEY_FILE"`
+
+	// Nodes used by indexers with failover, node_url and api_key are used if empty
+	Nodes []NodeConfig `toml:"nodes"`
+	// Interval of checking the last accepted index of nodes (0 = default)
+	NodeCheckInterval time.Duration `toml:"node_check_interval"`
+	// Switch to another node if the current one is more than this number of containers behind
+	MaxNodeLag uint64 `toml:"max_node_lag"`
+
+	// Retrying and rate limiting of requests to nodes
+	Requests RequestConfig `toml:"requests"`
+
+	// If set, all requests to nodes and their responses are recorded to files in this directory
+	RecordDir string `toml:"record_dir"`
+}
+
+type RequestConfig struct {
+	MaxRetries  int           `toml:"max_retries"`  // Number of retries of a failed request, 0 = no retries
+	BackoffBase time.Duration `toml:"backoff_base"` // Delay before the first retry, doubled for each next retry
+	BackoffMax  time.Duration `toml:"backoff_max"`  // Maximal delay between retries
+	RateLimit   float64       `toml:"rate_limit"`   // Maximal number of requests per second in the process, 0 = no limit
+	RateBurst   int           `toml:"rate_burst"`   // Maximal number of requests sent at once (token bucket size)
+}
+
+type NodeConfig struct {
+	URL    string `toml:"url"`
+	ApiKey string `toml:"api_key"`
+}
+
+// Return configured nodes; the node given by node_url and api_key if there is no node list
+func (cfg *ChainConfig) NodeList() []NodeConfig {
+	if len(cfg.Nodes) > 0 {
+		return cfg.Nodes
+	}
+	return []NodeConfig{{URL: cfg.NodeURL, ApiKey: cfg.ApiKey}}
 }
 
 func (cfg ChainConfig) GetPrivateKey() (string, error) {
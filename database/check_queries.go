@@ -0,0 +1,117 @@
+package database
+
+import (
+	"gorm.io/gorm"
+)
+
+// Queries of the integrity checker, each returns at most limit rows
+
+// Input whose output is not stored
+type UnmatchedInput struct {
+	TxID    string
+	InIdx   uint32
+	OutTxID string
+	OutIdx  uint32
+}
+
+// Range of heights (or indexes) stored in a table
+type HeightRange struct {
+	Min   uint64
+	Max   uint64
+	Count int64
+}
+
+// Returns heights h of rows in table such that h+1 is not stored and h is below the maximal
+// stored height, i.e., the last heights before gaps
+func FetchHeightGaps(db *gorm.DB, table string, column string, limit int) ([]uint64, error) {
+	var heights []uint64
+	err := db.Table(table+" AS b").
+		Joins("LEFT JOIN "+table+" AS n ON n."+column+" = b."+column+" + 1").
+		Where("n.id IS NULL AND b."+column+" < (SELECT MAX("+column+") FROM "+table+")").
+		Order("b."+column).Limit(limit).
+		Pluck("b."+column, &heights).Error
+	return heights, err
+}
+
+// Returns heights stored in more than one row of table
+func FetchDuplicateHeights(db *gorm.DB, table string, column string, limit int) ([]uint64, error) {
+	var heights []uint64
+	err := db.Table(table).
+		Group(column).Having("COUNT(*) > 1").
+		Order(column).Limit(limit).
+		Pluck(column, &heights).Error
+	return heights, err
+}
+
+// Returns the range of heights stored in table, zero range if the table is empty
+func FetchHeightRange(db *gorm.DB, table string, column string) (HeightRange, error) {
+	var r HeightRange
+	err := db.Table(table).
+		Select("COALESCE(MIN(" + column + "), 0) AS min, COALESCE(MAX(" + column + "), 0) AS max, COUNT(*) AS count").
+		Scan(&r).Error
+	return r, err
+}
+
+// Returns IDs of P-chain transactions whose block is not stored, genesis transactions
+// are not in blocks
+func FetchPChainTxsWithoutBlock(db *gorm.DB, limit int) ([]string, error) {
+	var txIDs []string
+	err := db.Table("p_chain_txes AS t").
+		Joins("LEFT JOIN p_chain_blocks AS b ON b.height = t.block_height").
+		Where("b.id IS NULL AND t.block_type <> ?", PChainGenesisBlock).
+		Order("t.id").Limit(limit).
+		Pluck("t.tx_id", &txIDs).Error
+	return txIDs, err
+}
+
+// Returns P-chain inputs spending outputs which are not stored. Imported inputs spend outputs
+// of other chains and are not checked. Inputs spending outputs of skipOutTxID are not checked
+// if it is not empty (genesis outputs if the genesis is not loaded).
+func FetchUnmatchedPChainInputs(db *gorm.DB, limit int, skipOutTxID string) ([]UnmatchedInput, error) {
+	var ins []UnmatchedInput
+	query := db.Table("p_chain_tx_inputs AS i").
+		Joins("LEFT JOIN p_chain_tx_outputs AS o ON o.tx_id = i.out_tx_id AND o.idx = i.out_idx").
+		Where("o.id IS NULL AND i.type <> ?", PChainImportedInput)
+	if len(skipOutTxID) > 0 {
+		query = query.Where("i.out_tx_id <> ?", skipOutTxID)
+	}
+	err := query.
+		Select("DISTINCT i.tx_id, i.in_idx, i.out_tx_id, i.out_idx").
+		Order("i.tx_id, i.in_idx").Limit(limit).
+		Scan(&ins).Error
+	return ins, err
+}
+
+// Returns X-chain inputs spending outputs which are not stored
+func FetchUnmatchedXChainInputs(db *gorm.DB, limit int) ([]UnmatchedInput, error) {
+	var ins []UnmatchedInput
+	err := db.Table("x_chain_tx_inputs AS i").
+		Joins("LEFT JOIN x_chain_tx_outputs AS o ON o.tx_id = i.out_tx_id AND o.idx = i.out_idx").
+		Where("o.id IS NULL").
+		Select("DISTINCT i.tx_id, i.in_idx, i.out_tx_id, i.out_idx").
+		Order("i.tx_id, i.in_idx").Limit(limit).
+		Scan(&ins).Error
+	return ins, err
+}
+
+// Returns IDs of staking transactions without start or end time
+func FetchPChainStakesWithoutTimes(db *gorm.DB, limit int) ([]string, error) {
+	var txIDs []string
+	err := db.Model(&PChainTx{}).
+		Where("type IN ? AND (start_time IS NULL OR end_time IS NULL)", PChainStakingTransactions).
+		Order("id").Limit(limit).
+		Pluck("tx_id", &txIDs).Error
+	return txIDs, err
+}
+
+// Returns reward transactions whose staking transaction is not stored
+func FetchPChainRewardTxsWithoutStake(db *gorm.DB, limit int) ([]PChainTx, error) {
+	var txs []PChainTx
+	err := db.Table("p_chain_txes AS r").
+		Joins("LEFT JOIN p_chain_txes AS s ON s.tx_id = r.reward_tx_id").
+		Where("s.id IS NULL AND r.type = ?", PChainRewardValidatorTx).
+		Select("r.tx_id, r.reward_tx_id, r.block_height").
+		Order("r.id").Limit(limit).
+		Scan(&txs).Error
+	return txs, err
+}
@@ -26,6 +26,19 @@ type State struct {
 	Updated        time.Time
 }
 
+// Record written by an indexer when the node's index does not continue the history
+// stored in the database (e.g., the node was resynced or replaced behind a load balancer)
+type IndexerDiagnostic struct {
+	BaseEntity
+	StateName      string                `gorm:"type:varchar(50);index"` // Name of the state of the indexer
+	Type           IndexerDiagnosticType `gorm:"type:varchar(30)"`
+	Index          uint64                // Index of the container that was checked
+	StoredID       string                `gorm:"type:varchar(50)"` // Container ID stored in the database
+	ChainID        string                `gorm:"type:varchar(50)"` // Container ID returned by the node
+	LastChainIndex uint64                // Last accepted index reported by the node
+	Timestamp      time.Time
+}
+
 // Abstact entity, common columns for X-chain and P-chain transaction inputs
 type TxInput struct {
 	BaseEntity
@@ -44,4 +57,28 @@ type TxOutput struct {
 	Amount  uint64
 	Idx     uint32
 	Address string `gorm:"type:varchar(60);index"`
+
+	AssetID           string `gorm:"type:varchar(50)"` // Asset ID of the output
+	Locktime          uint64 // Output cannot be spent before this unix time
+	Threshold         uint32 // Number of owner signatures required to spend the output
+	OwnerGroup        string `gorm:"type:varchar(50);index"` // Identifier of the owner set (addresses and threshold)
+	StakeableLocktime uint64 // Lock time of stakeable locked outputs, 0 if the output is not locked
+}
+
+// Table with transfers of funds between the P-chain and the X-chain or C-chain. A transfer is
+// an output exported by ExportTx of the source chain and the input of ImportTx of the
+// destination chain spending it, identified by the exported UTXO (export tx ID and index).
+// Export or import columns are empty until the corresponding transaction is indexed.
+type CrossChainTransfer struct {
+	BaseEntity
+	ExportTxID       string     `gorm:"type:varchar(50);not null;uniqueIndex:idx_transfer_utxo"` // ExportTx ID
+	OutIdx           uint32     `gorm:"uniqueIndex:idx_transfer_utxo"`                           // Index of the exported UTXO
+	SourceChain      string     `gorm:"type:varchar(50)"`
+	DestinationChain string     `gorm:"type:varchar(50)"`
+	Amount           uint64     // Exported amount
+	Address          string     `gorm:"type:varchar(60);index"` // First owner address of the exported output
+	ExportTime       *time.Time `gorm:"index"`
+	ImportTxID       *string    `gorm:"type:varchar(50);index"` // ImportTx ID, null if not imported
+	ImportTime       *time.Time
+	Status           CrossChainTransferStatus `gorm:"type:varchar(20);index"`
 }
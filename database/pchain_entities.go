@@ -12,7 +12,7 @@ type PChainTx struct {
 	BlockID                string          `gorm:"type:varchar(50);not null"` // Block ID
 	BlockType              PChainBlockType `gorm:"type:varchar(20)"`          // Block type (proposal, accepted, rejected, etc.)
 	RewardTxID             string          `gorm:"type:varchar(50)"`          // Referred transaction id in case of reward validator tx
-	BlockHeight            uint64          `gorm:"index"`                     // Block height
+	BlockHeight            uint64          `gorm:"index"`                     // Block height, references PChainBlock
 	Timestamp              time.Time       // Time when indexed
 	ChainID                string          `gorm:"type:varchar(50)"` // Filled in case of export or import transaction
 	NodeID                 string          `gorm:"type:varchar(50)"` // Filled in case of add delegator or validator transaction
@@ -20,18 +20,153 @@ type PChainTx struct {
 	EndTime                *time.Time      `gorm:"index"`            // End time of validator or delegator (when NodeID is not null)
 	Time                   *time.Time      // Chain time (in case of advance time transaction)
 	Weight                 uint64          // Weight (stake amount) (when NodeID is not null)
-	RewardsOwner           string          `gorm:"type:varchar(60)"`  // Rewards owner address (in case of add delegator or validator transaction)
-	DelegationRewardsOwner string          `gorm:"type:varchar(60)"`  // Delegation rewards owner address (in case of add validator transaction)
+	RewardsOwner           string          `gorm:"type:varchar(60)"`  // Rewards owner address (in case of add delegator or validator transaction with a single owner address)
+	DelegationRewardsOwner string          `gorm:"type:varchar(60)"`  // Delegation rewards owner address (in case of add validator transaction with a single owner address)
 	SubnetID               string          `gorm:"type:varchar(50)"`  // Subnet ID (from Cortina update on, will be empty for pre-Cortina)
 	SignerPublicKey        *string         `gorm:"type:varchar(256)"` // Signer public key (for PermissionlessStaker transactions)
 	Memo                   string          `gorm:"type:varchar(256)"`
-	Bytes                  []byte          `gorm:"type:mediumblob"`
 	FeePercentage          uint32          // Fee percentage (in case of add validator transaction)
 	BlockTime              *time.Time      `gorm:"index"` // Block time, non-null from Banff block activation on (Avalanche 1.9.0)
 }
 
+// Table with indexed data for a P-chain block, including blocks without transactions
+// (commit and abort blocks)
+type PChainBlock struct {
+	BaseEntity
+	Height    uint64          `gorm:"unique"`                           // Block height
+	BlockID   string          `gorm:"type:varchar(50);unique;not null"` // Block ID
+	ParentID  string          `gorm:"type:varchar(50)"`                 // Parent block ID
+	Type      PChainBlockType `gorm:"type:varchar(20)"`                 // Block type (proposal, standard, commit, abort)
+	BlockTime *time.Time      // Block time, non-null from Banff block activation on (Avalanche 1.9.0)
+	Timestamp time.Time       // Time when indexed
+	Bytes     []byte          `gorm:"size:16777215"` // mediumblob on MySQL, bytea on PostgreSQL
+}
+
+// Table with the state of P-chain transaction outputs, one row per row of p_chain_tx_outputs.
+// Stake outputs are returned to their owners (with the same output ID) by the RewardValidatorTx
+// of the staking transaction, reward outputs are created by it.
+type PChainUTXO struct {
+	BaseEntity
+	TxID          string           `gorm:"type:varchar(50);not null;uniqueIndex:idx_utxo_output"` // Transaction ID of the output
+	Idx           uint32           `gorm:"uniqueIndex:idx_utxo_output"`                           // Output index
+	Address       string           `gorm:"type:varchar(60);uniqueIndex:idx_utxo_output;index"`    // Output address
+	Amount        uint64           // Output amount
+	Type          PChainOutputType `gorm:"type:varchar(20)"` // Output type
+	BlockHeight   uint64           // Height of the block creating the output
+	UnstakeHeight *uint64          // Height of the block returning the stake (stake outputs only)
+	SpentTxID     *string          `gorm:"type:varchar(50)"` // Transaction spending the output, null if unspent
+	SpentHeight   *uint64          `gorm:"index"`            // Height of the block spending the output
+
+	AssetID           string `gorm:"type:varchar(50)"` // Asset ID of the output
+	Locktime          uint64 // Output cannot be spent before this unix time
+	Threshold         uint32 // Number of owner signatures required to spend the output
+	OwnerGroup        string `gorm:"type:varchar(50);index"` // Identifier of the owner set (addresses and threshold)
+	StakeableLocktime uint64 // Lock time of stakeable locked outputs
+}
+
+// Table with rewards paid by RewardValidatorTx, one row per rewarded staking transaction.
+// Rewards are split into the reward for the stake (validation reward of a validator or the
+// delegator's share of a delegation reward) and the delegation fee paid to the validator.
+type PChainStakeReward struct {
+	BaseEntity
+	StakeTxID            string       `gorm:"type:varchar(50);unique;not null"` // Rewarded staking transaction
+	RewardTxID           string       `gorm:"type:varchar(50);unique;not null"` // RewardValidatorTx ID
+	StakeTxType          PChainTxType `gorm:"type:varchar(40)"`                 // Type of the staking transaction
+	NodeID               string       `gorm:"type:varchar(50);index"`           // Node ID of the validator
+	Amount               uint64       // Total reward
+	StakerReward         uint64       // Reward for the stake
+	StakerRewardAddress  string       `gorm:"type:varchar(60);index"` // Recipient of the reward for the stake
+	DelegationFee        uint64       // Delegation fee paid to the validator
+	DelegationFeeAddress string       `gorm:"type:varchar(60);index"` // Recipient of the delegation fee
+	BlockHeight          uint64       // Height of the block with the RewardValidatorTx
+	Time                 time.Time    `gorm:"index"` // Block time (end time of the stake before Banff blocks)
+}
+
+// Table with rewards owners of staking transactions, one row per owner address. Validators
+// have an owner of the validation reward and an owner of delegation fees, delegators have an
+// owner of their share of the reward.
+type PChainRewardsOwner struct {
+	BaseEntity
+	TxID       string                 `gorm:"type:varchar(50);not null;uniqueIndex:idx_rewards_owner"` // Staking transaction ID
+	Type       PChainRewardsOwnerType `gorm:"type:varchar(20);uniqueIndex:idx_rewards_owner"`          // Rewards owner type (stake reward or delegation fee)
+	AddressIdx uint32                 `gorm:"uniqueIndex:idx_rewards_owner"`                           // Index of the address in the owner addresses
+	Address    string                 `gorm:"type:varchar(60);index"`                                  // Owner address
+	Threshold  uint32                 // Number of owner signatures required to spend rewards
+	Locktime   uint64                 // Rewards cannot be spent before this unix time
+}
+
+// Table with subnets created by CreateSubnetTx
+type PChainSubnet struct {
+	BaseEntity
+	SubnetID       string     `gorm:"type:varchar(50);unique;not null"` // Subnet ID (ID of the CreateSubnetTx)
+	OwnerThreshold uint32     // Number of owner signatures required to manage the subnet
+	OwnerLocktime  uint64     // Owner locktime (unix time)
+	OwnerAddresses string     `gorm:"type:varchar(1024)"` // Comma-separated owner addresses
+	BlockHeight    uint64     // Block height
+	BlockTime      *time.Time // Block time, non-null from Banff block activation on (Avalanche 1.9.0)
+}
+
+// Table with chains created by CreateChainTx
+type PChainChain struct {
+	BaseEntity
+	ChainID     string     `gorm:"type:varchar(50);unique;not null"` // Chain ID (ID of the CreateChainTx)
+	SubnetID    string     `gorm:"type:varchar(50);index"`           // Subnet validating the chain
+	ChainName   string     `gorm:"type:varchar(256)"`                // Chain name (need not be unique)
+	VMID        string     `gorm:"type:varchar(50)"`                 // ID of the VM running the chain
+	FxIDs       string     `gorm:"type:varchar(512)"`                // Comma-separated IDs of feature extensions
+	GenesisHash string     `gorm:"type:varchar(64)"`                 // SHA-256 hash of the genesis data (hex)
+	BlockHeight uint64     // Block height
+	BlockTime   *time.Time // Block time, non-null from Banff block activation on (Avalanche 1.9.0)
+}
+
+// Table with subnets converted to L1s (ConvertSubnetToL1Tx)
+type PChainL1Conversion struct {
+	BaseEntity
+	TxID           string    `gorm:"type:varchar(50);unique;not null"` // ConvertSubnetToL1Tx ID
+	SubnetID       string    `gorm:"type:varchar(50);unique;not null"` // Converted subnet ID
+	ManagerChainID string    `gorm:"type:varchar(50)"`                 // Chain of the validator manager
+	ManagerAddress string    `gorm:"type:varchar(100)"`                // Address of the validator manager (hex)
+	BlockHeight    uint64    // Block height
+	Time           time.Time // Block time
+}
+
+// Table with L1 validators, created by ConvertSubnetToL1Tx (initial validators) or
+// RegisterL1ValidatorTx. Changes are stored in PChainL1ValidatorChange.
+type PChainL1Validator struct {
+	BaseEntity
+	ValidationID                   string    `gorm:"type:varchar(50);unique;not null"` // Validation ID
+	SubnetID                       string    `gorm:"type:varchar(50);index"`           // L1 (subnet) ID
+	NodeID                         string    `gorm:"type:varchar(50);index"`           // Node ID
+	TxID                           string    `gorm:"type:varchar(50);index"`           // Transaction adding the validator
+	Weight                         uint64    // Initial weight
+	Balance                        uint64    // Initial balance
+	BLSPublicKey                   string    `gorm:"type:varchar(256)"` // Compressed BLS public key (hex)
+	RemainingBalanceOwnerThreshold uint32    // Threshold of the remaining balance owner
+	RemainingBalanceOwnerAddresses string    `gorm:"type:varchar(1024)"` // Comma-separated addresses of the remaining balance owner
+	DeactivationOwnerThreshold     uint32    // Threshold of the deactivation owner
+	DeactivationOwnerAddresses     string    `gorm:"type:varchar(1024)"` // Comma-separated addresses of the deactivation owner
+	BlockHeight                    uint64    // Block height
+	StartTime                      time.Time `gorm:"index"` // Block time of the transaction adding the validator
+}
+
+// Table with changes of L1 validators (SetL1ValidatorWeightTx, IncreaseL1ValidatorBalanceTx,
+// DisableL1ValidatorTx). Changes are not applied to PChainL1Validator, so they can be indexed
+// in any order (e.g., by backfill).
+type PChainL1ValidatorChange struct {
+	BaseEntity
+	TxID         string                      `gorm:"type:varchar(50);unique;not null"` // Transaction ID
+	ValidationID string                      `gorm:"type:varchar(50);index"`           // Validation ID
+	Type         PChainL1ValidatorChangeType `gorm:"type:varchar(20)"`                 // Change type
+	Weight       uint64                      // New weight (weight change, 0 removes the validator)
+	Nonce        uint64                      // Nonce of the weight change message
+	Balance      uint64                      // Added balance (balance increase)
+	BlockHeight  uint64                      // Block height
+	Time         time.Time                   `gorm:"index"` // Block time
+}
+
 type PChainTxInput struct {
 	TxInput
+	Type PChainInputType `gorm:"type:varchar(20)"` // Transaction input type (default or imported from another chain)
 }
 
 type PChainTxOutput struct {
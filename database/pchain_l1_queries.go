@@ -0,0 +1,89 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// State of an L1 validator at a given time
+type PChainL1ValidatorState struct {
+	PChainL1Validator
+	CurrentWeight uint64 // Weight after the last weight change
+	TotalBalance  uint64 // Initial balance and all balance increases, fees are not deducted
+	Active        bool   // Validator has a balance and was not disabled after the last balance increase
+}
+
+func CreatePChainL1Entities(db *gorm.DB, conversions []*PChainL1Conversion, validators []*PChainL1Validator, changes []*PChainL1ValidatorChange) error {
+	if len(conversions) > 0 { // attempt to create from an empty slice returns error
+		err := db.Create(conversions).Error
+		if err != nil {
+			return err
+		}
+	}
+	if len(validators) > 0 {
+		err := db.Create(validators).Error
+		if err != nil {
+			return err
+		}
+	}
+	if len(changes) > 0 {
+		return db.Create(changes).Error
+	}
+	return nil
+}
+
+// Returns validators of the L1 subnetID at time t which have a non-zero weight and are active.
+// Validators deactivated because their balance was spent on fees are not detected.
+func FetchActivePChainL1Validators(db *gorm.DB, subnetID string, t time.Time) ([]*PChainL1ValidatorState, error) {
+	var validators []PChainL1Validator
+	err := db.Where("subnet_id = ?", subnetID).
+		Where("start_time <= ?", t).
+		Order("block_height, id").
+		Find(&validators).Error
+	if err != nil || len(validators) == 0 {
+		return nil, err
+	}
+
+	states := make(map[string]*PChainL1ValidatorState, len(validators))
+	validationIDs := make([]string, len(validators))
+	for i, v := range validators {
+		states[v.ValidationID] = &PChainL1ValidatorState{
+			PChainL1Validator: v,
+			CurrentWeight:     v.Weight,
+			TotalBalance:      v.Balance,
+			Active:            v.Balance > 0,
+		}
+		validationIDs[i] = v.ValidationID
+	}
+
+	var changes []PChainL1ValidatorChange
+	err = db.Where("validation_id IN ?", validationIDs).
+		Where("time <= ?", t).
+		Order("block_height, id").
+		Find(&changes).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range changes {
+		state := states[c.ValidationID]
+		switch c.Type {
+		case PChainL1ValidatorWeightChange:
+			state.CurrentWeight = c.Weight
+		case PChainL1ValidatorBalanceIncrease:
+			state.TotalBalance += c.Balance
+			state.Active = true
+		case PChainL1ValidatorDisable:
+			state.Active = false
+		}
+	}
+
+	result := make([]*PChainL1ValidatorState, 0, len(validators))
+	for _, v := range validators {
+		state := states[v.ValidationID]
+		if state.Active && state.CurrentWeight > 0 {
+			result = append(result, state)
+		}
+	}
+	return result, nil
+}
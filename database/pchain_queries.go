@@ -19,8 +19,51 @@ func FetchPChainTxOutputs(db *gorm.DB, ids []string) ([]PChainTxOutput, error) {
 	return txs, err
 }
 
-func CreatePChainEntities(db *gorm.DB, txs []*PChainTx, ins []*PChainTxInput, outs []*PChainTxOutput) error {
-	if len(txs) > 0 { // attempt to create from an empty slice returns error
+// Returns the ID of the block at the given height, empty string if there is no
+// such block in the database
+func FetchPChainBlockIDAtHeight(db *gorm.DB, height uint64) (string, error) {
+	var block PChainBlock
+	err := db.Where("height = ?", height).Select("block_id").First(&block).Error
+	if err == nil {
+		return block.BlockID, nil
+	} else if err == gorm.ErrRecordNotFound {
+		return "", nil
+	} else {
+		return "", err
+	}
+}
+
+// Returns the block with the highest stored height, nil if no block is stored
+func FetchPChainLastBlock(db *gorm.DB) (*PChainBlock, error) {
+	var blocks []PChainBlock
+	err := db.Order("height DESC").Limit(1).Find(&blocks).Error
+	if err != nil || len(blocks) == 0 {
+		return nil, err
+	}
+	return &blocks[0], nil
+}
+
+// Returns the block at the given height, nil if there is no such block in the database
+func FetchPChainBlock(db *gorm.DB, height uint64) (*PChainBlock, error) {
+	var block PChainBlock
+	err := db.Where("height = ?", height).First(&block).Error
+	if err == nil {
+		return &block, nil
+	} else if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	} else {
+		return nil, err
+	}
+}
+
+func CreatePChainEntities(db *gorm.DB, blocks []*PChainBlock, txs []*PChainTx, ins []*PChainTxInput, outs []*PChainTxOutput) error {
+	if len(blocks) > 0 { // attempt to create from an empty slice returns error
+		err := db.Create(blocks).Error
+		if err != nil {
+			return err
+		}
+	}
+	if len(txs) > 0 {
 		err := db.Create(txs).Error
 		if err != nil {
 			return err
@@ -38,6 +81,19 @@ func CreatePChainEntities(db *gorm.DB, txs []*PChainTx, ins []*PChainTxInput, ou
 	return nil
 }
 
+func CreatePChainSubnetEntities(db *gorm.DB, subnets []*PChainSubnet, chains []*PChainChain) error {
+	if len(subnets) > 0 { // attempt to create from an empty slice returns error
+		err := db.Create(subnets).Error
+		if err != nil {
+			return err
+		}
+	}
+	if len(chains) > 0 {
+		return db.Create(chains).Error
+	}
+	return nil
+}
+
 // Returns a list of transaction ids initiating a create validator transaction or a create delegation transaction
 // - if address is not empty, only returns transactions where the given address is the sender of the transaction
 // - if time is not zero, only returns transactions where the validatot time or delegation time contains the given time
@@ -65,7 +121,7 @@ func FetchPChainStakingTransactions(
 		offset = 0
 	}
 
-	query := db.Where("type IN ?", txTypes)
+	query := db.Where("p_chain_txes.type IN ?", txTypes)
 	if len(nodeID) > 0 {
 		query = query.Where("node_id = ?", nodeID)
 	}
@@ -108,7 +164,7 @@ func FetchPChainStakingData(
 		"WHERE start_time <= ? AND end_time >= ? AND type IN ? " +
 		"ORDER BY id LIMIT ? OFFSET ?) "
 	query := db.Raw(withSubquery+
-		"SELECT p.*, GROUP_CONCAT(DISTINCT i.address) AS input_address "+
+		"SELECT p.*, "+groupConcatDistinct(db, "i.address")+" AS input_address "+
 		"FROM p_chain_txes p "+
 		"LEFT JOIN p_chain_tx_inputs i ON i.tx_id = p.tx_id "+
 		"WHERE p.id IN (SELECT id FROM matched_ids) "+
@@ -191,11 +247,12 @@ func FetchPChainTxData(db *gorm.DB, txID string, address string) (*PChainTxData,
 	var tx PChainTxData
 	err := db.Table("p_chain_txes").
 		Joins("left join p_chain_tx_inputs as inputs on inputs.tx_id = p_chain_txes.tx_id").
+		Joins("left join p_chain_blocks as blocks on blocks.height = p_chain_txes.block_height").
 		Where("p_chain_txes.tx_id = ?", txID).
 		Where("inputs.address = ?", address).
-		Group("p_chain_txes.id").
-		// any_value is used to avoid only_full_group_by error
-		Select("p_chain_txes.*, any_value(inputs.address) as input_address, min(inputs.in_idx) as input_index").
+		// Columns of the block are determined by its ID, inputs have the same address
+		Group("p_chain_txes.id, blocks.id").
+		Select("p_chain_txes.*, min(inputs.address) as input_address, min(inputs.in_idx) as input_index, blocks.bytes as block_bytes").
 		First(&tx).Error
 	if err == nil {
 		return &tx, nil
@@ -210,33 +267,41 @@ type PChainTxData struct {
 	PChainTx
 	InputAddress string
 	InputIndex   uint32
+	BlockBytes   []byte // Bytes of the block containing the transaction, only set by FetchPChainTxData
 }
 
-// Find P-chain transaction in given block height
+// Find P-chain transaction in given block height. The transaction must be the first transaction
+// of the block, its input address is the address of its first input.
 // Returns transaction and true if found, nil and true if block was found,
 // nil and false if block height does not exist.
 func FindPChainTxInBlockHeight(db *gorm.DB,
 	txID string,
 	height uint32,
 ) (*PChainTxData, bool, error) {
+	block, err := FetchPChainBlock(db, uint64(height))
+	if err != nil || block == nil {
+		return nil, false, err
+	}
+
 	var txs []PChainTxData
-	// err := db.Where(&PChainTx{BlockHeight: height}).Find(&txs).Error
-	err := db.Table("p_chain_txes").
+	err = db.Table("p_chain_txes").
 		Joins("left join p_chain_tx_inputs as inputs on inputs.tx_id = p_chain_txes.tx_id").
 		Where("p_chain_txes.block_height = ?", height).
 		Select("p_chain_txes.*, inputs.address as input_address, inputs.in_idx as input_index").
+		Order("p_chain_txes.id").
+		Order("inputs.in_idx").
 		Scan(&txs).Error
 	if err != nil {
 		return nil, false, err
 	}
 	if len(txs) == 0 {
-		return nil, false, nil
+		return nil, true, nil
 	}
 	tx := &txs[0]
 	if *tx.TxID != txID {
 		return nil, true, nil
 	}
-	return &txs[0], true, nil
+	return tx, true, nil
 }
 
 func FetchPChainVotingData(db *gorm.DB, from time.Time, to time.Time) ([]PChainTxData, error) {
@@ -245,7 +310,7 @@ func FetchPChainVotingData(db *gorm.DB, from time.Time, to time.Time) ([]PChainT
 	query := db.
 		Table("p_chain_txes").
 		Joins("left join p_chain_tx_inputs as inputs on inputs.tx_id = p_chain_txes.tx_id").
-		Where("type IN ?", PChainStakingTransactions).
+		Where("p_chain_txes.type IN ?", PChainStakingTransactions).
 		Where("start_time >= ?", from).Where("start_time < ?", to).
 		Select("p_chain_txes.*, inputs.address as input_address, inputs.in_idx as input_index").
 		Scan(&data)
@@ -291,3 +356,26 @@ func FetchNodeStakingIntervals(db *gorm.DB, txTypes []PChainTxType, startTime ti
 		Find(&txs).Error
 	return txs, err
 }
+
+// Returns inputs spending outputs of transactions with ids txIDs
+func FetchPChainInputsSpending(db *gorm.DB, txIDs []string) ([]*PChainTxInput, error) {
+	var ins []*PChainTxInput
+	err := db.Where("out_tx_id IN ?", txIDs).Find(&ins).Error
+	return ins, err
+}
+
+// Returns reward transactions of staking transactions with ids stakeTxIDs
+func FetchPChainRewardTxs(db *gorm.DB, stakeTxIDs []string) ([]*PChainTx, error) {
+	var txs []*PChainTx
+	err := db.Where("type = ? AND reward_tx_id IN ?", PChainRewardValidatorTx, stakeTxIDs).
+		Select("tx_id", "type", "reward_tx_id", "block_height").
+		Find(&txs).Error
+	return txs, err
+}
+
+// Set the address of inputs spending the output
+func UpdatePChainInputAddresses(db *gorm.DB, outTxID string, outIdx uint32, address string) error {
+	return db.Model(&PChainTxInput{}).
+		Where("out_tx_id = ? AND out_idx = ?", outTxID, outIdx).
+		Update("address", address).Error
+}
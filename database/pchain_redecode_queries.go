@@ -0,0 +1,88 @@
+package database
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Queries used to rebuild P-chain tables from stored blocks
+
+// Returns stored blocks with heights in [from, to] ordered by height, at most limit blocks
+func FetchPChainBlocks(db *gorm.DB, from uint64, to uint64, limit int) ([]*PChainBlock, error) {
+	var blocks []*PChainBlock
+	err := db.Where("height BETWEEN ? AND ?", from, to).
+		Order("height").Limit(limit).
+		Find(&blocks).Error
+	return blocks, err
+}
+
+// Returns reward outputs of staking transactions with ids stakeTxIDs
+func FetchPChainRewardOutputs(db *gorm.DB, stakeTxIDs []string) ([]*PChainTxOutput, error) {
+	var outs []*PChainTxOutput
+	err := db.Where("type = ? AND tx_id IN ?", PChainRewardOutput, stakeTxIDs).
+		Order("id").
+		Find(&outs).Error
+	return outs, err
+}
+
+// Returns IDs of staking transactions among stakeTxIDs with a stored reward
+func FetchPChainRewardedStakeTxIDs(db *gorm.DB, stakeTxIDs []string) ([]string, error) {
+	var txIDs []string
+	err := db.Model(&PChainStakeReward{}).
+		Where("stake_tx_id IN ?", stakeTxIDs).
+		Pluck("stake_tx_id", &txIDs).Error
+	return txIDs, err
+}
+
+// Returns names of columns of the p_chain_txes table
+func PChainTxColumns(db *gorm.DB) ([]string, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(&PChainTx{}); err != nil {
+		return nil, err
+	}
+	return stmt.Schema.DBNames, nil
+}
+
+// Create transactions, columns of already stored transactions (matched by transaction ID)
+// are updated
+func UpsertPChainTxs(db *gorm.DB, txs []*PChainTx, columns []string) error {
+	if len(txs) == 0 { // attempt to create from an empty slice returns error
+		return nil
+	}
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "tx_id"}},
+		DoUpdates: clause.AssignmentColumns(columns),
+	}).Create(txs).Error
+}
+
+// Delete rows of model whose column has one of values
+func DeletePChainRows(db *gorm.DB, model interface{}, column string, values []string) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return db.Where(column+" IN ?", values).Delete(model).Error
+}
+
+// Delete inputs of transactions with ids txIDs having one of inputTypes
+func DeletePChainTxInputs(db *gorm.DB, txIDs []string, inputTypes []PChainInputType) error {
+	if len(txIDs) == 0 {
+		return nil
+	}
+	return db.Where("tx_id IN ? AND type IN ?", txIDs, inputTypes).Delete(&PChainTxInput{}).Error
+}
+
+// Delete outputs stored in blocks of transactions with ids txIDs and reward outputs of staking
+// transactions with ids stakeTxIDs. Reward outputs have the ID of the staking transaction, but
+// they are created by its reward transaction.
+func DeletePChainTxOutputs(db *gorm.DB, txIDs []string, stakeTxIDs []string) error {
+	if len(txIDs) > 0 {
+		err := db.Where("tx_id IN ? AND type <> ?", txIDs, PChainRewardOutput).Delete(&PChainTxOutput{}).Error
+		if err != nil {
+			return err
+		}
+	}
+	if len(stakeTxIDs) > 0 {
+		return db.Where("tx_id IN ? AND type = ?", stakeTxIDs, PChainRewardOutput).Delete(&PChainTxOutput{}).Error
+	}
+	return nil
+}
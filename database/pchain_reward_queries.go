@@ -0,0 +1,139 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Rewards of stakes of a node
+type PChainNodeRewards struct {
+	NodeID          string
+	ValidatorReward uint64 // Rewards for validator stakes
+	DelegatorReward uint64 // Delegators' shares of rewards for delegator stakes
+	DelegationFee   uint64 // Delegation fees paid to the validator
+	Rewards         int    // Number of rewarded stakes
+}
+
+// Staking transaction with the type, threshold and locktime of its rewards owner
+type PChainRewardsOwnerStake struct {
+	PChainTx
+	OwnerType PChainRewardsOwnerType
+	Threshold uint32
+	Locktime  uint64
+}
+
+func CreatePChainRewardsOwners(db *gorm.DB, owners []*PChainRewardsOwner) error {
+	if len(owners) > 0 { // attempt to create from an empty slice returns error
+		return db.Create(owners).Error
+	}
+	return nil
+}
+
+// Returns staking transactions whose rewards of ownerType (both types if empty) go to an owner
+// including address, ordered by start time. A validator with both rewards owners including
+// address is returned twice. Request is paginated (offset, limit).
+func FetchPChainStakesByRewardsOwner(
+	db *gorm.DB,
+	address string,
+	ownerType PChainRewardsOwnerType,
+	offset int,
+	limit int,
+) ([]PChainRewardsOwnerStake, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	query := db.Table("p_chain_txes").
+		Joins("JOIN p_chain_rewards_owners ON p_chain_rewards_owners.tx_id = p_chain_txes.tx_id").
+		Where("p_chain_rewards_owners.address = ?", address)
+	if ownerType != "" {
+		query = query.Where("p_chain_rewards_owners.type = ?", ownerType)
+	}
+
+	var stakes []PChainRewardsOwnerStake
+	err := query.
+		Select("p_chain_txes.*, p_chain_rewards_owners.type AS owner_type, " +
+			"p_chain_rewards_owners.threshold, p_chain_rewards_owners.locktime").
+		Order("p_chain_txes.start_time, p_chain_txes.id, p_chain_rewards_owners.type").
+		Offset(offset).Limit(limit).
+		Scan(&stakes).Error
+	return stakes, err
+}
+
+func CreatePChainStakeRewards(db *gorm.DB, rewards []*PChainStakeReward) error {
+	if len(rewards) > 0 { // attempt to create from an empty slice returns error
+		return db.Create(rewards).Error
+	}
+	return nil
+}
+
+// Query rewards paid in [from, to) where address received the reward for the stake or the
+// delegation fee. Zero from or to is not bounded.
+func addressRewardsQuery(db *gorm.DB, address string, from time.Time, to time.Time) *gorm.DB {
+	query := db.Model(&PChainStakeReward{}).
+		Where("staker_reward_address = ? OR delegation_fee_address = ?", address, address)
+	if !from.IsZero() {
+		query = query.Where("time >= ?", from)
+	}
+	if !to.IsZero() {
+		query = query.Where("time < ?", to)
+	}
+	return query
+}
+
+// Returns rewards paid to address in [from, to), ordered by time. Request is paginated (offset, limit).
+func FetchPChainAddressRewards(
+	db *gorm.DB,
+	address string,
+	from time.Time,
+	to time.Time,
+	offset int,
+	limit int,
+) ([]PChainStakeReward, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	var rewards []PChainStakeReward
+	err := addressRewardsQuery(db, address, from, to).
+		Order("time, id").
+		Offset(offset).Limit(limit).
+		Find(&rewards).Error
+	return rewards, err
+}
+
+// Returns the total amount paid to address in [from, to)
+func FetchPChainAddressRewardTotal(db *gorm.DB, address string, from time.Time, to time.Time) (uint64, error) {
+	var total uint64
+	err := addressRewardsQuery(db, address, from, to).
+		Select("COALESCE(SUM("+
+			"CASE WHEN staker_reward_address = ? THEN staker_reward ELSE 0 END + "+
+			"CASE WHEN delegation_fee_address = ? THEN delegation_fee ELSE 0 END), 0)", address, address).
+		Scan(&total).Error
+	return total, err
+}
+
+// Returns rewards per node paid in [from, to), ordered by node ID
+func FetchPChainNodeRewards(db *gorm.DB, from time.Time, to time.Time) ([]PChainNodeRewards, error) {
+	validatorTypes := []PChainTxType{PChainAddValidatorTx, PChainAddPermissionlessValidatorTx}
+
+	var rewards []PChainNodeRewards
+	err := db.Model(&PChainStakeReward{}).
+		Select("node_id, "+
+			"SUM(CASE WHEN stake_tx_type IN ? THEN staker_reward ELSE 0 END) AS validator_reward, "+
+			"SUM(CASE WHEN stake_tx_type IN ? THEN 0 ELSE staker_reward END) AS delegator_reward, "+
+			"SUM(delegation_fee) AS delegation_fee, "+
+			"COUNT(*) AS rewards", validatorTypes, validatorTypes).
+		Where("time >= ?", from).Where("time < ?", to).
+		Group("node_id").
+		Order("node_id").
+		Scan(&rewards).Error
+	return rewards, err
+}
@@ -0,0 +1,222 @@
+package database
+
+import (
+	"flare-indexer/utils"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Output spent by a transaction
+type PChainUTXOSpend struct {
+	OutTxID     string
+	OutIdx      uint32
+	SpentTxID   string
+	SpentHeight uint64
+}
+
+// Balance of an address at a block height. Outputs requiring a single signature are
+// included in the balance of each of their addresses, outputs requiring more signatures
+// are reported separately.
+type PChainBalance struct {
+	Unlocked uint64 // Unspent outputs which are not staked or locked
+	Staked   uint64 // Stake outputs of stakes which have not ended yet
+	Locked   uint64 // Outputs locked (or stakeable locked) until a time after the block time
+	Multisig uint64 // Outputs which are not staked and require more than one signature
+}
+
+func CreatePChainUTXOs(db *gorm.DB, utxos []*PChainUTXO) error {
+	if len(utxos) > 0 { // attempt to create from an empty slice returns error
+		return db.Create(utxos).Error
+	}
+	return nil
+}
+
+// Set the unstake height of stake outputs of staking transactions (map from staking
+// transaction ID to the height of its RewardValidatorTx)
+func UnstakePChainUTXOs(db *gorm.DB, unstakeHeights map[string]uint64) error {
+	for txID, height := range unstakeHeights {
+		err := db.Model(&PChainUTXO{}).
+			Where("tx_id = ? AND type = ?", txID, PChainStakeOutput).
+			Update("unstake_height", height).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const utxoSpendBatchSize = 500
+
+// Mark outputs as spent. Outputs which are not stored (e.g., genesis outputs) are ignored.
+// Outputs spent at the same height are updated by a single statement per batch.
+func SpendPChainUTXOs(db *gorm.DB, spends []*PChainUTXOSpend) error {
+	var heights []uint64
+	heightSpends := make(map[uint64][]*PChainUTXOSpend)
+	for _, s := range spends {
+		if _, ok := heightSpends[s.SpentHeight]; !ok {
+			heights = append(heights, s.SpentHeight)
+		}
+		heightSpends[s.SpentHeight] = append(heightSpends[s.SpentHeight], s)
+	}
+
+	for _, height := range heights {
+		for hs := heightSpends[height]; len(hs) > 0; {
+			n := utils.Min(len(hs), utxoSpendBatchSize)
+			if err := spendPChainUTXOBatch(db, height, hs[:n]); err != nil {
+				return err
+			}
+			hs = hs[n:]
+		}
+	}
+	return nil
+}
+
+// Mark outputs spent at height as spent, the spending transaction is selected per output
+func spendPChainUTXOBatch(db *gorm.DB, height uint64, spends []*PChainUTXOSpend) error {
+	var spentTxID strings.Builder
+	args := make([]interface{}, 0, 3*len(spends))
+	outs := make([][]interface{}, len(spends))
+	spentTxID.WriteString("CASE")
+	for i, s := range spends {
+		spentTxID.WriteString(" WHEN tx_id = ? AND idx = ? THEN ?")
+		args = append(args, s.OutTxID, s.OutIdx, s.SpentTxID)
+		outs[i] = []interface{}{s.OutTxID, s.OutIdx}
+	}
+	spentTxID.WriteString(" END")
+
+	return db.Model(&PChainUTXO{}).
+		Where("(tx_id, idx) IN ?", outs).
+		Updates(map[string]interface{}{
+			"spent_tx_id":  gorm.Expr(spentTxID.String(), args...),
+			"spent_height": height,
+		}).Error
+}
+
+// Returns spends by transactions in blocks [fromHeight, toHeight] of outputs that are stored
+// as unspent. Spends are missing if the output was stored after its spending transaction (e.g.,
+// by parallel backfill segments).
+func FetchMissingPChainUTXOSpends(db *gorm.DB, fromHeight, toHeight uint64) ([]*PChainUTXOSpend, error) {
+	var spends []*PChainUTXOSpend
+	err := db.Table("p_chain_tx_inputs AS inputs").
+		Joins("JOIN p_chain_txes AS txes ON txes.tx_id = inputs.tx_id").
+		Joins("JOIN p_chain_utxos AS utxos ON utxos.tx_id = inputs.out_tx_id AND utxos.idx = inputs.out_idx").
+		Where("txes.block_height BETWEEN ? AND ?", fromHeight, toHeight).
+		Where("inputs.type = ?", PChainDefaultInput).
+		Where("utxos.spent_height IS NULL").
+		Distinct("inputs.out_tx_id", "inputs.out_idx", "inputs.tx_id AS spent_tx_id", "txes.block_height AS spent_height").
+		Scan(&spends).Error
+	return spends, err
+}
+
+// Returns unstake heights (map from staking transaction ID to the height of its
+// RewardValidatorTx) of RewardValidatorTxs in blocks [fromHeight, toHeight] whose stake outputs
+// are stored without an unstake height
+func FetchMissingPChainUnstakes(db *gorm.DB, fromHeight, toHeight uint64) (map[string]uint64, error) {
+	var rows []struct {
+		RewardTxID  string
+		BlockHeight uint64
+	}
+	err := db.Table("p_chain_txes AS txes").
+		Joins("JOIN p_chain_utxos AS utxos ON utxos.tx_id = txes.reward_tx_id AND utxos.type = ?", PChainStakeOutput).
+		Where("txes.type = ?", PChainRewardValidatorTx).
+		Where("txes.block_height BETWEEN ? AND ?", fromHeight, toHeight).
+		Where("utxos.unstake_height IS NULL").
+		Distinct("txes.reward_tx_id", "txes.block_height").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	unstakeHeights := make(map[string]uint64, len(rows))
+	for _, r := range rows {
+		unstakeHeights[r.RewardTxID] = r.BlockHeight
+	}
+	return unstakeHeights, nil
+}
+
+// Returns the height of the last indexed block, false if there are no blocks
+func FetchPChainLastHeight(db *gorm.DB) (uint64, bool, error) {
+	var blocks []PChainBlock
+	err := db.Order("height DESC").Limit(1).Find(&blocks).Error
+	if err != nil || len(blocks) == 0 {
+		return 0, false, err
+	}
+	return blocks[0].Height, true, nil
+}
+
+// Returns the height of the last block with block time (index time before Banff blocks)
+// at or before t, false if there is no such block
+func FetchPChainHeightAtTime(db *gorm.DB, t time.Time) (uint64, bool, error) {
+	var blocks []PChainBlock
+	err := db.Where("COALESCE(block_time, timestamp) <= ?", t).
+		Order("height DESC").Limit(1).Find(&blocks).Error
+	if err != nil || len(blocks) == 0 {
+		return 0, false, err
+	}
+	return blocks[0].Height, true, nil
+}
+
+// Outputs of address created at or before height and not spent at height
+func unspentPChainUTXOsQuery(db *gorm.DB, address string, height uint64) *gorm.DB {
+	return db.Model(&PChainUTXO{}).
+		Where("address = ?", address).
+		Where("block_height <= ?", height).
+		Where("spent_height IS NULL OR spent_height > ?", height)
+}
+
+// Returns outputs of address which are unspent at height, ordered by creation height.
+// Request is paginated (offset, limit).
+func FetchPChainUnspentUTXOs(db *gorm.DB, address string, height uint64, offset int, limit int) ([]PChainUTXO, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	var utxos []PChainUTXO
+	err := unspentPChainUTXOsQuery(db, address, height).
+		Order("block_height, id").
+		Offset(offset).Limit(limit).
+		Find(&utxos).Error
+	return utxos, err
+}
+
+// Returns the time of the block at height (index time before Banff blocks)
+func fetchPChainTimeAtHeight(db *gorm.DB, height uint64) (time.Time, error) {
+	var block PChainBlock
+	err := db.Where("height = ?", height).First(&block).Error
+	if err != nil {
+		return time.Time{}, err
+	}
+	if block.BlockTime != nil {
+		return *block.BlockTime, nil
+	}
+	return block.Timestamp, nil
+}
+
+// Returns the balance of address at height. Lock times are compared with the time of
+// the block at height.
+func FetchPChainBalance(db *gorm.DB, address string, height uint64) (*PChainBalance, error) {
+	blockTime, err := fetchPChainTimeAtHeight(db, height)
+	if err != nil {
+		return nil, err
+	}
+	t := blockTime.Unix()
+
+	staked := "type = @stake AND (unstake_height IS NULL OR unstake_height > @height)"
+	multisig := "threshold > 1"
+	locked := "locktime > @time OR stakeable_locktime > @time"
+	params := map[string]interface{}{"stake": PChainStakeOutput, "height": height, "time": t}
+
+	var balance PChainBalance
+	err = unspentPChainUTXOsQuery(db, address, height).
+		Select("COALESCE(SUM(CASE WHEN "+staked+" THEN amount ELSE 0 END), 0) AS staked, "+
+			"COALESCE(SUM(CASE WHEN "+staked+" THEN 0 WHEN "+multisig+" THEN amount ELSE 0 END), 0) AS multisig, "+
+			"COALESCE(SUM(CASE WHEN "+staked+" OR "+multisig+" THEN 0 WHEN "+locked+" THEN amount ELSE 0 END), 0) AS locked, "+
+			"COALESCE(SUM(CASE WHEN "+staked+" OR "+multisig+" OR "+locked+" THEN 0 ELSE amount END), 0) AS unlocked",
+			params).
+		Scan(&balance).Error
+	return &balance, err
+}
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"flare-indexer/utils"
 	"time"
 
 	"gorm.io/gorm"
@@ -18,6 +19,19 @@ func FetchMigrations(db *gorm.DB) ([]Migration, error) {
 	return migrations, err
 }
 
+func FetchMigration(db *gorm.DB, version string) (*Migration, error) {
+	var m Migration
+	err := db.Where(&Migration{Version: version}).First(&m).Error
+	return &m, err
+}
+
+// Returns migrations which failed or were interrupted (not completed), ordered by version
+func FetchFailedMigrations(db *gorm.DB) ([]Migration, error) {
+	var migrations []Migration
+	err := db.Where("status <> ?", MigrationCompleted).Order("version asc").Find(&migrations).Error
+	return migrations, err
+}
+
 func CreateMigration(db *gorm.DB, m *Migration) error {
 	return db.Create(m).Error
 }
@@ -26,14 +40,55 @@ func UpdateMigration(db *gorm.DB, m *Migration) error {
 	return db.Save(m).Error
 }
 
+func DeleteMigration(db *gorm.DB, m *Migration) error {
+	return db.Delete(m).Error
+}
+
 func CreateState(db *gorm.DB, s *State) error {
 	return db.Create(s).Error
 }
 
+// Create the state unless a state with its name exists
+func CreateStateIfMissing(db *gorm.DB, s *State) error {
+	return db.Where(State{Name: s.Name}).FirstOrCreate(s).Error
+}
+
 func UpdateState(db *gorm.DB, s *State) error {
 	return db.Save(s).Error
 }
 
+// Returns all states with names starting with prefix, ordered by name
+func FetchStatesWithPrefix(db *gorm.DB, prefix string) ([]State, error) {
+	var states []State
+	err := db.Where("name LIKE ?", prefix+"%").Order("name asc").Find(&states).Error
+	return states, err
+}
+
+func DeleteStates(db *gorm.DB, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	return db.Where("name IN ?", names).Delete(&State{}).Error
+}
+
+func CreateIndexerDiagnostic(db *gorm.DB, d *IndexerDiagnostic) error {
+	return db.Create(d).Error
+}
+
+// Returns the last diagnostic record of the indexer with the given state name, nil if
+// there is none
+func FetchLastIndexerDiagnostic(db *gorm.DB, stateName string) (*IndexerDiagnostic, error) {
+	var diagnostic IndexerDiagnostic
+	err := db.Where(&IndexerDiagnostic{StateName: stateName}).Order("id desc").First(&diagnostic).Error
+	if err == nil {
+		return &diagnostic, nil
+	} else if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	} else {
+		return nil, err
+	}
+}
+
 func CreateUptimeCronjobEntry(db *gorm.DB, entities []*UptimeCronjob) error {
 	if len(entities) > 0 {
 		return db.Create(entities).Error
@@ -69,3 +124,48 @@ func PersistUptimeAggregations(db *gorm.DB, aggregations []*UptimeAggregation) e
 func DeleteUptimesBefore(db *gorm.DB, timestamp time.Time) error {
 	return db.Where("timestamp < ?", timestamp).Delete(&UptimeCronjob{}).Error
 }
+
+// Maximum number of outputs updated by a single statement of UpdateOutputOwners
+const outputOwnersBatchSize = 500
+
+// Update asset ID, lock times, threshold and owner group of rows of model (a table with
+// tx_id and idx columns) matching the transaction IDs and indexes of outs. Outputs with the
+// same values are updated by a single statement.
+func UpdateOutputOwners(db *gorm.DB, model interface{}, outs []*TxOutput) error {
+	type ownerValues struct {
+		assetID           string
+		locktime          uint64
+		threshold         uint32
+		ownerGroup        string
+		stakeableLocktime uint64
+	}
+	var keys []ownerValues
+	outputs := make(map[ownerValues][][]interface{})
+	for _, out := range outs {
+		key := ownerValues{out.AssetID, out.Locktime, out.Threshold, out.OwnerGroup, out.StakeableLocktime}
+		if _, ok := outputs[key]; !ok {
+			keys = append(keys, key)
+		}
+		outputs[key] = append(outputs[key], []interface{}{out.TxID, out.Idx})
+	}
+
+	for _, key := range keys {
+		for ids := outputs[key]; len(ids) > 0; {
+			n := utils.Min(len(ids), outputOwnersBatchSize)
+			err := db.Model(model).
+				Where("(tx_id, idx) IN ?", ids[:n]).
+				Updates(map[string]interface{}{
+					"asset_id":           key.assetID,
+					"locktime":           key.locktime,
+					"threshold":          key.threshold,
+					"owner_group":        key.ownerGroup,
+					"stakeable_locktime": key.stakeableLocktime,
+				}).Error
+			if err != nil {
+				return err
+			}
+			ids = ids[n:]
+		}
+	}
+	return nil
+}
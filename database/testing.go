@@ -2,13 +2,10 @@ package database
 
 import (
 	"flare-indexer/config"
-	"fmt"
+	"os"
 	"time"
 
-	"github.com/go-sql-driver/mysql"
-	gormMysql "gorm.io/driver/mysql"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
 const (
@@ -16,29 +13,48 @@ const (
 	MysqlTestPassword string = "indexeruser"
 	MysqlTestHost     string = "localhost"
 	MysqlTestPort     int    = 3307
+
+	PostgresTestUser     string = "indexeruser"
+	PostgresTestPassword string = "indexeruser"
+	PostgresTestHost     string = "localhost"
+	PostgresTestPort     int    = 5433
+
+	// Environment variable selecting the dialect of the test database, in-memory SQLite if not set
+	TestDialectEnv string = "TEST_DB_DIALECT"
 )
 
-func ConnectTestDB(cfg *config.DBConfig) (*gorm.DB, error) {
-	dbConfig := mysql.Config{
-		User:                 cfg.Username,
-		Passwd:               cfg.Password,
-		Net:                  "tcp",
-		Addr:                 fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
-		DBName:               cfg.Database,
-		AllowNativePasswords: true,
-		ParseTime:            true,
+// Config of the test database with the given name, the engine is selected by TEST_DB_DIALECT.
+// The name is ignored by SQLite, each connection opens a new in-memory database.
+func TestDBConfig(database string) config.DBConfig {
+	switch os.Getenv(TestDialectEnv) {
+	case MySQLDialect:
+		return config.DBConfig{
+			Dialect:  MySQLDialect,
+			Username: MysqlTestUser,
+			Password: MysqlTestPassword,
+			Host:     MysqlTestHost,
+			Port:     MysqlTestPort,
+			Database: database,
+		}
+	case PostgresDialect:
+		return config.DBConfig{
+			Dialect:  PostgresDialect,
+			Username: PostgresTestUser,
+			Password: PostgresTestPassword,
+			Host:     PostgresTestHost,
+			Port:     PostgresTestPort,
+			Database: database,
+		}
+	default:
+		return config.DBConfig{
+			Dialect:  SQLiteDialect,
+			Database: SQLiteMemory,
+		}
 	}
+}
 
-	var gormLogLevel logger.LogLevel
-	if cfg.LogQueries {
-		gormLogLevel = logger.Info
-	} else {
-		gormLogLevel = logger.Silent
-	}
-	gormConfig := gorm.Config{
-		Logger: logger.Default.LogMode(gormLogLevel),
-	}
-	return gorm.Open(gormMysql.Open(dbConfig.FormatDSN()), &gormConfig)
+func ConnectTestDB(cfg *config.DBConfig) (*gorm.DB, error) {
+	return Connect(cfg)
 }
 
 func ConnectAndInitializeTestDB(cfg *config.DBConfig, dropTables bool) (*gorm.DB, error) {
@@ -72,6 +88,13 @@ func FetchTransactionsByBlockHeights(db *gorm.DB, heights []uint64) ([]*PChainTx
 	return transactions, err
 }
 
+// Fetch P-chain blocks by heights
+func FetchPChainBlocksByHeights(db *gorm.DB, heights []uint64) ([]*PChainBlock, error) {
+	var blocks []*PChainBlock
+	err := db.Where("height IN ?", heights).Find(&blocks).Error
+	return blocks, err
+}
+
 func FetchUptimes(db *gorm.DB, nodeIDs []string, start time.Time, end time.Time) ([]*UptimeCronjob, error) {
 	var uptimes []*UptimeCronjob
 	query := db.Table("uptime_cronjobs").
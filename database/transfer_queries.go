@@ -0,0 +1,224 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Exported output or imported input of a cross-chain transfer with the data of its transaction.
+// ID is the ID of the output or input row.
+type CrossChainUTXO struct {
+	ID      uint64
+	TxID    string // ID of the exporting or importing transaction
+	OutTxID string // ID of the ExportTx
+	OutIdx  uint32 // Index of the exported UTXO
+	Amount  uint64
+	Address string
+	ChainID string     // Destination chain of exports, source chain of imports
+	Time    *time.Time // Block time of the transaction (time indexed if not available)
+}
+
+// X-chain transaction with the time of its block or vertex
+type XChainTimedTx struct {
+	XChainTx
+	Time *time.Time
+}
+
+// Row of cross-chain UTXO queries. Both times are selected as columns and combined after the
+// scan, since the type of a COALESCE expression is not known to the SQLite driver.
+type crossChainUTXORow struct {
+	CrossChainUTXO
+	TxBlockTime   *time.Time
+	TxIndexedTime *time.Time
+}
+
+// Row of X-chain transaction queries, see crossChainUTXORow
+type xChainTimedTxRow struct {
+	XChainTx
+	TxBlockTime   *time.Time
+	TxIndexedTime *time.Time
+}
+
+func firstTime(times ...*time.Time) *time.Time {
+	for _, t := range times {
+		if t != nil {
+			return t
+		}
+	}
+	return nil
+}
+
+func scanCrossChainUTXOs(query *gorm.DB) ([]CrossChainUTXO, error) {
+	var rows []crossChainUTXORow
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	utxos := make([]CrossChainUTXO, len(rows))
+	for i, row := range rows {
+		utxos[i] = row.CrossChainUTXO
+		utxos[i].Time = firstTime(row.TxBlockTime, row.TxIndexedTime)
+	}
+	return utxos, nil
+}
+
+// Returns outputs exported by P-chain ExportTxs with output ID >= fromID, ordered by ID
+func FetchPChainCrossChainExports(db *gorm.DB, fromID uint64, limit int) ([]CrossChainUTXO, error) {
+	return scanCrossChainUTXOs(db.Table("p_chain_tx_outputs AS o").
+		Joins("JOIN p_chain_txes AS t ON t.tx_id = o.tx_id").
+		Where("o.id >= ? AND o.type = ?", fromID, PChainExportedOutput).
+		Select("o.id, o.tx_id, o.tx_id AS out_tx_id, o.idx AS out_idx, o.amount, o.address, " +
+			"t.chain_id, t.block_time AS tx_block_time, t.timestamp AS tx_indexed_time").
+		Order("o.id").Limit(limit))
+}
+
+// Returns inputs of P-chain ImportTxs importing outputs of other chains with input ID >= fromID,
+// ordered by ID
+func FetchPChainCrossChainImports(db *gorm.DB, fromID uint64, limit int) ([]CrossChainUTXO, error) {
+	return scanCrossChainUTXOs(db.Table("p_chain_tx_inputs AS i").
+		Joins("JOIN p_chain_txes AS t ON t.tx_id = i.tx_id").
+		Where("i.id >= ? AND i.type = ?", fromID, PChainImportedInput).
+		Select("i.id, i.tx_id, i.out_tx_id, i.out_idx, i.amount, i.address, " +
+			"t.chain_id, t.block_time AS tx_block_time, t.timestamp AS tx_indexed_time").
+		Order("i.id").Limit(limit))
+}
+
+// X-chain transaction time is the block time of its block or the time its vertex was indexed
+const (
+	xChainTxTimeJoins = "LEFT JOIN x_chain_blocks AS b ON b.height = t.block_height " +
+		"LEFT JOIN x_chain_vtxes AS v ON v.id = (SELECT MIN(v2.id) FROM x_chain_vtxes AS v2 WHERE v2.height = t.vtx_height)"
+	xChainTxTimeColumns = "b.block_time AS tx_block_time, v.timestamp AS tx_indexed_time"
+)
+
+// Returns outputs exported by X-chain ExportTxs to the chain with chainID with output
+// ID >= fromID, ordered by ID
+func FetchXChainCrossChainExports(db *gorm.DB, chainID string, fromID uint64, limit int) ([]CrossChainUTXO, error) {
+	return scanCrossChainUTXOs(db.Table("x_chain_tx_outputs AS o").
+		Joins("JOIN x_chain_txes AS t ON t.tx_id = o.tx_id").
+		Joins(xChainTxTimeJoins).
+		Where("o.id >= ? AND o.type = ? AND t.chain_id = ?", fromID, XChainExportedOutput, chainID).
+		Select("o.id, o.tx_id, o.tx_id AS out_tx_id, o.idx AS out_idx, o.amount, o.address, " +
+			"t.chain_id, " + xChainTxTimeColumns).
+		Order("o.id").Limit(limit))
+}
+
+// Returns X-chain ImportTxs importing from the chain with chainID with ID >= fromID, ordered by ID
+func FetchXChainCrossChainImportTxs(db *gorm.DB, chainID string, fromID uint64, limit int) ([]XChainTimedTx, error) {
+	var rows []xChainTimedTxRow
+	err := db.Table("x_chain_txes AS t").
+		Joins(xChainTxTimeJoins).
+		Where("t.id >= ? AND t.type = ? AND t.chain_id = ?", fromID, XChainImportTx, chainID).
+		Select("t.*, " + xChainTxTimeColumns).
+		Order("t.id").Limit(limit).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	txs := make([]XChainTimedTx, len(rows))
+	for i, row := range rows {
+		txs[i] = XChainTimedTx{XChainTx: row.XChainTx, Time: firstTime(row.TxBlockTime, row.TxIndexedTime)}
+	}
+	return txs, nil
+}
+
+// Create or update transfers from their exports, the status of existing transfers is kept
+func UpsertCrossChainTransferExports(db *gorm.DB, transfers []*CrossChainTransfer) error {
+	if len(transfers) == 0 { // attempt to create from an empty slice returns error
+		return nil
+	}
+	return db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "export_tx_id"}, {Name: "out_idx"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"source_chain", "destination_chain", "amount", "address", "export_time",
+		}),
+	}).Create(transfers).Error
+}
+
+// Create or update transfers from their imports, transfers are completed
+func UpsertCrossChainTransferImports(db *gorm.DB, transfers []*CrossChainTransfer) error {
+	if len(transfers) == 0 { // attempt to create from an empty slice returns error
+		return nil
+	}
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "export_tx_id"}, {Name: "out_idx"}},
+		DoUpdates: clause.AssignmentColumns([]string{"import_tx_id", "import_time", "status"}),
+	}).Create(transfers).Error
+}
+
+// Flag pending transfers to destination exported before the given time as orphaned. Returns
+// the number of flagged transfers.
+func FlagOrphanedCrossChainTransfers(db *gorm.DB, destination string, before time.Time) (int64, error) {
+	result := db.Model(&CrossChainTransfer{}).
+		Where("status = ? AND destination_chain = ? AND export_time < ?",
+			CrossChainTransferPending, destination, before).
+		Update("status", CrossChainTransferOrphaned)
+	return result.RowsAffected, result.Error
+}
+
+// Returns the time of the P-chain block with the highest indexed height (time indexed if the
+// block time is not available), nil if no block is indexed
+func FetchPChainLastBlockTime(db *gorm.DB) (*time.Time, error) {
+	block, err := FetchPChainLastBlock(db)
+	if err != nil || block == nil {
+		return nil, err
+	}
+	if block.BlockTime != nil {
+		return block.BlockTime, nil
+	}
+	return &block.Timestamp, nil
+}
+
+// Returns the time of the X-chain block with the highest indexed height or, if no block is
+// indexed, the time the last vertex was indexed. Returns nil if nothing is indexed.
+func FetchXChainLastBlockTime(db *gorm.DB) (*time.Time, error) {
+	var blocks []XChainBlock
+	err := db.Order("height DESC").Limit(1).Find(&blocks).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(blocks) > 0 {
+		return &blocks[0].BlockTime, nil
+	}
+	var vertices []XChainVtx
+	err = db.Order("vtx_index DESC").Limit(1).Find(&vertices).Error
+	if err != nil || len(vertices) == 0 {
+		return nil, err
+	}
+	return &vertices[0].Timestamp, nil
+}
+
+// Returns transfers filtered by address, export or import transaction ID and status (filters
+// are not applied if empty), ordered by export time. Request is paginated (offset, limit).
+func FetchCrossChainTransfers(
+	db *gorm.DB,
+	address string,
+	txID string,
+	status CrossChainTransferStatus,
+	offset int,
+	limit int,
+) ([]CrossChainTransfer, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	query := db.Model(&CrossChainTransfer{})
+	if address != "" {
+		query = query.Where("address = ?", address)
+	}
+	if txID != "" {
+		query = query.Where("export_tx_id = ? OR import_tx_id = ?", txID, txID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var transfers []CrossChainTransfer
+	err := query.Order("export_time, id").
+		Offset(offset).Limit(limit).
+		Find(&transfers).Error
+	return transfers, err
+}
@@ -5,8 +5,18 @@ package database
 type XChainTxType string
 
 const (
-	XChainBaseTx   XChainTxType = "BASE_TX"
-	XChainImportTx XChainTxType = "IMPORT_TX"
+	XChainBaseTx        XChainTxType = "BASE_TX"
+	XChainImportTx      XChainTxType = "IMPORT_TX"
+	XChainExportTx      XChainTxType = "EXPORT_TX"
+	XChainCreateAssetTx XChainTxType = "CREATE_ASSET_TX"
+	XChainOperationTx   XChainTxType = "OPERATION_TX"
+)
+
+type XChainOutputType string
+
+const (
+	XChainDefaultOutput  XChainOutputType = "TX"
+	XChainExportedOutput XChainOutputType = "EXPORTED"
 )
 
 // P-chain types
@@ -45,18 +55,58 @@ const (
 	PChainCommitBlock   PChainBlockType = "COMMIT_BLOCK"
 	PChainAbortBlock    PChainBlockType = "ABORT_BLOCK"
 	PChainStandardBlock PChainBlockType = "STANDARD_BLOCK"
+	PChainGenesisBlock  PChainBlockType = "GENESIS" // Block type of transactions of the network genesis
+)
+
+type PChainL1ValidatorChangeType string
+
+const (
+	PChainL1ValidatorWeightChange    PChainL1ValidatorChangeType = "WEIGHT"
+	PChainL1ValidatorBalanceIncrease PChainL1ValidatorChangeType = "BALANCE"
+	PChainL1ValidatorDisable         PChainL1ValidatorChangeType = "DISABLE"
 )
 
 type PChainOutputType string
 
 const (
-	PChainDefaultOutput PChainOutputType = "TX"
-	PChainStakeOutput   PChainOutputType = "STAKE"
-	PChainRewardOutput  PChainOutputType = "REWARD"
+	PChainDefaultOutput  PChainOutputType = "TX"
+	PChainStakeOutput    PChainOutputType = "STAKE"
+	PChainRewardOutput   PChainOutputType = "REWARD"
+	PChainExportedOutput PChainOutputType = "EXPORTED" // Output exported by ExportTx to another chain, not a P-chain UTXO
+	PChainGenesisOutput  PChainOutputType = "GENESIS"  // UTXO allocated by the network genesis, has the empty transaction ID
+)
+
+type PChainInputType string
+
+const (
+	PChainDefaultInput  PChainInputType = "TX"
+	PChainImportedInput PChainInputType = "IMPORTED" // Imported input of ImportTx, spends an output exported by another chain
+)
+
+type PChainRewardsOwnerType string
+
+const (
+	PChainStakeRewardsOwner         PChainRewardsOwnerType = "STAKE"          // Owner of the validation reward or the delegator's share
+	PChainDelegationFeeRewardsOwner PChainRewardsOwnerType = "DELEGATION_FEE" // Owner of delegation fees of a validator
 )
 
 // Misc other types
 
+type CrossChainTransferStatus string
+
+const (
+	CrossChainTransferPending   CrossChainTransferStatus = "PENDING"   // Exported output is not imported yet
+	CrossChainTransferCompleted CrossChainTransferStatus = "COMPLETED" // Exported output is imported by the destination chain
+	CrossChainTransferOrphaned  CrossChainTransferStatus = "ORPHANED"  // Exported output was not imported in the configured time
+)
+
+type IndexerDiagnosticType string
+
+const (
+	IndexerDiagnosticIndexRewind       IndexerDiagnosticType = "INDEX_REWIND"
+	IndexerDiagnosticContainerMismatch IndexerDiagnosticType = "CONTAINER_MISMATCH"
+)
+
 type MigrationStatus string
 
 const (
@@ -3,40 +3,61 @@ package database
 import (
 	"flare-indexer/config"
 	"fmt"
+	"net/url"
 
+	"github.com/glebarez/sqlite"
 	"github.com/go-sql-driver/mysql"
 	gormMysql "gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
+// Supported values of the dialect in DBConfig, equal to the names of gorm dialectors
+const (
+	MySQLDialect    = "mysql"
+	PostgresDialect = "postgres"
+	SQLiteDialect   = "sqlite"
+
+	// Database name of the in-memory SQLite database
+	SQLiteMemory = ":memory:"
+)
+
 var (
 	// List entities to auto-migrate
 	entities []interface{} = []interface{}{
 		Migration{},
 		State{},
+		IndexerDiagnostic{},
 		XChainTx{},
 		XChainVtx{},
+		XChainBlock{},
 		XChainTxInput{},
 		XChainTxOutput{},
+		XChainAsset{},
+		PChainBlock{},
 		PChainTx{},
 		PChainTxInput{},
 		PChainTxOutput{},
+		PChainUTXO{},
+		PChainStakeReward{},
+		PChainRewardsOwner{},
+		PChainSubnet{},
+		PChainChain{},
+		PChainL1Conversion{},
+		PChainL1Validator{},
+		PChainL1ValidatorChange{},
 		UptimeCronjob{},
 		UptimeAggregation{},
+		CrossChainTransfer{},
 	}
 )
 
 func Connect(cfg *config.DBConfig) (*gorm.DB, error) {
 	// Connect to the database
-	dbConfig := mysql.Config{
-		User:                 cfg.Username,
-		Passwd:               cfg.Password,
-		Net:                  "tcp",
-		Addr:                 fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
-		DBName:               cfg.Database,
-		AllowNativePasswords: true,
-		ParseTime:            true,
+	dialector, err := newDialector(cfg)
+	if err != nil {
+		return nil, err
 	}
 
 	var gormLogLevel logger.LogLevel
@@ -48,7 +69,61 @@ func Connect(cfg *config.DBConfig) (*gorm.DB, error) {
 	gormConfig := gorm.Config{
 		Logger: logger.Default.LogMode(gormLogLevel),
 	}
-	return gorm.Open(gormMysql.Open(dbConfig.FormatDSN()), &gormConfig)
+	db, err := gorm.Open(dialector, &gormConfig)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Dialect == SQLiteDialect {
+		// A single connection, each connection to an in-memory database opens a new database,
+		// and SQLite allows one writer at a time anyway
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, err
+		}
+		sqlDB.SetMaxOpenConns(1)
+	}
+	return db, nil
+}
+
+// Dialector of the configured database engine, MySQL if the dialect is not set
+func newDialector(cfg *config.DBConfig) (gorm.Dialector, error) {
+	switch cfg.Dialect {
+	case "", MySQLDialect:
+		dbConfig := mysql.Config{
+			User:                 cfg.Username,
+			Passwd:               cfg.Password,
+			Net:                  "tcp",
+			Addr:                 fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+			DBName:               cfg.Database,
+			AllowNativePasswords: true,
+			ParseTime:            true,
+		}
+		return gormMysql.Open(dbConfig.FormatDSN()), nil
+	case PostgresDialect:
+		dsn := url.URL{
+			Scheme: "postgres",
+			User:   url.UserPassword(cfg.Username, cfg.Password),
+			Host:   fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+			Path:   cfg.Database,
+		}
+		return postgres.Open(dsn.String()), nil
+	case SQLiteDialect:
+		if cfg.Database == SQLiteMemory {
+			return sqlite.Open(SQLiteMemory), nil
+		}
+		// Wait for locks held by other processes (e.g., services reading the indexer's database)
+		return sqlite.Open(cfg.Database + "?_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)"), nil
+	default:
+		return nil, fmt.Errorf("unsupported database dialect %s", cfg.Dialect)
+	}
+}
+
+// SQL expression joining distinct values of column with commas (aggregate function)
+func groupConcatDistinct(db *gorm.DB, column string) string {
+	if db.Dialector.Name() == PostgresDialect {
+		return "STRING_AGG(DISTINCT " + column + ", ',')"
+	}
+	return "GROUP_CONCAT(DISTINCT " + column + ")"
 }
 
 func ConnectAndInitialize(cfg *config.DBConfig) (*gorm.DB, error) {
@@ -7,11 +7,13 @@ import (
 // Table with indexed data for an X-chain transaction
 type XChainTx struct {
 	BaseEntity
-	Type      XChainTxType `gorm:"type:varchar(20)"`                 // Transaction type
-	TxID      string       `gorm:"type:varchar(50);unique;not null"` // Transaction ID
-	VtxHeight uint64
-	Memo      string `gorm:"type:varchar(256)"`
-	Bytes     []byte `gorm:"type:mediumblob"`
+	Type        XChainTxType `gorm:"type:varchar(20)"`                 // Transaction type
+	TxID        string       `gorm:"type:varchar(50);unique;not null"` // Transaction ID
+	VtxHeight   uint64
+	BlockHeight *uint64 `gorm:"index"`            // Height of the linearized block, null for transactions in vertices
+	ChainID     string  `gorm:"type:varchar(50)"` // Destination chain of export or source chain of import transactions
+	Memo        string  `gorm:"type:varchar(256)"`
+	Bytes       []byte  `gorm:"size:16777215"` // mediumblob on MySQL, bytea on PostgreSQL
 }
 
 type XChainTxInput struct {
@@ -20,14 +22,36 @@ type XChainTxInput struct {
 
 type XChainTxOutput struct {
 	TxOutput
+	Type XChainOutputType `gorm:"type:varchar(20)"` // Transaction output type (default or exported to another chain)
+}
+
+// Table with assets created by CreateAssetTx
+type XChainAsset struct {
+	BaseEntity
+	AssetID      string `gorm:"type:varchar(50);unique;not null"` // Asset ID (ID of the CreateAssetTx)
+	Name         string `gorm:"type:varchar(128)"`
+	Symbol       string `gorm:"type:varchar(16)"`
+	Denomination uint8  // Number of decimal places
 }
 
 // Table with indexed data for an X-chain vertex (block)
 type XChainVtx struct {
 	BaseEntity
-	VtxID     string    `gorm:"type:varchar(50);unique;not null"`
-	ParentID  string    `gorm:"type:varchar(50)"`
-	VtxIndex  uint64    `gorm:"unique"` // Vertex index - from indexer
-	Height    uint64    // Vertex height
-	Timestamp time.Time // Time indexed, not when accepted by the consensus
+	VtxID      string    `gorm:"type:varchar(50);unique;not null"`
+	ParentID   string    `gorm:"type:varchar(50)"`
+	VtxIndex   uint64    `gorm:"unique"` // Vertex index - from indexer
+	Height     uint64    // Vertex height
+	Timestamp  time.Time // Time indexed, not when accepted by the consensus
+	StopVertex bool      // Stop vertex linearizing the chain, no vertices are accepted after it
+}
+
+// Table with indexed data for an X-chain block (after the linearization of the chain in Cortina)
+type XChainBlock struct {
+	BaseEntity
+	BlockID    string    `gorm:"type:varchar(50);unique;not null"`
+	ParentID   string    `gorm:"type:varchar(50)"` // Parent block ID (stop vertex ID for the first block)
+	BlockIndex uint64    `gorm:"unique"`           // Block index - from indexer
+	Height     uint64    `gorm:"unique"`           // Block height
+	BlockTime  time.Time // Block timestamp
+	Timestamp  time.Time // Time indexed, not when accepted by the consensus
 }
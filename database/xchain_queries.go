@@ -10,6 +10,62 @@ func FetchXChainTxOutputs(db *gorm.DB, ids []string) ([]XChainTxOutput, error) {
 	return txs, err
 }
 
+// Returns outputs exported by ExportTxs with ids
+func FetchXChainExportedOutputs(db *gorm.DB, ids []string) ([]XChainTxOutput, error) {
+	var outs []XChainTxOutput
+	err := db.Where("tx_id IN ?", ids).Where("type = ?", XChainExportedOutput).Find(&outs).Error
+	return outs, err
+}
+
+// Returns the ID of the vertex with the given index, empty string if there is no
+// such vertex in the database
+func FetchXChainVtxIDAtIndex(db *gorm.DB, index uint64) (string, error) {
+	var vtx XChainVtx
+	err := db.Where("vtx_index = ?", index).First(&vtx).Error
+	if err == nil {
+		return vtx.VtxID, nil
+	} else if err == gorm.ErrRecordNotFound {
+		return "", nil
+	} else {
+		return "", err
+	}
+}
+
+// Returns the ID of the block with the given index, empty string if there is no
+// such block in the database
+func FetchXChainBlockIDAtIndex(db *gorm.DB, index uint64) (string, error) {
+	var block XChainBlock
+	err := db.Where("block_index = ?", index).First(&block).Error
+	if err == nil {
+		return block.BlockID, nil
+	} else if err == gorm.ErrRecordNotFound {
+		return "", nil
+	} else {
+		return "", err
+	}
+}
+
+// Returns true if the stop vertex of the X-chain has been indexed
+func FetchXChainLinearized(db *gorm.DB) (bool, error) {
+	var count int64
+	err := db.Model(&XChainVtx{}).Where("stop_vertex = ?", true).Count(&count).Error
+	return count > 0, err
+}
+
+func CreateXChainBlocks(db *gorm.DB, blocks []*XChainBlock) error {
+	if len(blocks) > 0 { // attempt to create from an empty slice returns error
+		return db.Create(blocks).Error
+	}
+	return nil
+}
+
+func CreateXChainAssets(db *gorm.DB, assets []*XChainAsset) error {
+	if len(assets) > 0 { // attempt to create from an empty slice returns error
+		return db.Create(assets).Error
+	}
+	return nil
+}
+
 func CreateXChainEntities(db *gorm.DB, vertices []*XChainVtx, txs []*XChainTx, ins []*XChainTxInput, outs []*XChainTxOutput) error {
 	if len(vertices) > 0 { // attempt to create from an empty slice returns error
 		err := db.Create(vertices).Error
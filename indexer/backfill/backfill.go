@@ -0,0 +1,257 @@
+package backfill
+
+import (
+	"context"
+	"flag"
+	"flare-indexer/database"
+	indexerctx "flare-indexer/indexer/context"
+	"flare-indexer/indexer/pchain"
+	"flare-indexer/indexer/shared"
+	"flare-indexer/logger"
+	"flare-indexer/utils"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+const (
+	// Prefix of the names of states (progress rows) of backfill segments
+	StatePrefix = "p_chain_backfill_"
+
+	defaultWorkers     = 4
+	defaultSegmentSize = 100000
+)
+
+// Closed range of indexes indexed by a single worker
+type segment struct {
+	start     uint64
+	end       uint64
+	stateName string
+}
+
+type backfill struct {
+	ictx       indexerctx.IndexerContext
+	db         *gorm.DB
+	fromHeight uint64
+	toHeight   uint64
+	indexes    *pchain.IndexRange
+}
+
+// Run the backfill command with its command line arguments:
+//
+//	backfill --from <height> --to <height> [--workers <n>] [--segment-size <n>]
+//
+// The P-chain block height range [from, to] is split into segments that are indexed in
+// parallel into the P-chain transaction tables. Each segment has its own progress row in the
+// states table, so an interrupted backfill is resumed by running the same command again.
+// When all segments are indexed, spends of outputs persisted by a later segment are applied
+// and the state of the P-chain indexer is moved to the block following the range. The range must continue the data already indexed by the P-chain indexer,
+// which must not run during the backfill.
+func Run(ctx context.Context, ictx indexerctx.IndexerContext, args []string) error {
+	fs := flag.NewFlagSet("backfill", flag.ContinueOnError)
+	from := fs.Uint64("from", 0, "First P-chain block height to index")
+	to := fs.Uint64("to", 0, "Last P-chain block height to index")
+	workers := fs.Int("workers", defaultWorkers, "Number of segments indexed in parallel")
+	segmentSize := fs.Uint64("segment-size", defaultSegmentSize, "Number of blocks in a segment")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *to < *from || *to == 0 {
+		return errors.Errorf("invalid height range [%d, %d]", *from, *to)
+	}
+	if *workers <= 0 || *segmentSize == 0 {
+		return errors.New("number of workers and segment size must be positive")
+	}
+
+	indexes, err := pchain.HeightsToIndexRange(ctx, ictx, *from, *to)
+	if err != nil {
+		return err
+	}
+	b := &backfill{ictx: ictx, db: ictx.DB(), fromHeight: *from, toHeight: *to, indexes: indexes}
+
+	segments := b.segments(*segmentSize)
+	if err := b.initStates(segments); err != nil {
+		return err
+	}
+	logger.Info("Backfilling P-chain heights [%d, %d] (indexes [%d, %d]) in %d segments with %d workers",
+		*from, *to, indexes.From, indexes.To, len(segments), *workers)
+
+	if err := b.indexSegments(ctx, segments, *workers); err != nil {
+		return err
+	}
+	return b.handOver(segments)
+}
+
+func (b *backfill) segments(size uint64) []segment {
+	var segments []segment
+	for start := b.indexes.From; start <= b.indexes.To; start += size {
+		end := utils.Min(start+size-1, b.indexes.To)
+		segments = append(segments, segment{
+			start:     start,
+			end:       end,
+			stateName: fmt.Sprintf("%s%d_%d", StatePrefix, start, end),
+		})
+	}
+	return segments
+}
+
+// Create progress rows of segments. Existing rows are reused if they belong to the same
+// backfill, rows of a backfill with a different range or segmentation are an error.
+func (b *backfill) initStates(segments []segment) error {
+	if err := b.checkContinuesIndexed(b.db); err != nil {
+		return err
+	}
+	existing, err := database.FetchStatesWithPrefix(b.db, StatePrefix)
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		names := make(map[string]bool)
+		for _, s := range segments {
+			names[s.stateName] = true
+		}
+		for _, s := range existing {
+			if !names[s.Name] {
+				return errors.Errorf("state %s belongs to another backfill, finish it or delete its states first", s.Name)
+			}
+		}
+		if len(existing) != len(segments) {
+			return errors.Errorf("found %d of %d backfill states", len(existing), len(segments))
+		}
+		logger.Info("Resuming backfill")
+		return nil
+	}
+	return database.DoInTransaction(b.db, func(db *gorm.DB) error {
+		for _, s := range segments {
+			err := database.CreateState(db, &database.State{
+				Name:           s.stateName,
+				NextDBIndex:    s.start,
+				LastChainIndex: b.indexes.LastIndex,
+				Updated:        time.Now(),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Index segments with the given number of workers. Stops all workers after the first error.
+func (b *backfill) indexSegments(parentCtx context.Context, segments []segment, workers int) error {
+	ctx, cancel := context.WithCancel(parentCtx)
+	defer cancel()
+
+	queue := make(chan segment, len(segments))
+	for _, s := range segments {
+		queue <- s
+	}
+	close(queue)
+
+	var wg sync.WaitGroup
+	var errOnce sync.Once
+	var firstErr error
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for s := range queue {
+				if err := b.indexSegment(ctx, s); err != nil {
+					errOnce.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+	return parentCtx.Err()
+}
+
+func (b *backfill) indexSegment(ctx context.Context, s segment) error {
+	idxr, err := pchain.CreatePChainSegmentIndexer(b.ictx, s.stateName, s.start, s.end, b.fromHeight-b.indexes.From)
+	if err != nil {
+		return err
+	}
+	for {
+		state, err := database.FetchState(b.db, s.stateName)
+		if err != nil {
+			return err
+		}
+		if state.NextDBIndex > s.end {
+			logger.Info("Backfill segment [%d, %d] done", s.start, s.end)
+			return nil
+		}
+
+		err = idxr.IndexBatch(ctx)
+		if err == nil {
+			continue
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if errors.Is(err, shared.ErrChainMismatch) {
+			return err
+		}
+		logger.Error("Backfill segment [%d, %d] error %v", s.start, s.end, err)
+		select {
+		case <-time.After(idxr.Config.Timeout):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// The backfilled range must start at the next index of the P-chain indexer
+func (b *backfill) checkContinuesIndexed(db *gorm.DB) error {
+	state, err := database.FetchState(db, pchain.StateName)
+	if err != nil {
+		return err
+	}
+	next := utils.Max(state.NextDBIndex, b.ictx.Config().PChainIndexer.StartIndex)
+	if next != b.indexes.From {
+		return errors.Errorf("backfill must start at index %d where the P-chain indexer stopped, range starts at index %d",
+			next, b.indexes.From)
+	}
+	return nil
+}
+
+// Apply UTXO changes persisted before their outputs, move the P-chain indexer state after the
+// backfilled range and delete progress rows
+func (b *backfill) handOver(segments []segment) error {
+	return database.DoInTransaction(b.db,
+		func(db *gorm.DB) error { return b.checkContinuesIndexed(db) },
+		func(db *gorm.DB) error {
+			names := make([]string, len(segments))
+			for i, s := range segments {
+				state, err := database.FetchState(db, s.stateName)
+				if err != nil {
+					return err
+				}
+				if state.NextDBIndex <= s.end {
+					return errors.Errorf("backfill segment [%d, %d] is not complete", s.start, s.end)
+				}
+				names[i] = s.stateName
+			}
+			return database.DeleteStates(db, names)
+		},
+		func(db *gorm.DB) error { return pchain.ReconcileUTXOs(db, b.fromHeight, b.toHeight) },
+		func(db *gorm.DB) error {
+			state, err := database.FetchState(db, pchain.StateName)
+			if err != nil {
+				return err
+			}
+			state.Update(b.indexes.To+1, utils.Max(state.LastChainIndex, b.indexes.LastIndex))
+			logger.Info("P-chain indexer continues at index %d", state.NextDBIndex)
+			return database.UpdateState(db, &state)
+		},
+	)
+}
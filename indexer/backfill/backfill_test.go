@@ -0,0 +1,22 @@
+package backfill
+
+import (
+	"flare-indexer/indexer/pchain"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSegments(t *testing.T) {
+	b := &backfill{indexes: &pchain.IndexRange{From: 10, To: 34}}
+
+	segments := b.segments(10)
+	require.Equal(t, []segment{
+		{start: 10, end: 19, stateName: "p_chain_backfill_10_19"},
+		{start: 20, end: 29, stateName: "p_chain_backfill_20_29"},
+		{start: 30, end: 34, stateName: "p_chain_backfill_30_34"},
+	}, segments)
+
+	segments = b.segments(100)
+	require.Equal(t, []segment{{start: 10, end: 34, stateName: "p_chain_backfill_10_34"}}, segments)
+}
@@ -0,0 +1,296 @@
+package check
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"flare-indexer/database"
+	indexerctx "flare-indexer/indexer/context"
+	"flare-indexer/indexer/pchain"
+	"flare-indexer/indexer/xchain"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+const defaultLimit = 100
+
+var ErrIssuesFound = errors.New("integrity check found issues")
+
+// Problem found by a check
+type Issue struct {
+	Ref     string `json:"ref"` // Transaction ID, height or state name of the problem
+	Message string `json:"message"`
+}
+
+type CheckResult struct {
+	Name      string  `json:"name"`
+	Issues    []Issue `json:"issues"`
+	Truncated bool    `json:"truncated"` // Limit was reached, there may be more issues
+}
+
+type Report struct {
+	OK     bool          `json:"ok"`
+	Checks []CheckResult `json:"checks"`
+}
+
+// Integrity check of the indexed data, returns issues found (at most limit)
+type check struct {
+	name string
+	run  func(db *gorm.DB, limit int) ([]Issue, error)
+}
+
+// Run the check command with its command line arguments:
+//
+//	check [--limit <n>] [--output <file>]
+//
+// The database is checked without connecting to the node and the JSON report is written to
+// the output file (standard output if empty). Returns ErrIssuesFound if any check found issues.
+func Run(ctx context.Context, ictx indexerctx.IndexerContext, args []string) error {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	limit := fs.Int("limit", defaultLimit, "Maximal number of issues reported by a check")
+	output := fs.String("output", "", "File to write the JSON report to (standard output if empty)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *limit <= 0 {
+		return errors.New("limit must be positive")
+	}
+
+	report, err := runChecks(ctx, ictx.DB(), *limit)
+	if err != nil {
+		return err
+	}
+	w := io.Writer(os.Stdout)
+	if len(*output) > 0 {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := writeReport(w, report); err != nil {
+		return err
+	}
+	if !report.OK {
+		return ErrIssuesFound
+	}
+	return nil
+}
+
+func runChecks(ctx context.Context, db *gorm.DB, limit int) (*Report, error) {
+	report := &Report{OK: true}
+	for _, c := range checks() {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		// One more issue than reported shows whether the result is truncated
+		issues, err := c.run(db, limit+1)
+		if err != nil {
+			return nil, errors.Wrapf(err, "check %s failed", c.name)
+		}
+		truncated := len(issues) > limit
+		if truncated {
+			issues = issues[:limit]
+		}
+		if issues == nil {
+			issues = []Issue{}
+		}
+		report.Checks = append(report.Checks, CheckResult{
+			Name:      c.name,
+			Issues:    issues,
+			Truncated: truncated,
+		})
+		report.OK = report.OK && len(issues) == 0
+	}
+	return report, nil
+}
+
+func writeReport(w io.Writer, report *Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+func checks() []check {
+	return []check{
+		{"p_chain_block_heights", heightChecks("p_chain_blocks", "height")},
+		{"x_chain_block_heights", heightChecks("x_chain_blocks", "height")},
+		{"x_chain_vtx_indexes", heightChecks("x_chain_vtxes", "vtx_index")},
+		{"p_chain_txs_without_block", checkPChainTxsWithoutBlock},
+		{"p_chain_unmatched_inputs", unmatchedInputs(fetchUnmatchedPChainInputs)},
+		{"x_chain_unmatched_inputs", unmatchedInputs(database.FetchUnmatchedXChainInputs)},
+		{"p_chain_stakes_without_times", checkStakesWithoutTimes},
+		{"p_chain_rewards_without_stake", checkRewardsWithoutStake},
+		{"states", checkStates},
+	}
+}
+
+// Gaps and duplicates in the sequence of heights (or indexes) stored in table
+func heightChecks(table string, column string) func(db *gorm.DB, limit int) ([]Issue, error) {
+	return func(db *gorm.DB, limit int) ([]Issue, error) {
+		gaps, err := database.FetchHeightGaps(db, table, column, limit)
+		if err != nil {
+			return nil, err
+		}
+		duplicates, err := database.FetchDuplicateHeights(db, table, column, limit)
+		if err != nil {
+			return nil, err
+		}
+		var issues []Issue
+		for _, h := range gaps {
+			issues = append(issues, Issue{
+				Ref:     fmt.Sprint(h + 1),
+				Message: fmt.Sprintf("%s %d is missing in %s", column, h+1, table),
+			})
+		}
+		for _, h := range duplicates {
+			issues = append(issues, Issue{
+				Ref:     fmt.Sprint(h),
+				Message: fmt.Sprintf("%s %d is stored more than once in %s", column, h, table),
+			})
+		}
+		return issues, nil
+	}
+}
+
+func checkPChainTxsWithoutBlock(db *gorm.DB, limit int) ([]Issue, error) {
+	txIDs, err := database.FetchPChainTxsWithoutBlock(db, limit)
+	if err != nil {
+		return nil, err
+	}
+	issues := make([]Issue, len(txIDs))
+	for i, txID := range txIDs {
+		issues[i] = Issue{Ref: txID, Message: "block of the transaction is not stored"}
+	}
+	return issues, nil
+}
+
+func unmatchedInputs(
+	fetch func(db *gorm.DB, limit int) ([]database.UnmatchedInput, error),
+) func(db *gorm.DB, limit int) ([]Issue, error) {
+	return func(db *gorm.DB, limit int) ([]Issue, error) {
+		ins, err := fetch(db, limit)
+		if err != nil {
+			return nil, err
+		}
+		issues := make([]Issue, len(ins))
+		for i, in := range ins {
+			issues[i] = Issue{
+				Ref:     in.TxID,
+				Message: fmt.Sprintf("input %d spends output %s:%d which is not stored", in.InIdx, in.OutTxID, in.OutIdx),
+			}
+		}
+		return issues, nil
+	}
+}
+
+// Genesis outputs are stored only if the genesis is loaded, inputs spending them are not
+// checked otherwise
+func fetchUnmatchedPChainInputs(db *gorm.DB, limit int) ([]database.UnmatchedInput, error) {
+	var skipOutTxID string
+	_, err := database.FetchState(db, pchain.GenesisStateName)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		skipOutTxID = ids.Empty.String()
+	} else if err != nil {
+		return nil, err
+	}
+	return database.FetchUnmatchedPChainInputs(db, limit, skipOutTxID)
+}
+
+func checkStakesWithoutTimes(db *gorm.DB, limit int) ([]Issue, error) {
+	txIDs, err := database.FetchPChainStakesWithoutTimes(db, limit)
+	if err != nil {
+		return nil, err
+	}
+	issues := make([]Issue, len(txIDs))
+	for i, txID := range txIDs {
+		issues[i] = Issue{Ref: txID, Message: "staking transaction has no start or end time"}
+	}
+	return issues, nil
+}
+
+func checkRewardsWithoutStake(db *gorm.DB, limit int) ([]Issue, error) {
+	txs, err := database.FetchPChainRewardTxsWithoutStake(db, limit)
+	if err != nil {
+		return nil, err
+	}
+	issues := make([]Issue, len(txs))
+	for i, tx := range txs {
+		issues[i] = Issue{
+			Ref:     *tx.TxID,
+			Message: fmt.Sprintf("reward transaction at height %d rewards unknown stake %s", tx.BlockHeight, tx.RewardTxID),
+		}
+	}
+	return issues, nil
+}
+
+// States of indexers compared with the stored blocks and vertices
+func checkStates(db *gorm.DB, limit int) ([]Issue, error) {
+	var issues []Issue
+	for _, s := range []struct {
+		stateName string
+		table     string
+		column    string
+		indexed   bool // column is the container index, otherwise index and height differ by an offset
+	}{
+		{pchain.StateName, "p_chain_blocks", "height", false},
+		{xchain.BlockStateName, "x_chain_blocks", "block_index", true},
+		{xchain.StateName, "x_chain_vtxes", "vtx_index", true},
+	} {
+		state, err := database.FetchState(db, s.stateName)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		r, err := database.FetchHeightRange(db, s.table, s.column)
+		if err != nil {
+			return nil, err
+		}
+		if message := stateIssue(state, r, s.indexed); message != "" {
+			issues = append(issues, Issue{Ref: s.stateName, Message: message})
+		}
+	}
+	return issues, nil
+}
+
+// Describes the inconsistency of the state with the stored range of heights (or indexes if
+// indexed), empty if there is none. The next index of the state must follow the last stored
+// container. Heights are not less than indexes, so the last height can only be checked against
+// the next index.
+func stateIssue(state database.State, r database.HeightRange, indexed bool) string {
+	if r.Count == 0 {
+		return ""
+	}
+	if state.NextDBIndex == 0 {
+		return fmt.Sprintf("next index is 0, but %s %d is stored", columnName(indexed), r.Max)
+	}
+	last := state.NextDBIndex - 1
+	if indexed && last != r.Max {
+		return fmt.Sprintf("next index %d does not follow the last stored index %d", state.NextDBIndex, r.Max)
+	}
+	if !indexed && last > r.Max {
+		return fmt.Sprintf("next index %d is ahead of the last stored height %d", state.NextDBIndex, r.Max)
+	}
+	if !indexed && r.Max-last > r.Min {
+		return fmt.Sprintf("last stored height %d is ahead of next index %d", r.Max, state.NextDBIndex)
+	}
+	if state.LastChainIndex < last {
+		return fmt.Sprintf("last chain index %d is below next index %d", state.LastChainIndex, state.NextDBIndex)
+	}
+	return ""
+}
+
+func columnName(indexed bool) string {
+	if indexed {
+		return "index"
+	}
+	return "height"
+}
@@ -0,0 +1,104 @@
+package check
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flare-indexer/database"
+	"flare-indexer/indexer/pchain"
+	"fmt"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateIssue(t *testing.T) {
+	state := func(next, last uint64) database.State {
+		return database.State{NextDBIndex: next, LastChainIndex: last}
+	}
+	// Heights of P-chain blocks are their indexes + 1
+	heights := database.HeightRange{Min: 1, Max: 100, Count: 100}
+	require.Empty(t, stateIssue(state(100, 200), heights, false))
+	require.NotEmpty(t, stateIssue(state(0, 200), heights, false))
+	require.NotEmpty(t, stateIssue(state(102, 200), heights, false))
+	// Offset of 50 would put the first stored block before index 0
+	require.NotEmpty(t, stateIssue(state(50, 200), heights, false))
+	require.NotEmpty(t, stateIssue(state(100, 90), heights, false))
+	require.Empty(t, stateIssue(state(7, 7), database.HeightRange{}, false))
+
+	indexes := database.HeightRange{Min: 0, Max: 99, Count: 100}
+	require.Empty(t, stateIssue(state(100, 150), indexes, true))
+	require.NotEmpty(t, stateIssue(state(99, 150), indexes, true))
+	require.NotEmpty(t, stateIssue(state(101, 150), indexes, true))
+}
+
+func TestReport(t *testing.T) {
+	report := &Report{OK: false, Checks: []CheckResult{
+		{Name: "states", Issues: []Issue{{Ref: "p_chain_block", Message: "next index is 0"}}},
+	}}
+	var buf bytes.Buffer
+	require.NoError(t, writeReport(&buf, report))
+
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	require.Equal(t, false, decoded["ok"])
+	checks := decoded["checks"].([]interface{})
+	require.Len(t, checks, 1)
+	require.Equal(t, "states", checks[0].(map[string]interface{})["name"])
+}
+
+func TestTruncated(t *testing.T) {
+	cfg := database.TestDBConfig("flare_indexer_indexer")
+	db, err := database.ConnectAndInitializeTestDB(&cfg, true)
+	require.NoError(t, err)
+
+	// Heights 2, 4 and 6 are missing
+	var blocks []*database.PChainBlock
+	for _, h := range []uint64{1, 3, 5, 7} {
+		blocks = append(blocks, &database.PChainBlock{Height: h, BlockID: fmt.Sprintf("block%d", h)})
+	}
+	require.NoError(t, database.CreatePChainEntities(db, blocks, nil, nil, nil))
+
+	heights := func(limit int) CheckResult {
+		report, err := runChecks(context.Background(), db, limit)
+		require.NoError(t, err)
+		require.Equal(t, "p_chain_block_heights", report.Checks[0].Name)
+		return report.Checks[0]
+	}
+	result := heights(3)
+	require.Len(t, result.Issues, 3)
+	require.False(t, result.Truncated)
+	result = heights(2)
+	require.Len(t, result.Issues, 2)
+	require.True(t, result.Truncated)
+	result = heights(4)
+	require.Len(t, result.Issues, 3)
+	require.False(t, result.Truncated)
+}
+
+func TestUnmatchedGenesisInputs(t *testing.T) {
+	cfg := database.TestDBConfig("flare_indexer_indexer")
+	db, err := database.ConnectAndInitializeTestDB(&cfg, true)
+	require.NoError(t, err)
+
+	genesisTxID := ids.Empty.String()
+	ins := []*database.PChainTxInput{
+		{TxInput: database.TxInput{TxID: "spend", InIdx: 0, OutTxID: genesisTxID, OutIdx: 0, Address: "a"}, Type: database.PChainDefaultInput},
+		{TxInput: database.TxInput{TxID: "spend", InIdx: 1, OutTxID: "missing", OutIdx: 0, Address: "a"}, Type: database.PChainDefaultInput},
+	}
+	require.NoError(t, database.CreatePChainEntities(db, nil, nil, ins, nil))
+
+	// Genesis is not loaded, its outputs are not expected to be stored
+	unmatched, err := fetchUnmatchedPChainInputs(db, 10)
+	require.NoError(t, err)
+	require.Len(t, unmatched, 1)
+	require.Equal(t, "missing", unmatched[0].OutTxID)
+
+	// Genesis is loaded, the spent genesis output is missing
+	require.NoError(t, database.CreateState(db, &database.State{Name: pchain.GenesisStateName}))
+	unmatched, err = fetchUnmatchedPChainInputs(db, 10)
+	require.NoError(t, err)
+	require.Len(t, unmatched, 2)
+	require.Equal(t, genesisTxID, unmatched[0].OutTxID)
+}
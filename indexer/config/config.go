@@ -12,16 +12,18 @@ import (
 )
 
 type Config struct {
-	DB                config.DBConfig     `toml:"db"`
-	Logger            config.LoggerConfig `toml:"logger"`
-	Chain             config.ChainConfig  `toml:"chain"`
-	Metrics           MetricsConfig       `toml:"metrics"`
-	XChainIndexer     IndexerConfig       `toml:"x_chain_indexer"`
-	PChainIndexer     IndexerConfig       `toml:"p_chain_indexer"`
-	UptimeCronjob     UptimeConfig        `toml:"uptime_cronjob"`
-	Mirror            MirrorConfig        `toml:"mirroring_cronjob"`
-	VotingCronjob     VotingConfig        `toml:"voting_cronjob"`
-	ContractAddresses ContractAddresses   `toml:"contract_addresses"`
+	DB                 config.DBConfig     `toml:"db"`
+	Logger             config.LoggerConfig `toml:"logger"`
+	Chain              config.ChainConfig  `toml:"chain"`
+	Metrics            MetricsConfig       `toml:"metrics"`
+	XChainIndexer      IndexerConfig       `toml:"x_chain_indexer"`
+	XChainBlockIndexer IndexerConfig       `toml:"x_chain_block_indexer"`
+	PChainIndexer      IndexerConfig       `toml:"p_chain_indexer"`
+	UptimeCronjob      UptimeConfig        `toml:"uptime_cronjob"`
+	Mirror             MirrorConfig        `toml:"mirroring_cronjob"`
+	VotingCronjob      VotingConfig        `toml:"voting_cronjob"`
+	TransferCronjob    TransferConfig      `toml:"transfer_cronjob"`
+	ContractAddresses  ContractAddresses   `toml:"contract_addresses"`
 }
 
 type Gas struct {
@@ -51,6 +53,25 @@ type IndexerConfig struct {
 	Timeout    time.Duration `toml:"timeout"`
 	BatchSize  int           `toml:"batch_size"`
 	StartIndex uint64        `toml:"start_index"`
+
+	// Number of batches fetched ahead while the previous ones are processed and persisted,
+	// 0 disables prefetching
+	PrefetchDepth int `toml:"prefetch_depth"`
+
+	// Directory of the container archive, empty disables archiving
+	ArchiveDir string `toml:"archive_dir"`
+	// Number of containers in an archive segment file (0 = default)
+	ArchiveSegmentSize uint64 `toml:"archive_segment_size"`
+	// Read containers from the archive instead of the node
+	ArchiveReplay bool `toml:"archive_replay"`
+
+	// Record imported inputs of ImportTxs with addresses resolved from the source chain
+	// (P-chain indexer only)
+	ImportedInputs bool `toml:"imported_inputs"`
+
+	// Genesis config file of the network, empty uses the genesis embedded for the network
+	// address HRP (P-chain indexer only)
+	GenesisFile string `toml:"genesis_file"`
 }
 
 type CronjobConfig struct {
@@ -85,6 +106,13 @@ type UptimeConfig struct {
 	DeleteOldUptimesEpochThreshold int64           `toml:"delete_old_uptimes_epoch_threshold"`
 }
 
+type TransferConfig struct {
+	CronjobConfig
+	// Pending transfers exported longer before the last block indexed on the destination chain
+	// are flagged as orphaned
+	OrphanTimeout time.Duration `toml:"orphan_timeout"`
+}
+
 type ContractAddresses struct {
 	config.ContractAddresses
 	Mirroring common.Address `toml:"mirroring" env:"MIRRORING_CONTRACT_ADDRESS, default=0x0000000000000000000000000000000000000000"`
@@ -98,6 +126,12 @@ func newConfig() *Config {
 			BatchSize:  10,
 			StartIndex: 0,
 		},
+		XChainBlockIndexer: IndexerConfig{
+			Enabled:    false,
+			Timeout:    3000 * time.Millisecond,
+			BatchSize:  10,
+			StartIndex: 0,
+		},
 		PChainIndexer: IndexerConfig{
 			Enabled:    true,
 			Timeout:    3000 * time.Millisecond,
@@ -110,8 +144,21 @@ func newConfig() *Config {
 				Timeout: 60 * time.Second,
 			},
 		},
+		TransferCronjob: TransferConfig{
+			CronjobConfig: CronjobConfig{
+				Enabled:   false,
+				Timeout:   60 * time.Second,
+				BatchSize: 1000,
+			},
+			OrphanTimeout: 24 * time.Hour,
+		},
 		Chain: config.ChainConfig{
 			NodeURL: "http://localhost:9650/",
+			Requests: config.RequestConfig{
+				MaxRetries:  3,
+				BackoffBase: 500 * time.Millisecond,
+				BackoffMax:  10 * time.Second,
+			},
 		},
 	}
 }
@@ -27,6 +27,11 @@ type IndexerFlags struct {
 	// Set start epoch for mirroring cronjob to this value, overrides config and database value,
 	// valid value is > 0
 	ResetMirrorCronjob int64
+
+	// Command to run instead of the indexer (e.g., "backfill") and its arguments,
+	// empty to run the indexer
+	Command     string
+	CommandArgs []string
 }
 
 type indexerContext struct {
@@ -67,10 +72,15 @@ func ParseIndexerFlags() *IndexerFlags {
 	resetMirrorFlag := flag.Int64("reset-mirroring", 0, "Set start epoch for mirroring cronjob to this value, overrides config and database value, valid values are > 0")
 	flag.Parse()
 
-	return &IndexerFlags{
+	flags := &IndexerFlags{
 		Version:            *versionFlag,
 		ConfigFileName:     *cfgFlag,
 		ResetVotingCronjob: *resetVotingFlag,
 		ResetMirrorCronjob: *resetMirrorFlag,
 	}
+	if args := flag.Args(); len(args) > 0 {
+		flags.Command = args[0]
+		flags.CommandArgs = args[1:]
+	}
+	return flags
 }
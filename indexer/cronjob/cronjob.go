@@ -1,6 +1,7 @@
 package cronjob
 
 import (
+	"context"
 	"flare-indexer/database"
 	"flare-indexer/indexer/config"
 	"flare-indexer/indexer/shared"
@@ -8,6 +9,8 @@ import (
 	"flare-indexer/utils"
 	"flare-indexer/utils/staking"
 	"time"
+
+	"github.com/pkg/errors"
 )
 
 type Cronjob interface {
@@ -15,7 +18,7 @@ type Cronjob interface {
 	Enabled() bool
 	Timeout() time.Duration
 	RandomTimeoutDelta() time.Duration
-	Call() error
+	Call(ctx context.Context) error
 	OnStart() error
 
 	// Set health status of cronjob
@@ -23,7 +26,9 @@ type Cronjob interface {
 	UpdateCronjobStatus(status shared.HealthStatus)
 }
 
-func RunCronjob(c Cronjob) {
+// Run cronjob until ctx is cancelled. A call in progress is allowed to finish
+// (cronjobs check ctx between epochs), no new calls are made after cancellation.
+func RunCronjob(ctx context.Context, c Cronjob) {
 	if !c.Enabled() {
 		logger.Debug("%s cronjob disabled", c.Name())
 		c.UpdateCronjobStatus(shared.HealthStatusOk)
@@ -33,6 +38,7 @@ func RunCronjob(c Cronjob) {
 	err := c.OnStart()
 	if err != nil {
 		logger.Error("%s cronjob on start error %v", c.Name(), err)
+		c.UpdateCronjobStatus(shared.HealthStatusError)
 		return
 	}
 
@@ -40,9 +46,21 @@ func RunCronjob(c Cronjob) {
 
 	ticker := utils.NewRandomizedTicker(c.Timeout(), c.RandomTimeoutDelta())
 	for {
-		<-ticker
+		select {
+		case <-ticker:
+		case <-ctx.Done():
+			logger.Info("%s cronjob stopped", c.Name())
+			return
+		}
 
-		err := c.Call()
+		err := c.Call(ctx)
+		if ctx.Err() != nil {
+			if err != nil {
+				logger.Warn("%s cronjob interrupted: %v", c.Name(), err)
+			}
+			logger.Info("%s cronjob stopped", c.Name())
+			return
+		}
 		if err == nil {
 			c.UpdateCronjobStatus(shared.HealthStatusOk)
 		} else {
@@ -56,6 +74,23 @@ const (
 	defaultEpochBatchSize int64 = 100
 )
 
+type migrationsDB interface {
+	FetchFailedMigrations() ([]database.Migration, error)
+}
+
+// Cronjobs submitting data derived from the database (voting, mirroring, uptime voting) refuse to start while
+// a migration is failed or interrupted, the data may be incomplete until it is resolved
+func checkMigrations(db migrationsDB) error {
+	failed, err := db.FetchFailedMigrations()
+	if err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		return errors.Errorf("migration %s is %s, resolve it with the migrate command", failed[0].Version, failed[0].Status)
+	}
+	return nil
+}
+
 type epochCronjob struct {
 	enabled   bool
 	timeout   time.Duration // call cronjob every "timeout"
@@ -1,9 +1,8 @@
-//go:build integration
-// +build integration
-
 package cronjob
 
 import (
+	globalConfig "flare-indexer/config"
+	"flare-indexer/indexer/config"
 	"flare-indexer/utils/chain"
 	"log"
 	"testing"
@@ -20,7 +19,21 @@ var (
 	testUptimeClient *chain.RecordedUptimeClient
 )
 
+// Global config of tests without a test context, tests building a test context set their own
+// config and restore this one
+var unitTestConfig = config.Config{
+	Chain: globalConfig.ChainConfig{
+		ChainAddressHRP: "costwo",
+	},
+	Logger: globalConfig.LoggerConfig{
+		Level:   "DEBUG",
+		Console: true,
+	},
+}
+
 func TestMain(m *testing.M) {
+	globalConfig.GlobalConfigCallback.Call(unitTestConfig)
+
 	var err error
 	testUptimeClient, err = chain.UptimeTestClient()
 	if err != nil {
@@ -11,10 +11,11 @@ import (
 func init() {
 	migrations.Container.Add("2023-08-25-00-00", "Create initial state for voting cronjob", createVotingCronjobState)
 	migrations.Container.Add("2023-08-30-00-00", "Create initial state for mirror cronjob", createMirrorCronjobState)
+	migrations.Container.AddWithDown("2026-10-18-07-01", "Create initial states for transfer cronjob", createTransferCronjobStates, deleteTransferCronjobStates)
 }
 
 func createVotingCronjobState(db *gorm.DB) error {
-	return database.CreateState(db, &database.State{
+	return database.CreateStateIfMissing(db, &database.State{
 		Name:           votingStateName,
 		NextDBIndex:    0,
 		LastChainIndex: 0,
@@ -23,10 +24,30 @@ func createVotingCronjobState(db *gorm.DB) error {
 }
 
 func createMirrorCronjobState(db *gorm.DB) error {
-	return database.CreateState(db, &database.State{
+	return database.CreateStateIfMissing(db, &database.State{
 		Name:           mirrorStateName,
 		NextDBIndex:    0,
 		LastChainIndex: 0,
 		Updated:        time.Now(),
 	})
 }
+
+func createTransferCronjobStates(db *gorm.DB) error {
+	for _, name := range transferStateNames {
+		err := database.CreateStateIfMissing(db, &database.State{
+			Name:           name,
+			NextDBIndex:    0,
+			LastChainIndex: 0,
+			Updated:        time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Transfers are read again from the start when the states are created again
+func deleteTransferCronjobStates(db *gorm.DB) error {
+	return db.Where("name IN ?", transferStateNames).Delete(&database.State{}).Error
+}
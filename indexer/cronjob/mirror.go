@@ -1,6 +1,7 @@
 package cronjob
 
 import (
+	"context"
 	"flare-indexer/database"
 	indexerctx "flare-indexer/indexer/context"
 	"flare-indexer/logger"
@@ -31,6 +32,7 @@ type mirrorCronJob struct {
 
 type mirrorDB interface {
 	FetchState(name string) (database.State, error)
+	FetchFailedMigrations() ([]database.Migration, error)
 	UpdateJobState(epoch int64, force bool) error
 	GetPChainTxsForEpoch(start, end time.Time) ([]database.PChainTxData, error)
 	GetPChainTx(txID string, address string) (*database.PChainTxData, error)
@@ -89,10 +91,10 @@ func (c *mirrorCronJob) Name() string {
 }
 
 func (c *mirrorCronJob) OnStart() error {
-	return nil
+	return checkMigrations(c.db)
 }
 
-func (c *mirrorCronJob) Call() error {
+func (c *mirrorCronJob) Call(ctx context.Context) error {
 	epochRange, err := c.getEpochRange()
 	if err != nil {
 		if errors.Is(err, errNoEpochsToMirror) {
@@ -106,21 +108,30 @@ func (c *mirrorCronJob) Call() error {
 	logger.Debug("mirroring epochs %d-%d", epochRange.start, epochRange.end)
 	c.updateLastEpochMetrics(epochRange.end)
 
+	lastMirrored := epochRange.start - 1
 	for epoch := epochRange.start; epoch <= epochRange.end; epoch++ {
+		// Stop between epochs, persisting the progress made so far
+		if ctx.Err() != nil {
+			break
+		}
 		logger.Debug("mirroring epoch %d", epoch)
 		if err := c.mirrorEpoch(epoch); err != nil {
 			return err
 		}
+		lastMirrored = epoch
+	}
+	if lastMirrored < epochRange.start {
+		return ctx.Err()
 	}
 
-	logger.Debug("successfully mirrored epochs %d-%d", epochRange.start, epochRange.end)
+	logger.Debug("successfully mirrored epochs %d-%d", epochRange.start, lastMirrored)
 
-	if err := c.db.UpdateJobState(epochRange.end+1, false); err != nil {
+	if err := c.db.UpdateJobState(lastMirrored+1, false); err != nil {
 		return err
 	}
-	c.updateLastProcessedEpochMetrics(epochRange.end)
+	c.updateLastProcessedEpochMetrics(lastMirrored)
 
-	return nil
+	return ctx.Err()
 }
 
 var errNoEpochsToMirror = errors.New("no epochs to mirror")
@@ -337,7 +348,7 @@ func (c *mirrorCronJob) registerAddress(txID string, address string) error {
 		return errors.Wrap(err, "chain.ParseAddress")
 	}
 
-	publicKey, err := chain.PublicKeyFromPChainBlock(txID, addrBytes, tx.InputIndex, tx.Bytes)
+	publicKey, err := chain.PublicKeyFromPChainBlock(txID, addrBytes, tx.InputIndex, tx.BlockBytes)
 	if err != nil {
 		return err
 	}
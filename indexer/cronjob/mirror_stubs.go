@@ -34,6 +34,10 @@ func (m mirrorDBGorm) FetchState(name string) (database.State, error) {
 	return database.FetchState(m.db, name)
 }
 
+func (m mirrorDBGorm) FetchFailedMigrations() ([]database.Migration, error) {
+	return database.FetchFailedMigrations(m.db)
+}
+
 func (m mirrorDBGorm) UpdateJobState(epoch int64, force bool) error {
 	return m.db.Transaction(func(tx *gorm.DB) error {
 		jobState, err := database.FetchState(tx, mirrorStateName)
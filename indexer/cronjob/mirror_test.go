@@ -1,12 +1,8 @@
-//go:build !integration
-// +build !integration
-
 package cronjob
 
 import (
-	globalConfig "flare-indexer/config"
+	"context"
 	"flare-indexer/database"
-	"flare-indexer/indexer/config"
 	"flare-indexer/indexer/pchain"
 	"flare-indexer/utils/contracts/mirroring"
 	"flare-indexer/utils/staking"
@@ -27,21 +23,6 @@ var epochInfo = staking.EpochInfo{
 	Start:  time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
 }
 
-func TestMain(m *testing.M) {
-	cfg := config.Config{
-		Chain: globalConfig.ChainConfig{
-			ChainAddressHRP: "costwo",
-		},
-		Logger: globalConfig.LoggerConfig{
-			Level:   "DEBUG",
-			Console: true,
-		},
-	}
-	globalConfig.GlobalConfigCallback.Call(cfg)
-
-	m.Run()
-}
-
 func TestOneTransaction(t *testing.T) {
 	startTime := epochInfo.GetStartTime(3)
 	endTime := epochInfo.GetEndTime(999)
@@ -253,7 +234,7 @@ func testMirror(
 		registeredAddresses: mapset.NewSet[string](),
 	}
 
-	err := j.Call()
+	err := j.Call(context.Background())
 	require.NoError(t, err)
 
 	cupaloy.SnapshotT(t, contracts.mirroredStakes)
@@ -276,6 +257,10 @@ func (db testDB) FetchState(name string) (database.State, error) {
 	return state, nil
 }
 
+func (db testDB) FetchFailedMigrations() ([]database.Migration, error) {
+	return nil, nil
+}
+
 func (db testDB) UpdateJobState(epoch int64, force bool) error {
 	db.states[mirrorStateName] = database.State{
 		Name:        mirrorStateName,
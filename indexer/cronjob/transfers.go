@@ -0,0 +1,334 @@
+package cronjob
+
+import (
+	"context"
+	"flare-indexer/database"
+	"flare-indexer/indexer/config"
+	indexerctx "flare-indexer/indexer/context"
+	"flare-indexer/indexer/shared"
+	"flare-indexer/logger"
+	"flare-indexer/utils/chain"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/constants"
+	"github.com/ava-labs/avalanchego/vms/avm/txs"
+	"github.com/ava-labs/avalanchego/wallet/chain/x/builder"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+const (
+	transferCronjobName = "transfer_cronjob"
+
+	// States with the next ID of the rows read by the transfer cronjob
+	transferPChainExportsState = "transfer_cronjob_p_chain_exports"
+	transferPChainImportsState = "transfer_cronjob_p_chain_imports"
+	transferXChainExportsState = "transfer_cronjob_x_chain_exports"
+	transferXChainImportsState = "transfer_cronjob_x_chain_imports"
+)
+
+var transferStateNames = []string{
+	transferPChainExportsState,
+	transferPChainImportsState,
+	transferXChainExportsState,
+	transferXChainImportsState,
+}
+
+// Cronjob reconciling transfers between the P-chain and the X-chain or C-chain. Outputs exported
+// by P-chain and X-chain ExportTxs are paired with imported inputs of P-chain and X-chain
+// ImportTxs spending them. Exports and imports are read in the order they were indexed, they
+// may be indexed in any order. Transfers to the C-chain are never completed since C-chain
+// imports are not indexed.
+type transferCronjob struct {
+	config config.TransferConfig
+	db     *gorm.DB
+
+	// Imported inputs are stored by the P-chain indexer
+	pChainImports bool
+	// X-chain transactions are stored by the X-chain indexers
+	xChainIndexed bool
+
+	xChainID      string // fetched on the first call if X-chain transactions are indexed
+	fetchXChainID func(ctx context.Context) (ids.ID, error)
+
+	metrics *shared.MetricsBase
+}
+
+// Source of exports or imports read by the transfer cronjob
+type transferSource struct {
+	stateName string
+	fetch     func(db *gorm.DB, fromID uint64, limit int) ([]database.CrossChainUTXO, error)
+	transfers func(utxos []database.CrossChainUTXO) []*database.CrossChainTransfer
+	upsert    func(db *gorm.DB, transfers []*database.CrossChainTransfer) error
+}
+
+func NewTransferCronjob(ctx indexerctx.IndexerContext) Cronjob {
+	cfg := ctx.Config()
+	return &transferCronjob{
+		config:        cfg.TransferCronjob,
+		db:            ctx.DB(),
+		pChainImports: cfg.PChainIndexer.Enabled && cfg.PChainIndexer.ImportedInputs,
+		xChainIndexed: cfg.XChainIndexer.Enabled || cfg.XChainBlockIndexer.Enabled,
+		fetchXChainID: func(ctx context.Context) (ids.ID, error) {
+			return chain.FetchBlockchainID(ctx, &cfg.Chain, "X")
+		},
+		metrics: shared.NewMetricsBase(transferCronjobName),
+	}
+}
+
+func (c *transferCronjob) Name() string {
+	return transferCronjobName
+}
+
+func (c *transferCronjob) Timeout() time.Duration {
+	return c.config.Timeout
+}
+
+func (c *transferCronjob) Enabled() bool {
+	return c.config.Enabled
+}
+
+func (c *transferCronjob) RandomTimeoutDelta() time.Duration {
+	return 0
+}
+
+func (c *transferCronjob) UpdateCronjobStatus(status shared.HealthStatus) {
+	if c.metrics != nil {
+		c.metrics.SetStatus(status)
+	}
+}
+
+func (c *transferCronjob) OnStart() error {
+	return nil
+}
+
+func (c *transferCronjob) Call(ctx context.Context) error {
+	if c.xChainIndexed && c.xChainID == "" {
+		id, err := c.fetchXChainID(ctx)
+		if err != nil {
+			return errors.Wrap(err, "failed to fetch X-chain ID")
+		}
+		c.xChainID = id.String()
+	}
+	caughtUp := make(map[string]bool)
+	for _, source := range c.sources() {
+		done, err := c.processSource(source)
+		if err != nil {
+			return err
+		}
+		caughtUp[source.stateName] = done
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+	return c.flagOrphans(caughtUp)
+}
+
+// Sources of exports and imports, X-chain sources are included if X-chain transactions are indexed
+func (c *transferCronjob) sources() []transferSource {
+	pChainID := constants.PlatformChainID.String()
+	sources := []transferSource{
+		{
+			stateName: transferPChainExportsState,
+			fetch:     database.FetchPChainCrossChainExports,
+			transfers: func(utxos []database.CrossChainUTXO) []*database.CrossChainTransfer {
+				return exportTransfers(pChainID, utxos)
+			},
+			upsert: database.UpsertCrossChainTransferExports,
+		},
+		{
+			stateName: transferPChainImportsState,
+			fetch:     database.FetchPChainCrossChainImports,
+			transfers: func(utxos []database.CrossChainUTXO) []*database.CrossChainTransfer {
+				return importTransfers(pChainID, utxos)
+			},
+			upsert: database.UpsertCrossChainTransferImports,
+		},
+	}
+	if !c.xChainIndexed {
+		return sources
+	}
+	return append(sources,
+		transferSource{
+			stateName: transferXChainExportsState,
+			fetch: func(db *gorm.DB, fromID uint64, limit int) ([]database.CrossChainUTXO, error) {
+				return database.FetchXChainCrossChainExports(db, pChainID, fromID, limit)
+			},
+			transfers: func(utxos []database.CrossChainUTXO) []*database.CrossChainTransfer {
+				return exportTransfers(c.xChainID, utxos)
+			},
+			upsert: database.UpsertCrossChainTransferExports,
+		},
+		transferSource{
+			stateName: transferXChainImportsState,
+			fetch: func(db *gorm.DB, fromID uint64, limit int) ([]database.CrossChainUTXO, error) {
+				txs, err := database.FetchXChainCrossChainImportTxs(db, pChainID, fromID, limit)
+				if err != nil {
+					return nil, err
+				}
+				var utxos []database.CrossChainUTXO
+				for _, tx := range txs {
+					txUTXOs, err := xChainImportedUTXOs(&tx)
+					if err != nil {
+						return nil, err
+					}
+					utxos = append(utxos, txUTXOs...)
+				}
+				return utxos, nil
+			},
+			transfers: func(utxos []database.CrossChainUTXO) []*database.CrossChainTransfer {
+				return importTransfers(c.xChainID, utxos)
+			},
+			upsert: database.UpsertCrossChainTransferImports,
+		},
+	)
+}
+
+// Read the next batch of the source and store its transfers together with the updated state.
+// Returns true if all indexed rows of the source were read.
+func (c *transferCronjob) processSource(source transferSource) (bool, error) {
+	caughtUp := false
+	err := c.db.Transaction(func(db *gorm.DB) error {
+		state, err := database.FetchState(db, source.stateName)
+		if err != nil {
+			return errors.Wrapf(err, "failed to fetch state %s", source.stateName)
+		}
+		utxos, err := source.fetch(db, state.NextDBIndex, int(c.config.BatchSize))
+		if err != nil {
+			return err
+		}
+		caughtUp = len(utxos) < int(c.config.BatchSize)
+		if len(utxos) == 0 {
+			return nil
+		}
+		transfers := source.transfers(utxos)
+		if err := source.upsert(db, transfers); err != nil {
+			return err
+		}
+		logger.Debug("%s: stored %d transfers from %s", transferCronjobName, len(transfers), source.stateName)
+
+		state.Update(utxos[len(utxos)-1].ID+1, 0)
+		return database.UpdateState(db, &state)
+	})
+	return caughtUp, err
+}
+
+// Flag transfers which were not imported in the configured time. Only transfers to chains
+// whose imports are indexed and read up to date (caughtUp by state name) are flagged. The time
+// is measured up to the last block indexed on the destination chain, so that imports lagging
+// behind are not flagged.
+func (c *transferCronjob) flagOrphans(caughtUp map[string]bool) error {
+	if c.config.OrphanTimeout <= 0 {
+		return nil
+	}
+	if c.pChainImports && caughtUp[transferPChainImportsState] {
+		err := c.flagOrphansTo(constants.PlatformChainID.String(), database.FetchPChainLastBlockTime)
+		if err != nil {
+			return err
+		}
+	}
+	if c.xChainIndexed && caughtUp[transferXChainImportsState] {
+		return c.flagOrphansTo(c.xChainID, database.FetchXChainLastBlockTime)
+	}
+	return nil
+}
+
+// Flag transfers to destination exported more than the orphan timeout before the last indexed
+// time of the destination chain
+func (c *transferCronjob) flagOrphansTo(destination string, lastIndexedTime func(db *gorm.DB) (*time.Time, error)) error {
+	lastTime, err := lastIndexedTime(c.db)
+	if err != nil {
+		return errors.Wrapf(err, "failed to fetch last indexed time of chain %s", destination)
+	}
+	if lastTime == nil {
+		return nil
+	}
+	n, err := database.FlagOrphanedCrossChainTransfers(c.db, destination, lastTime.Add(-c.config.OrphanTimeout))
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		logger.Info("%s: flagged %d transfers to %s as orphaned", transferCronjobName, n, destination)
+	}
+	return nil
+}
+
+// Pending transfers of exported outputs of sourceChain. Outputs with multiple owner addresses
+// have multiple rows, the transfer has the first address.
+func exportTransfers(sourceChain string, utxos []database.CrossChainUTXO) []*database.CrossChainTransfer {
+	return newTransfers(utxos, func(utxo *database.CrossChainUTXO) *database.CrossChainTransfer {
+		return &database.CrossChainTransfer{
+			ExportTxID:       utxo.OutTxID,
+			OutIdx:           utxo.OutIdx,
+			SourceChain:      sourceChain,
+			DestinationChain: utxo.ChainID,
+			Amount:           utxo.Amount,
+			Address:          utxo.Address,
+			ExportTime:       utxo.Time,
+			Status:           database.CrossChainTransferPending,
+		}
+	})
+}
+
+// Completed transfers of imported inputs of destinationChain
+func importTransfers(destinationChain string, utxos []database.CrossChainUTXO) []*database.CrossChainTransfer {
+	return newTransfers(utxos, func(utxo *database.CrossChainUTXO) *database.CrossChainTransfer {
+		importTxID := utxo.TxID
+		return &database.CrossChainTransfer{
+			ExportTxID:       utxo.OutTxID,
+			OutIdx:           utxo.OutIdx,
+			SourceChain:      utxo.ChainID,
+			DestinationChain: destinationChain,
+			Amount:           utxo.Amount,
+			Address:          utxo.Address,
+			ImportTxID:       &importTxID,
+			ImportTime:       utxo.Time,
+			Status:           database.CrossChainTransferCompleted,
+		}
+	})
+}
+
+// One transfer per exported UTXO, created from the first row of the UTXO
+func newTransfers(
+	utxos []database.CrossChainUTXO,
+	create func(utxo *database.CrossChainUTXO) *database.CrossChainTransfer,
+) []*database.CrossChainTransfer {
+	transfers := make([]*database.CrossChainTransfer, 0, len(utxos))
+	seen := make(map[shared.IdIndexKey]bool, len(utxos))
+	for i := range utxos {
+		key := shared.NewIdIndexKey(utxos[i].OutTxID, utxos[i].OutIdx)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		transfers = append(transfers, create(&utxos[i]))
+	}
+	return transfers
+}
+
+// Imported inputs of X-chain ImportTx. Addresses of imported inputs are not known, they are
+// set by the export.
+func xChainImportedUTXOs(tx *database.XChainTimedTx) ([]database.CrossChainUTXO, error) {
+	parsedTx, err := builder.Parser.ParseTx(tx.Bytes)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse X-chain transaction %s", tx.TxID)
+	}
+	importTx, ok := parsedTx.Unsigned.(*txs.ImportTx)
+	if !ok {
+		return nil, errors.Errorf("X-chain transaction %s is not an import transaction", tx.TxID)
+	}
+	utxos := make([]database.CrossChainUTXO, len(importTx.ImportedIns))
+	for i, in := range importTx.ImportedIns {
+		utxos[i] = database.CrossChainUTXO{
+			ID:      tx.ID,
+			TxID:    tx.TxID,
+			OutTxID: in.TxID.String(),
+			OutIdx:  in.OutputIndex,
+			Amount:  in.In.Amount(),
+			ChainID: tx.ChainID,
+			Time:    tx.Time,
+		}
+	}
+	return utxos, nil
+}
@@ -0,0 +1,198 @@
+package cronjob
+
+import (
+	"flare-indexer/database"
+	"flare-indexer/indexer/config"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/constants"
+	"github.com/ava-labs/avalanchego/vms/avm/txs"
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+	"github.com/ava-labs/avalanchego/wallet/chain/x/builder"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransfers(t *testing.T) {
+	exportTime := time.Unix(100, 0)
+	utxos := []database.CrossChainUTXO{
+		{ID: 1, TxID: "export", OutTxID: "export", OutIdx: 1, Amount: 10, Address: "a", ChainID: "X", Time: &exportTime},
+		// Second owner address of the same output
+		{ID: 2, TxID: "export", OutTxID: "export", OutIdx: 1, Amount: 10, Address: "b", ChainID: "X", Time: &exportTime},
+		{ID: 3, TxID: "export", OutTxID: "export", OutIdx: 2, Amount: 20, Address: "c", ChainID: "X", Time: &exportTime},
+	}
+	transfers := exportTransfers("P", utxos)
+	require.Len(t, transfers, 2)
+	require.Equal(t, database.CrossChainTransfer{
+		ExportTxID:       "export",
+		OutIdx:           1,
+		SourceChain:      "P",
+		DestinationChain: "X",
+		Amount:           10,
+		Address:          "a",
+		ExportTime:       &exportTime,
+		Status:           database.CrossChainTransferPending,
+	}, *transfers[0])
+	require.Equal(t, uint32(2), transfers[1].OutIdx)
+
+	importTime := time.Unix(200, 0)
+	transfers = importTransfers("X", []database.CrossChainUTXO{
+		{ID: 5, TxID: "import", OutTxID: "export", OutIdx: 1, Amount: 10, ChainID: "P", Time: &importTime},
+	})
+	require.Len(t, transfers, 1)
+	require.Equal(t, "import", *transfers[0].ImportTxID)
+	require.Equal(t, &importTime, transfers[0].ImportTime)
+	require.Nil(t, transfers[0].ExportTime)
+	require.Equal(t, "P", transfers[0].SourceChain)
+	require.Equal(t, database.CrossChainTransferCompleted, transfers[0].Status)
+}
+
+func TestXChainImportedUTXOs(t *testing.T) {
+	exportTxID := ids.GenerateTestID()
+	tx := &txs.Tx{Unsigned: &txs.ImportTx{
+		SourceChain: constants.PlatformChainID,
+		ImportedIns: []*avax.TransferableInput{{
+			UTXOID: avax.UTXOID{TxID: exportTxID, OutputIndex: 3},
+			In:     &secp256k1fx.TransferInput{Amt: 30},
+		}},
+	}}
+	require.NoError(t, tx.Initialize(builder.Parser.Codec()))
+
+	txTime := time.Unix(300, 0)
+	dbTx := &database.XChainTimedTx{
+		XChainTx: database.XChainTx{
+			BaseEntity: database.BaseEntity{ID: 7},
+			Type:       database.XChainImportTx,
+			TxID:       tx.ID().String(),
+			ChainID:    constants.PlatformChainID.String(),
+			Bytes:      tx.Bytes(),
+		},
+		Time: &txTime,
+	}
+	utxos, err := xChainImportedUTXOs(dbTx)
+	require.NoError(t, err)
+	require.Equal(t, []database.CrossChainUTXO{{
+		ID:      7,
+		TxID:    tx.ID().String(),
+		OutTxID: exportTxID.String(),
+		OutIdx:  3,
+		Amount:  30,
+		ChainID: constants.PlatformChainID.String(),
+		Time:    &txTime,
+	}}, utxos)
+
+	// Transaction which is not an import
+	baseTx := &txs.Tx{Unsigned: &txs.BaseTx{}}
+	require.NoError(t, baseTx.Initialize(builder.Parser.Codec()))
+	dbTx.Bytes = baseTx.Bytes()
+	_, err = xChainImportedUTXOs(dbTx)
+	require.Error(t, err)
+}
+
+func TestTransferCronjob(t *testing.T) {
+	cfg := database.TestDBConfig("flare_indexer_indexer")
+	db, err := database.ConnectAndInitializeTestDB(&cfg, true)
+	require.NoError(t, err)
+	for _, name := range transferStateNames {
+		require.NoError(t, database.CreateState(db, &database.State{Name: name}))
+	}
+
+	xChainID := ids.GenerateTestID().String()
+	exportTime := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	exportTxID, importTxID, xExportTxID := "export", "import", ids.GenerateTestID().String()
+	require.NoError(t, database.CreatePChainEntities(db,
+		[]*database.PChainBlock{{Height: 1, BlockID: "block1", BlockTime: &exportTime}},
+		[]*database.PChainTx{
+			{TxID: &exportTxID, Type: database.PChainExportTx, BlockID: "block1", BlockHeight: 1, ChainID: xChainID, BlockTime: &exportTime},
+			{TxID: &importTxID, Type: database.PChainImportTx, BlockID: "block1", BlockHeight: 1, ChainID: xChainID, BlockTime: &exportTime},
+		},
+		[]*database.PChainTxInput{{
+			TxInput: database.TxInput{TxID: importTxID, OutTxID: xExportTxID, OutIdx: 0, Amount: 5},
+			Type:    database.PChainImportedInput,
+		}},
+		[]*database.PChainTxOutput{
+			{TxOutput: database.TxOutput{TxID: exportTxID, Idx: 0, Amount: 10, Address: "a"}, Type: database.PChainExportedOutput},
+			{TxOutput: database.TxOutput{TxID: exportTxID, Idx: 1, Amount: 20, Address: "b"}, Type: database.PChainExportedOutput},
+		},
+	))
+
+	c := &transferCronjob{
+		config:        config.TransferConfig{CronjobConfig: config.CronjobConfig{BatchSize: 1}, OrphanTimeout: time.Hour},
+		db:            db,
+		pChainImports: true,
+		xChainIndexed: true,
+		xChainID:      xChainID,
+	}
+	sources := c.sources()
+	require.Equal(t, transferPChainExportsState, sources[0].stateName)
+
+	// Exports are read in batches, the source is caught up when a batch is not full
+	caughtUp, err := c.processSource(sources[0])
+	require.NoError(t, err)
+	require.False(t, caughtUp)
+	caughtUp, err = c.processSource(sources[0])
+	require.NoError(t, err)
+	require.False(t, caughtUp)
+	caughtUp, err = c.processSource(sources[0])
+	require.NoError(t, err)
+	require.True(t, caughtUp)
+	transfers, err := database.FetchCrossChainTransfers(db, "", exportTxID, "", 0, 10)
+	require.NoError(t, err)
+	require.Len(t, transfers, 2)
+	require.Equal(t, database.CrossChainTransferPending, transfers[0].Status)
+	require.Equal(t, xChainID, transfers[0].DestinationChain)
+
+	// Import of an X-chain export completes its transfer
+	_, err = c.processSource(sources[1])
+	require.NoError(t, err)
+	transfers, err = database.FetchCrossChainTransfers(db, "", importTxID, "", 0, 10)
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+	require.Equal(t, database.CrossChainTransferCompleted, transfers[0].Status)
+	require.Equal(t, xExportTxID, transfers[0].ExportTxID)
+
+	xChainCaughtUp := map[string]bool{transferXChainImportsState: true}
+	orphaned := func() int {
+		transfers, err := database.FetchCrossChainTransfers(db, "", "", database.CrossChainTransferOrphaned, 0, 10)
+		require.NoError(t, err)
+		return len(transfers)
+	}
+
+	// Nothing is indexed on the X-chain
+	require.NoError(t, c.flagOrphans(xChainCaughtUp))
+	require.Zero(t, orphaned())
+
+	// X-chain is indexed up to less than the orphan timeout after the export
+	require.NoError(t, database.CreateXChainBlocks(db, []*database.XChainBlock{
+		{BlockID: "xblock1", BlockIndex: 1, Height: 1, BlockTime: exportTime.Add(30 * time.Minute)},
+	}))
+	require.NoError(t, c.flagOrphans(xChainCaughtUp))
+	require.Zero(t, orphaned())
+
+	// X-chain is indexed past the orphan timeout, but its imports were not read up to date
+	require.NoError(t, database.CreateXChainBlocks(db, []*database.XChainBlock{
+		{BlockID: "xblock2", BlockIndex: 2, Height: 2, BlockTime: exportTime.Add(2 * time.Hour)},
+	}))
+	require.NoError(t, c.flagOrphans(map[string]bool{}))
+	require.Zero(t, orphaned())
+
+	require.NoError(t, c.flagOrphans(xChainCaughtUp))
+	require.Equal(t, 2, orphaned())
+
+	// X-chain export has the time of its block
+	blockHeight := uint64(2)
+	require.NoError(t, database.CreateXChainEntities(db, nil,
+		[]*database.XChainTx{{Type: database.XChainExportTx, TxID: xExportTxID, BlockHeight: &blockHeight, ChainID: constants.PlatformChainID.String()}},
+		nil,
+		[]*database.XChainTxOutput{{TxOutput: database.TxOutput{TxID: xExportTxID, Amount: 5, Address: "c"}, Type: database.XChainExportedOutput}},
+	))
+	_, err = c.processSource(sources[2])
+	require.NoError(t, err)
+	transfers, err = database.FetchCrossChainTransfers(db, "", xExportTxID, "", 0, 10)
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+	require.Equal(t, database.CrossChainTransferCompleted, transfers[0].Status)
+	require.True(t, exportTime.Add(2*time.Hour).Equal(*transfers[0].ExportTime))
+}
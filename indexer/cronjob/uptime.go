@@ -1,11 +1,11 @@
 package cronjob
 
 import (
+	"context"
 	"flare-indexer/database"
 	"flare-indexer/indexer/config"
-	"flare-indexer/indexer/context"
+	indexerctx "flare-indexer/indexer/context"
 	"flare-indexer/indexer/shared"
-	"flare-indexer/utils"
 	"flare-indexer/utils/chain"
 	"time"
 
@@ -22,12 +22,11 @@ type uptimeCronjob struct {
 	metrics *shared.MetricsBase
 }
 
-func NewUptimeCronjob(ctx context.IndexerContext) Cronjob {
-	endpoint := utils.JoinPaths(ctx.Config().Chain.NodeURL, "ext/bc/P"+chain.RPCClientOptions(ctx.Config().Chain.ApiKey))
+func NewUptimeCronjob(ctx indexerctx.IndexerContext) Cronjob {
 	return &uptimeCronjob{
 		config:  ctx.Config().UptimeCronjob,
 		db:      ctx.DB(),
-		client:  chain.NewAvalancheUptimeClient(endpoint),
+		client:  chain.NewUptimeClientFromConfig(&ctx.Config().Chain),
 		metrics: shared.NewMetricsBase(uptimeCronjobName),
 	}
 }
@@ -63,8 +62,8 @@ func (c *uptimeCronjob) OnStart() error {
 	return database.CreateUptimeCronjobEntry(c.db, entities)
 }
 
-func (c *uptimeCronjob) Call() error {
-	validators, status, err := c.client.GetValidatorStatus()
+func (c *uptimeCronjob) Call(ctx context.Context) error {
+	validators, status, err := c.client.GetValidatorStatus(ctx)
 	if err != nil {
 		return err
 	}
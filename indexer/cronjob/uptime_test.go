@@ -1,13 +1,11 @@
-//go:build integration
-// +build integration
-
 package cronjob
 
 import (
+	"context"
 	globalConfig "flare-indexer/config"
 	"flare-indexer/database"
 	"flare-indexer/indexer/config"
-	"flare-indexer/indexer/context"
+	indexerctx "flare-indexer/indexer/context"
 	"testing"
 	"time"
 )
@@ -24,21 +22,14 @@ func uptimeCronjobTestConfig() *config.Config {
 				Timeout: 60 * time.Second,
 			},
 		},
-		DB: globalConfig.DBConfig{
-			Username:   database.MysqlTestUser,
-			Password:   database.MysqlTestPassword,
-			Host:       database.MysqlTestHost,
-			Port:       database.MysqlTestPort,
-			Database:   "flare_indexer_indexer",
-			LogQueries: false,
-		},
+		DB: database.TestDBConfig("flare_indexer_indexer"),
 	}
 	return cfg
 
 }
 
 func createTestUptimeCronjob() (*uptimeCronjob, error) {
-	ctx, err := context.BuildTestContext(uptimeCronjobTestConfig())
+	ctx, err := indexerctx.BuildTestContext(uptimeCronjobTestConfig())
 	if err != nil {
 		return nil, err
 	}
@@ -50,6 +41,7 @@ func createTestUptimeCronjob() (*uptimeCronjob, error) {
 }
 
 func TestUptime(t *testing.T) {
+	t.Cleanup(func() { globalConfig.GlobalConfigCallback.Call(unitTestConfig) })
 	cronjob, err := createTestUptimeCronjob()
 	if err != nil {
 		t.Fatal(err)
@@ -59,7 +51,7 @@ func TestUptime(t *testing.T) {
 	testUptimeClient.SetNow(now)
 
 	for i := 0; i < 100; i++ {
-		if err := cronjob.Call(); err != nil {
+		if err := cronjob.Call(context.Background()); err != nil {
 			t.Fatal(err)
 		}
 		testUptimeClient.Time.AdvanceNow(30 * time.Second)
@@ -1,9 +1,10 @@
 package cronjob
 
 import (
+	"context"
 	globalConfig "flare-indexer/config"
 	"flare-indexer/database"
-	"flare-indexer/indexer/context"
+	indexerctx "flare-indexer/indexer/context"
 	"flare-indexer/logger"
 	"flare-indexer/utils"
 	"flare-indexer/utils/contracts/voting"
@@ -51,7 +52,7 @@ type uptimeVotingCronjob struct {
 	time utils.ShiftedTime
 }
 
-func NewUptimeVotingCronjob(ctx context.IndexerContext) (*uptimeVotingCronjob, error) {
+func NewUptimeVotingCronjob(ctx indexerctx.IndexerContext) (*uptimeVotingCronjob, error) {
 	cfg := ctx.Config()
 
 	if !cfg.UptimeCronjob.Enabled || !cfg.UptimeCronjob.EnableVoting {
@@ -109,10 +110,10 @@ func (c *uptimeVotingCronjob) Enabled() bool {
 }
 
 func (c *uptimeVotingCronjob) OnStart() error {
-	return nil
+	return checkMigrations(&votingDBGorm{g: c.db})
 }
 
-func (c *uptimeVotingCronjob) Call() error {
+func (c *uptimeVotingCronjob) Call(ctx context.Context) error {
 	now := c.time.Now()
 	epochRange, err := c.aggregationRange(now)
 	if err != nil {
@@ -128,6 +129,11 @@ func (c *uptimeVotingCronjob) Call() error {
 
 	// Aggregate missing epochs for all nodes
 	for epoch := epochRange.start; epoch <= epochRange.end; epoch++ {
+		// Stop between epochs, aggregations of submitted epochs are still persisted
+		if ctx.Err() != nil {
+			logger.Info("Uptime aggregation interrupted before epoch %d", epoch)
+			break
+		}
 		nodeAggregations, err := c.aggregateEpoch(epoch)
 		if err != nil {
 			return err
@@ -4,10 +4,11 @@
 package cronjob
 
 import (
+	"context"
 	globalConfig "flare-indexer/config"
 	"flare-indexer/database"
 	"flare-indexer/indexer/config"
-	"flare-indexer/indexer/context"
+	indexerctx "flare-indexer/indexer/context"
 	"flare-indexer/indexer/pchain"
 	"flare-indexer/indexer/shared"
 	"flare-indexer/utils"
@@ -51,21 +52,14 @@ func uptimeVotingCronjobTestConfig(epochStart time.Time) *config.Config {
 			BatchSize:  200,
 			StartIndex: 0,
 		},
-		DB: globalConfig.DBConfig{
-			Username:   database.MysqlTestUser,
-			Password:   database.MysqlTestPassword,
-			Host:       database.MysqlTestHost,
-			Port:       database.MysqlTestPort,
-			Database:   "flare_indexer_indexer",
-			LogQueries: false,
-		},
+		DB: database.TestDBConfig("flare_indexer_indexer"),
 	}
 	return cfg
 
 }
 
 func createTestUptimeVotingCronjob(epochStart time.Time) (*uptimeVotingCronjob, *shared.ChainIndexerBase, error) {
-	ctx, err := context.BuildTestContext(uptimeVotingCronjobTestConfig(epochStart))
+	ctx, err := indexerctx.BuildTestContext(uptimeVotingCronjobTestConfig(epochStart))
 	if err != nil {
 		return nil, nil, err
 	}
@@ -99,16 +93,16 @@ func TestUptimeVoting(t *testing.T) {
 	require.NoError(t, err)
 
 	// Run indexer to allow uptime client test to fetch validator data
-	err = indexer.IndexBatch()
+	err = indexer.IndexBatch(context.Background())
 	require.NoError(t, err)
 
 	testUptimeClient.SetNow(now)
 	votingCronjob.time.SetNow(now)
 	for i := 0; i < 10; i++ {
-		if err := uptimeCronjob.Call(); err != nil {
+		if err := uptimeCronjob.Call(context.Background()); err != nil {
 			t.Fatal(err)
 		}
-		if err := votingCronjob.Call(); err != nil {
+		if err := votingCronjob.Call(context.Background()); err != nil {
 			t.Fatal(err)
 		}
 		testUptimeClient.Time.AdvanceNow(10 * time.Second)
@@ -1,6 +1,7 @@
 package cronjob
 
 import (
+	"context"
 	"flare-indexer/database"
 	indexerctx "flare-indexer/indexer/context"
 	"flare-indexer/indexer/pchain"
@@ -38,6 +39,8 @@ type votingCronjob struct {
 
 type votingDB interface {
 	FetchState(name string) (database.State, error)
+	FetchLastIndexerDiagnostic(stateName string) (*database.IndexerDiagnostic, error)
+	FetchFailedMigrations() ([]database.Migration, error)
 	FetchPChainVotingData(start, end time.Time) ([]database.PChainTxData, error)
 	UpdateState(state *database.State) error
 }
@@ -88,19 +91,29 @@ func (c *votingCronjob) Name() string {
 }
 
 func (c *votingCronjob) OnStart() error {
-	return nil
+	return checkMigrations(c.db)
 }
 
 func (c *votingCronjob) RandomTimeoutDelta() time.Duration {
 	return 10 * time.Second
 }
 
-func (c *votingCronjob) Call() error {
+func (c *votingCronjob) Call(ctx context.Context) error {
 	idxState, err := c.db.FetchState(pchain.StateName)
 	if err != nil {
 		return err
 	}
 
+	// Never vote on data indexed from a node whose history does not match ours
+	diagnostic, err := c.db.FetchLastIndexerDiagnostic(pchain.StateName)
+	if err != nil {
+		return err
+	}
+	if diagnostic != nil {
+		return errors.Errorf("P-chain indexer reported %s at index %d, voting is suspended until the diagnostic record is resolved",
+			diagnostic.Type, diagnostic.Index)
+	}
+
 	state, err := c.db.FetchState(votingStateName)
 	if err != nil {
 		return err
@@ -115,6 +128,10 @@ func (c *votingCronjob) Call() error {
 	c.updateLastEpochMetrics(epochRange.end)
 
 	for e := epochRange.start; e <= epochRange.end; e++ {
+		// Stop between epochs, the state is updated after each submitted epoch
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		start, end := c.epochs.GetTimeRange(e)
 
 		if c.indexerBehind(&idxState, e) {
@@ -4,11 +4,11 @@
 package cronjob
 
 import (
-	sysContext "context"
+	"context"
 	globalConfig "flare-indexer/config"
 	"flare-indexer/database"
 	"flare-indexer/indexer/config"
-	"flare-indexer/indexer/context"
+	indexerctx "flare-indexer/indexer/context"
 	"flare-indexer/indexer/pchain"
 	"flare-indexer/indexer/shared"
 	"flare-indexer/utils"
@@ -55,14 +55,7 @@ func votingCronjobTestConfig(epochStart time.Time, dbName string, privateKey str
 			BatchSize:  200,
 			StartIndex: 0,
 		},
-		DB: globalConfig.DBConfig{
-			Username:   database.MysqlTestUser,
-			Password:   database.MysqlTestPassword,
-			Host:       database.MysqlTestHost,
-			Port:       database.MysqlTestPort,
-			Database:   dbName,
-			LogQueries: false,
-		},
+		DB: database.TestDBConfig(dbName),
 		Logger: globalConfig.LoggerConfig{
 			Level: "debug",
 		},
@@ -81,7 +74,7 @@ func transformPChainTx(tx *database.PChainTx) *database.PChainTx {
 }
 
 func createTestVotingClients(epochStart time.Time) (*votingCronjob, *votingCronjob, *mirrorCronJob, *shared.ChainIndexerBase, *shared.ChainIndexerBase, error) {
-	ctx1, err := context.BuildTestContext(votingCronjobTestConfig(epochStart, "flare_indexer_indexer", privateKey1))
+	ctx1, err := indexerctx.BuildTestContext(votingCronjobTestConfig(epochStart, "flare_indexer_indexer", privateKey1))
 	if err != nil {
 		return nil, nil, nil, nil, nil, err
 	}
@@ -89,7 +82,7 @@ func createTestVotingClients(epochStart time.Time) (*votingCronjob, *votingCronj
 	if err != nil {
 		return nil, nil, nil, nil, nil, err
 	}
-	ctx2, err := context.BuildTestContext(votingCronjobTestConfig(epochStart, "flare_indexer_indexer_2", privateKey2))
+	ctx2, err := indexerctx.BuildTestContext(votingCronjobTestConfig(epochStart, "flare_indexer_indexer_2", privateKey2))
 	if err != nil {
 		return nil, nil, nil, nil, nil, err
 	}
@@ -128,7 +121,7 @@ func createTestVotingClients(epochStart time.Time) (*votingCronjob, *votingCronj
 }
 
 func getMerkleRootFromContract(votingContract *voting.Voting, epoch int64) ([32]byte, error) {
-	ctx := sysContext.Background()
+	ctx := context.Background()
 	opts := &bind.CallOpts{Context: ctx}
 	merkleRoot, err := votingContract.GetMerkleRoot(opts, big.NewInt(epoch))
 	if err != nil {
@@ -146,11 +139,11 @@ func TestVoting(t *testing.T) {
 	// We need two indexers, each one for a different voting client,
 	// since the progress is stored in the DB
 	t.Run("Run indexer 1", func(t *testing.T) {
-		err := indexer1.IndexBatch()
+		err := indexer1.IndexBatch(context.Background())
 		require.NoError(t, err)
 	})
 	t.Run("Run indexer 2", func(t *testing.T) {
-		err := indexer2.IndexBatch()
+		err := indexer2.IndexBatch(context.Background())
 		require.NoError(t, err)
 	})
 
@@ -158,9 +151,9 @@ func TestVoting(t *testing.T) {
 		vCronjob1.time.SetNow(now)
 		vCronjob2.time.SetNow(now)
 		for i := 0; i < 10; i++ {
-			err := vCronjob1.Call()
+			err := vCronjob1.Call(context.Background())
 			require.NoError(t, err)
-			err = vCronjob2.Call()
+			err = vCronjob2.Call(context.Background())
 			require.NoError(t, err)
 			vCronjob1.time.AdvanceNow(30 * time.Second)
 			vCronjob2.time.AdvanceNow(30 * time.Second)
@@ -174,7 +167,7 @@ func TestVoting(t *testing.T) {
 	t.Run("Run mirroring client", func(t *testing.T) {
 		mCronjob.time.SetNow(now)
 		mCronjob.time.AdvanceNow(10 * 30 * time.Second)
-		err := mCronjob.Call()
+		err := mCronjob.Call(context.Background())
 		require.NoError(t, err)
 	})
 }
@@ -23,6 +23,14 @@ func (db *votingDBGorm) FetchState(name string) (database.State, error) {
 	return database.FetchState(db.g, name)
 }
 
+func (db *votingDBGorm) FetchLastIndexerDiagnostic(stateName string) (*database.IndexerDiagnostic, error) {
+	return database.FetchLastIndexerDiagnostic(db.g, stateName)
+}
+
+func (db *votingDBGorm) FetchFailedMigrations() ([]database.Migration, error) {
+	return database.FetchFailedMigrations(db.g)
+}
+
 func (db *votingDBGorm) FetchPChainVotingData(start, end time.Time) ([]database.PChainTxData, error) {
 	return database.FetchPChainVotingData(db.g, start, end)
 }
@@ -1,6 +1,7 @@
 package cronjob
 
 import (
+	"context"
 	"flare-indexer/database"
 	"flare-indexer/indexer/config"
 	"flare-indexer/indexer/pchain"
@@ -15,8 +16,10 @@ import (
 )
 
 type votingDBTest struct {
-	states     map[string]database.State
-	votingData map[timeRange][]database.PChainTxData
+	states      map[string]database.State
+	votingData  map[timeRange][]database.PChainTxData
+	diagnostics map[string]*database.IndexerDiagnostic
+	migrations  []database.Migration
 }
 
 type timeRange struct {
@@ -33,6 +36,14 @@ func (db *votingDBTest) FetchState(name string) (database.State, error) {
 	return database.State{Name: name}, nil
 }
 
+func (db *votingDBTest) FetchLastIndexerDiagnostic(stateName string) (*database.IndexerDiagnostic, error) {
+	return db.diagnostics[stateName], nil
+}
+
+func (db *votingDBTest) FetchFailedMigrations() ([]database.Migration, error) {
+	return db.migrations, nil
+}
+
 func (db *votingDBTest) FetchPChainVotingData(start, end time.Time) ([]database.PChainTxData, error) {
 	return db.votingData[timeRange{start, end}], nil
 }
@@ -76,7 +87,7 @@ func TestNoVotes(t *testing.T) {
 		epochCronjob: initEpochCronjob(),
 	}
 
-	err := cronjob.Call()
+	err := cronjob.Call(context.Background())
 	require.NoError(t, err)
 	require.Empty(t, contract.submittedVotes)
 }
@@ -112,7 +123,7 @@ func TestVotes(t *testing.T) {
 		epochCronjob: epochs,
 	}
 
-	err := cronjob.Call()
+	err := cronjob.Call(context.Background())
 	require.NoError(t, err)
 	require.NotEmpty(t, contract.submittedVotes)
 
@@ -122,6 +133,65 @@ func TestVotes(t *testing.T) {
 	require.Equal(t, updatedState.NextDBIndex, uint64(5))
 }
 
+func TestNoVotesOnChainMismatch(t *testing.T) {
+	epochs := initEpochCronjob()
+
+	db := votingDBTest{
+		states: map[string]database.State{
+			pchain.StateName: {
+				Updated:        time.Now(),
+				NextDBIndex:    3,
+				LastChainIndex: 2,
+			},
+		},
+		votingData: map[timeRange][]database.PChainTxData{
+			timeRangeForEpoch(epochs, 1): {newTxData(0)},
+		},
+		diagnostics: map[string]*database.IndexerDiagnostic{
+			pchain.StateName: {
+				StateName: pchain.StateName,
+				Type:      database.IndexerDiagnosticContainerMismatch,
+				Index:     2,
+			},
+		},
+	}
+
+	contract := votingContractTest{
+		shouldVote:     map[int64]bool{1: true},
+		submittedVotes: make(map[int64][32]byte),
+	}
+
+	cronjob := votingCronjob{
+		db:           &db,
+		contract:     &contract,
+		epochCronjob: epochs,
+	}
+
+	err := cronjob.Call(context.Background())
+	require.Error(t, err)
+	require.Empty(t, contract.submittedVotes)
+}
+
+func TestVotingNotStartedOnFailedMigration(t *testing.T) {
+	db := votingDBTest{}
+	cronjob := votingCronjob{db: &db, epochCronjob: initEpochCronjob()}
+	require.NoError(t, cronjob.OnStart())
+
+	db.migrations = []database.Migration{{Version: "2026-10-18-00-00", Status: database.MigrationFailed}}
+	require.ErrorContains(t, cronjob.OnStart(), "2026-10-18-00-00")
+}
+
+func TestUptimeVotingNotStartedOnFailedMigration(t *testing.T) {
+	cfg := database.TestDBConfig("flare_indexer_indexer")
+	db, err := database.ConnectAndInitializeTestDB(&cfg, true)
+	require.NoError(t, err)
+	cronjob := uptimeVotingCronjob{db: db}
+	require.NoError(t, cronjob.OnStart())
+
+	require.NoError(t, database.CreateMigration(db, &database.Migration{Version: "2026-10-18-00-00", Status: database.MigrationPending}))
+	require.ErrorContains(t, cronjob.OnStart(), "2026-10-18-00-00")
+}
+
 func timeRangeForEpoch(cj epochCronjob, epoch int64) timeRange {
 	start, end := cj.epochs.GetTimeRange(epoch)
 
@@ -1,8 +1,13 @@
 package main
 
 import (
-	"flare-indexer/indexer/context"
+	"context"
+	"flare-indexer/indexer/backfill"
+	"flare-indexer/indexer/check"
+	indexerctx "flare-indexer/indexer/context"
+	"flare-indexer/indexer/migrate"
 	"flare-indexer/indexer/migrations"
+	"flare-indexer/indexer/redecode"
 	"flare-indexer/indexer/runner"
 	"flare-indexer/indexer/shared"
 	"flare-indexer/logger"
@@ -13,36 +18,69 @@ import (
 )
 
 func main() {
-	flags := context.ParseIndexerFlags()
+	flags := indexerctx.ParseIndexerFlags()
 
 	if flags.Version {
 		fmt.Printf("Flare P-chain indexer version %s\n", shared.ApplicationVersion)
 		return
 	}
 
-	ctx, err := context.BuildContext(flags)
+	ictx, err := indexerctx.BuildContext(flags)
 	if err != nil {
 		fmt.Printf("%v\n", err)
-		return
+		os.Exit(1)
 	}
 
 	logger.Info("Starting Flare indexer application version %s", shared.ApplicationVersion)
 
-	err = migrations.Container.ExecuteAll(ctx.DB())
-	if err != nil {
-		fmt.Printf("%v\n", err)
-		return
+	// The migrate command manages migrations itself, the check command only reads the database
+	if flags.Command != "migrate" && flags.Command != "check" {
+		// A failed or pending migration must be resolved (with the migrate command) before
+		// anything writes to the database
+		err = migrations.Container.ExecuteAll(ictx.DB())
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
 	}
 
-	cancelChan := make(chan os.Signal, 1)
-	signal.Notify(cancelChan, os.Interrupt, syscall.SIGTERM)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if flags.Command != "" {
+		if err := runCommand(ctx, ictx, flags); err != nil {
+			logger.Error("%s failed: %v", flags.Command, err)
+			stop()
+			os.Exit(1)
+		}
+		return
+	}
 
 	// Prometheus metrics
-	shared.InitMetricsServer(&ctx.Config().Metrics)
+	shared.InitMetricsServer(&ictx.Config().Metrics)
 
-	runner.Start(ctx)
+	components := runner.Start(ctx, ictx)
 
-	<-cancelChan
+	<-ctx.Done()
+	logger.Info("Stopping flare indexer, waiting for running jobs to finish")
+	if interrupted := components.Wait(runner.ShutdownTimeout); len(interrupted) > 0 {
+		logger.Warn("Stopped flare indexer, interrupted: %v", interrupted)
+		return
+	}
 	logger.Info("Stopped flare indexer")
+}
 
+func runCommand(ctx context.Context, ictx indexerctx.IndexerContext, flags *indexerctx.IndexerFlags) error {
+	switch flags.Command {
+	case "backfill":
+		return backfill.Run(ctx, ictx, flags.CommandArgs)
+	case "check":
+		return check.Run(ctx, ictx, flags.CommandArgs)
+	case "migrate":
+		return migrate.Run(ctx, ictx, flags.CommandArgs)
+	case "redecode":
+		return redecode.Run(ctx, ictx, flags.CommandArgs)
+	default:
+		return fmt.Errorf("unknown command %s", flags.Command)
+	}
 }
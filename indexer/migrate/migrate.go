@@ -0,0 +1,115 @@
+package migrate
+
+import (
+	"context"
+	"flag"
+	indexerctx "flare-indexer/indexer/context"
+	"flare-indexer/indexer/migrations"
+	"flare-indexer/logger"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+const usage = "usage: migrate status | up [--dry-run] | down <version> | retry <version>"
+
+// Run the migrate command with its command line arguments:
+//
+//	migrate status
+//	migrate up [--dry-run]
+//	migrate down <version>
+//	migrate retry <version>
+//
+// Migrations are not executed at startup when this command is run. status lists registered and
+// executed migrations, up executes pending migrations (only lists them with --dry-run), down
+// rolls back the last executed migration if it has a down function and retry executes a failed
+// migration again.
+func Run(ctx context.Context, ictx indexerctx.IndexerContext, args []string) error {
+	return run(os.Stdout, ictx.DB(), migrations.Container, args)
+}
+
+func run(w io.Writer, db *gorm.DB, container migrations.MigrationContainer, args []string) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+	switch args[0] {
+	case "status":
+		infos, err := container.Status(db)
+		if err != nil {
+			return err
+		}
+		return writeMigrations(w, infos)
+	case "up":
+		fs := flag.NewFlagSet("migrate up", flag.ContinueOnError)
+		dryRun := fs.Bool("dry-run", false, "List pending migrations without executing them")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if !*dryRun {
+			return container.ExecuteAll(db)
+		}
+		infos, err := container.Pending(db)
+		if err != nil {
+			return err
+		}
+		return writeMigrations(w, infos)
+	case "down":
+		version, err := versionArg(args)
+		if err != nil {
+			return err
+		}
+		if err := container.Down(db, version); err != nil {
+			return err
+		}
+		logger.Info("Rolled back migration %s", version)
+		return nil
+	case "retry":
+		version, err := versionArg(args)
+		if err != nil {
+			return err
+		}
+		if err := container.Retry(db, version); err != nil {
+			return err
+		}
+		logger.Info("Migration %s completed", version)
+		return nil
+	default:
+		return errors.Errorf("unknown migrate subcommand %s, %s", args[0], usage)
+	}
+}
+
+func versionArg(args []string) (string, error) {
+	if len(args) != 2 {
+		return "", errors.New(usage)
+	}
+	return args[1], nil
+}
+
+// Write a table of migrations, not executed migrations have an empty status
+func writeMigrations(w io.Writer, infos []migrations.MigrationInfo) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tSTATUS\tEXECUTED AT\tDURATION\tDOWN\tDESCRIPTION")
+	for _, info := range infos {
+		status, executedAt, duration := "-", "-", "-"
+		if len(info.Status) > 0 {
+			status = string(info.Status)
+			executedAt = info.ExecutedAt.UTC().Format(time.DateTime)
+			duration = (time.Duration(info.Duration) * time.Millisecond).String()
+		}
+		down := "no"
+		if info.HasDown {
+			down = "yes"
+		}
+		description := info.Description
+		if !info.Registered {
+			description += " (not registered)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", info.Version, status, executedAt, duration, down, description)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,125 @@
+package migrate
+
+import (
+	"bytes"
+	"errors"
+	"flare-indexer/database"
+	_ "flare-indexer/indexer/cronjob"
+	"flare-indexer/indexer/migrations"
+	_ "flare-indexer/indexer/pchain"
+	_ "flare-indexer/indexer/xchain"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// Container with two migrations, the second one fails while fail is set
+func newTestContainer(t *testing.T) (*gorm.DB, migrations.MigrationContainer, *[]string, *bool) {
+	cfg := database.TestDBConfig("flare_indexer_indexer")
+	db, err := database.ConnectAndInitializeTestDB(&cfg, true)
+	require.NoError(t, err)
+
+	var applied []string
+	fail := true
+	container := migrations.NewMigrationContainer()
+	container.AddWithDown("2026-01-02-00-00", "Second",
+		func(db *gorm.DB) error {
+			if fail {
+				return errors.New("failed")
+			}
+			applied = append(applied, "second")
+			return nil
+		},
+		func(db *gorm.DB) error {
+			applied = applied[:len(applied)-1]
+			return nil
+		})
+	container.Add("2026-01-01-00-00", "First", func(db *gorm.DB) error {
+		applied = append(applied, "first")
+		return nil
+	})
+	return db, container, &applied, &fail
+}
+
+func TestMigrate(t *testing.T) {
+	db, container, applied, fail := newTestContainer(t)
+	var out bytes.Buffer
+
+	require.NoError(t, run(&out, db, container, []string{"up", "--dry-run"}))
+	require.Contains(t, out.String(), "2026-01-01-00-00")
+	require.Contains(t, out.String(), "2026-01-02-00-00")
+	require.Empty(t, *applied)
+
+	err := run(&out, db, container, []string{"up"})
+	require.ErrorIs(t, err, migrations.ErrMigrationFailed)
+	require.Equal(t, []string{"first"}, *applied)
+	failed, err := database.FetchFailedMigrations(db)
+	require.NoError(t, err)
+	require.Len(t, failed, 1)
+	require.ErrorIs(t, run(&out, db, container, []string{"up", "--dry-run"}), migrations.ErrMigrationFailed)
+
+	require.Error(t, run(&out, db, container, []string{"retry", "2026-01-01-00-00"}))
+	*fail = false
+	failed[0].Status = database.MigrationPending
+	require.NoError(t, database.UpdateMigration(db, &failed[0]))
+	require.Error(t, run(&out, db, container, []string{"retry", "2026-01-02-00-00"}))
+	failed[0].Status = database.MigrationFailed
+	require.NoError(t, database.UpdateMigration(db, &failed[0]))
+	require.NoError(t, run(&out, db, container, []string{"retry", "2026-01-02-00-00"}))
+	require.Equal(t, []string{"first", "second"}, *applied)
+
+	out.Reset()
+	require.NoError(t, run(&out, db, container, []string{"status"}))
+	require.Regexp(t, `2026-01-02-00-00\s+COMPLETED`, out.String())
+
+	// Only the last migration with a down function can be rolled back
+	require.Error(t, run(&out, db, container, []string{"down", "2026-01-01-00-00"}))
+	require.NoError(t, run(&out, db, container, []string{"down", "2026-01-02-00-00"}))
+	require.Equal(t, []string{"first"}, *applied)
+	pending, err := container.Pending(db)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	require.Equal(t, "2026-01-02-00-00", pending[0].Version)
+
+	require.NoError(t, run(&out, db, container, []string{"up"}))
+	require.Equal(t, []string{"first", "second"}, *applied)
+
+	require.Error(t, run(&out, db, container, nil))
+	require.Error(t, run(&out, db, container, []string{"down"}))
+}
+
+// The last migrations registered by the indexer can be rolled back in reverse order and executed
+// again
+func TestDownRegistered(t *testing.T) {
+	cfg := database.TestDBConfig("flare_indexer_indexer")
+	db, err := database.ConnectAndInitializeTestDB(&cfg, true)
+	require.NoError(t, err)
+	var out bytes.Buffer
+	require.NoError(t, run(&out, db, migrations.Container, []string{"up"}))
+
+	rolledBack := []string{
+		"2026-10-18-07-01",
+		"2026-10-18-07-00",
+		"2026-10-18-06-00",
+		"2026-10-18-05-00",
+		"2026-10-18-04-01",
+		"2026-10-18-04-00",
+	}
+	for _, version := range rolledBack {
+		require.NoError(t, run(&out, db, migrations.Container, []string{"down", version}))
+	}
+	require.Error(t, run(&out, db, migrations.Container, []string{"down", "2026-10-18-03-00"}))
+
+	pending, err := migrations.Container.Pending(db)
+	require.NoError(t, err)
+	require.Len(t, pending, len(rolledBack))
+	for i, info := range pending {
+		require.Equal(t, rolledBack[len(rolledBack)-1-i], info.Version)
+	}
+
+	require.NoError(t, run(&out, db, migrations.Container, []string{"up"}))
+	pending, err = migrations.Container.Pending(db)
+	require.NoError(t, err)
+	require.Empty(t, pending)
+}
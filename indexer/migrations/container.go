@@ -1,6 +1,7 @@
 package migrations
 
 import (
+	"errors"
 	"flare-indexer/database"
 	"flare-indexer/logger"
 	"fmt"
@@ -13,15 +14,35 @@ import (
 
 var Container MigrationContainer = NewMigrationContainer()
 
+// Returned (wrapped) when a migration fails or a stored migration is not completed
+var ErrMigrationFailed = errors.New("migration failed")
+
 type MigrationContainer interface {
 	Add(version string, description string, code func(*gorm.DB) error)
+	AddWithDown(version string, description string, code func(*gorm.DB) error, down func(*gorm.DB) error)
 	ExecuteAll(db *gorm.DB) error
+	Status(db *gorm.DB) ([]MigrationInfo, error)
+	Pending(db *gorm.DB) ([]MigrationInfo, error)
+	Down(db *gorm.DB, version string) error
+	Retry(db *gorm.DB, version string) error
+}
+
+// Registered or stored migration with its stored execution (if any)
+type MigrationInfo struct {
+	Version     string
+	Description string
+	Status      database.MigrationStatus // Empty if the migration was not executed
+	ExecutedAt  *time.Time
+	Duration    int  // Duration of the execution in milliseconds
+	HasDown     bool // Migration can be rolled back
+	Registered  bool // False for stored migrations whose code is not part of this version
 }
 
 type migration struct {
 	version     string
 	description string
 	code        func(*gorm.DB) error
+	down        func(*gorm.DB) error
 }
 
 func executeMigration(db *gorm.DB, m migration) error {
@@ -35,8 +56,11 @@ func executeMigration(db *gorm.DB, m migration) error {
 	if err != nil {
 		return err
 	}
+	return runMigration(db, m, &dbMigration)
+}
 
-	// Execute migration and measure its duration
+// Execute migration code, measure its duration and store the result in dbMigration
+func runMigration(db *gorm.DB, m migration, dbMigration *database.Migration) error {
 	start := time.Now()
 	execErr := m.code(db)
 	end := time.Now()
@@ -49,12 +73,12 @@ func executeMigration(db *gorm.DB, m migration) error {
 	}
 	dbMigration.Status = status
 	dbMigration.Duration = int((end.Sub(start)).Milliseconds())
-	err = database.UpdateMigration(db, &dbMigration)
+	err := database.UpdateMigration(db, dbMigration)
 	if err != nil {
 		return fmt.Errorf("error updating migration %s with status %s, error is %w", m.version, status, err)
 	}
 	if execErr != nil {
-		return fmt.Errorf("error executing migration %s, error is %w", m.version, execErr)
+		return fmt.Errorf("%w: error executing migration %s, error is %w", ErrMigrationFailed, m.version, execErr)
 	}
 	return nil
 }
@@ -75,10 +99,16 @@ func NewMigrationContainer() MigrationContainer {
 //	description - Description of the migration
 //	code - Migration code, function without parameters
 func (mc *migrationContainer) Add(version string, description string, code func(*gorm.DB) error) {
+	mc.AddWithDown(version, description, code, nil)
+}
+
+// Adds a migration that can be rolled back by down (see Add for other parameters)
+func (mc *migrationContainer) AddWithDown(version string, description string, code func(*gorm.DB) error, down func(*gorm.DB) error) {
 	mc.migrations = append(mc.migrations, migration{
 		version:     version,
 		description: description,
 		code:        code,
+		down:        down,
 	})
 }
 
@@ -87,35 +117,192 @@ func (mc *migrationContainer) ExecuteAll(db *gorm.DB) error {
 	if err != nil {
 		return err
 	}
+	pending, err := mc.pending(dbMigrations)
+	if err != nil {
+		return err
+	}
+	currentVersion := "/"
+	if len(dbMigrations) > 0 {
+		currentVersion = dbMigrations[len(dbMigrations)-1].Version
+	}
 
+	executedCount := 0
+	for _, m := range pending {
+		logger.Info("Executing migration %s (%s)", m.description, m.version)
+		err := executeMigration(db, m)
+		if err != nil {
+			return err
+		}
+		currentVersion = m.version
+		executedCount++
+	}
+	logger.Info("Executed %d migrations, current version is %s", executedCount, currentVersion)
+	return nil
+}
+
+// Registered migrations which are not among stored dbMigrations, sorted by version. Returns an
+// error if a stored migration is not completed, migrations are not executed until it is resolved.
+func (mc *migrationContainer) pending(dbMigrations []database.Migration) ([]migration, error) {
 	executedVersions := mapset.NewSet[string]()
 	for _, m := range dbMigrations {
 		if m.Status != database.MigrationCompleted {
-			return fmt.Errorf("there is a PENDING or FAILED migration with version: '%s'. Aborting execution of migrations. Problem should be resolved manually", m.Version)
+			return nil, fmt.Errorf("%w: there is a PENDING or FAILED migration with version: '%s'. Aborting execution of migrations. "+
+				"Problem should be resolved manually or with the migrate command", ErrMigrationFailed, m.Version)
 		}
 		executedVersions.Add(m.Version)
 	}
-	currentVersion := "/"
-	if len(dbMigrations) > 0 {
-		currentVersion = dbMigrations[len(dbMigrations)-1].Version
+
+	var pending []migration
+	for _, m := range mc.sorted() {
+		if !executedVersions.Contains(m.version) {
+			pending = append(pending, m)
+		}
 	}
+	return pending, nil
+}
 
-	sort.Slice(mc.migrations, func(i, j int) bool {
-		return mc.migrations[i].version < mc.migrations[j].version
-	})
-	executedCount := 0
+// Migrations which would be executed by ExecuteAll, in the order of execution
+func (mc *migrationContainer) Pending(db *gorm.DB) ([]MigrationInfo, error) {
+	dbMigrations, err := database.FetchMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+	pending, err := mc.pending(dbMigrations)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]MigrationInfo, len(pending))
+	for i, m := range pending {
+		infos[i] = m.info()
+	}
+	return infos, nil
+}
+
+// Registered and stored migrations, sorted by version
+func (mc *migrationContainer) Status(db *gorm.DB) ([]MigrationInfo, error) {
+	dbMigrations, err := database.FetchMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make(map[string]MigrationInfo)
 	for _, m := range mc.migrations {
-		if !executedVersions.Contains(m.version) {
+		infos[m.version] = m.info()
+	}
+	for _, dbm := range dbMigrations {
+		info, ok := infos[dbm.Version]
+		if !ok {
+			info = MigrationInfo{Version: dbm.Version, Description: dbm.Description}
+		}
+		executedAt := dbm.ExecutedAt
+		info.Status = dbm.Status
+		info.ExecutedAt = &executedAt
+		info.Duration = dbm.Duration
+		infos[dbm.Version] = info
+	}
+
+	result := make([]MigrationInfo, 0, len(infos))
+	for _, info := range infos {
+		result = append(result, info)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Version < result[j].Version
+	})
+	return result, nil
+}
+
+// Roll back the completed or failed migration with version by its down function and delete its
+// record, so that it is executed again by ExecuteAll. Migrations executed later must be rolled
+// back first. A failed roll back leaves the record in the FAILED state.
+func (mc *migrationContainer) Down(db *gorm.DB, version string) error {
+	m, dbMigration, err := mc.find(db, version)
+	if err != nil {
+		return err
+	}
+	if m.down == nil {
+		return fmt.Errorf("migration %s has no down function", version)
+	}
+	if dbMigration.Status == database.MigrationPending {
+		return fmt.Errorf("migration %s is PENDING, it may still be running", version)
+	}
+	dbMigrations, err := database.FetchMigrations(db)
+	if err != nil {
+		return err
+	}
+	if last := dbMigrations[len(dbMigrations)-1]; last.Version != version {
+		return fmt.Errorf("migration %s was executed after %s and must be rolled back first", last.Version, version)
+	}
 
-			logger.Info("Executing migration %s (%s)", m.description, m.version)
-			err := executeMigration(db, m)
-			if err != nil {
-				return err
-			}
-			currentVersion = m.version
-			executedCount++
+	logger.Info("Rolling back migration %s (%s)", m.description, m.version)
+	if downErr := m.down(db); downErr != nil {
+		dbMigration.Status = database.MigrationFailed
+		if err := database.UpdateMigration(db, dbMigration); err != nil {
+			return fmt.Errorf("error updating migration %s with status %s, error is %w", version, dbMigration.Status, err)
 		}
+		return fmt.Errorf("%w: error rolling back migration %s, error is %w", ErrMigrationFailed, version, downErr)
 	}
-	logger.Info("Executed %d migrations, current version is %s", executedCount, currentVersion)
+	return database.DeleteMigration(db, dbMigration)
+}
+
+// Execute the failed migration with version again. Pending migrations are not executed. A
+// PENDING migration may still be running, an interrupted migration must be marked as FAILED
+// manually before it is retried.
+func (mc *migrationContainer) Retry(db *gorm.DB, version string) error {
+	m, dbMigration, err := mc.find(db, version)
+	if err != nil {
+		return err
+	}
+	if dbMigration.Status == database.MigrationCompleted {
+		return fmt.Errorf("migration %s is already COMPLETED", version)
+	}
+	if dbMigration.Status == database.MigrationPending {
+		return fmt.Errorf("migration %s is PENDING, it may still be running", version)
+	}
+
+	logger.Info("Retrying migration %s (%s)", m.description, m.version)
+	dbMigration.ExecutedAt = time.Now()
+	return runMigration(db, m, dbMigration)
+}
+
+// Registered migration with version and its stored record
+func (mc *migrationContainer) find(db *gorm.DB, version string) (migration, *database.Migration, error) {
+	var m migration
+	found := false
+	for _, rm := range mc.migrations {
+		if rm.version == version {
+			m, found = rm, true
+			break
+		}
+	}
+	if !found {
+		return m, nil, fmt.Errorf("migration %s is not registered", version)
+	}
+	dbMigration, err := database.FetchMigration(db, version)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return m, nil, fmt.Errorf("migration %s was not executed", version)
+	}
+	return m, dbMigration, err
+}
+
+// Down function of a migration setting values derived from stored data. The values are kept
+// when the migration is rolled back, they are set again when it is executed.
+func KeepValues(db *gorm.DB) error {
 	return nil
 }
+
+// Registered migrations sorted by version
+func (mc *migrationContainer) sorted() []migration {
+	sort.Slice(mc.migrations, func(i, j int) bool {
+		return mc.migrations[i].version < mc.migrations[j].version
+	})
+	return mc.migrations
+}
+
+func (m migration) info() MigrationInfo {
+	return MigrationInfo{
+		Version:     m.version,
+		Description: m.description,
+		HasDown:     m.down != nil,
+		Registered:  true,
+	}
+}
@@ -0,0 +1,65 @@
+package pchain
+
+import (
+	"context"
+	indexerctx "flare-indexer/indexer/context"
+	"flare-indexer/utils/chain"
+
+	"github.com/pkg/errors"
+)
+
+// Range of indexer indexes corresponding to a closed range of P-chain block heights
+type IndexRange struct {
+	From uint64
+	To   uint64
+
+	// Last accepted index on the chain
+	LastIndex uint64
+}
+
+// Convert the block height range [fromHeight, toHeight] to the indexes of the node's
+// P-chain block index. Blocks are indexed in order of their heights, so the offset between
+// the height and the index is determined from the last accepted block.
+func HeightsToIndexRange(ctx context.Context, ictx indexerctx.IndexerContext, fromHeight, toHeight uint64) (*IndexRange, error) {
+	client, err := newContainerClient(ictx)
+	if err != nil {
+		return nil, err
+	}
+	container, lastIndex, err := chain.FetchLastAcceptedContainer(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	blk, err := chain.ParsePChainBlock(container.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	lastHeight := blk.Height()
+	if toHeight > lastHeight {
+		return nil, errors.Errorf("height %d is above the last accepted height %d", toHeight, lastHeight)
+	}
+	if lastHeight < lastIndex {
+		return nil, errors.Errorf("last accepted height %d is below its index %d", lastHeight, lastIndex)
+	}
+	offset := lastHeight - lastIndex
+	if fromHeight < offset {
+		return nil, errors.Errorf("height %d is not indexed by the node, first indexed height is %d", fromHeight, offset)
+	}
+
+	r := &IndexRange{From: fromHeight - offset, To: toHeight - offset, LastIndex: lastIndex}
+
+	// Sanity check of the offset
+	ctx, cancel := context.WithTimeout(ctx, chain.IndexerTimeout)
+	defer cancel()
+	container, err = client.GetContainerByIndex(ctx, r.From)
+	if err != nil {
+		return nil, err
+	}
+	blk, err = chain.ParsePChainBlock(container.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	if blk.Height() != fromHeight {
+		return nil, errors.Errorf("block at index %d has height %d, expected %d", r.From, blk.Height(), fromHeight)
+	}
+	return r, nil
+}
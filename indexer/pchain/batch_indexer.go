@@ -1,9 +1,10 @@
 package pchain
 
 import (
+	"context"
 	"encoding/hex"
 	"flare-indexer/database"
-	"flare-indexer/indexer/context"
+	indexerctx "flare-indexer/indexer/context"
 	"flare-indexer/indexer/shared"
 	"flare-indexer/utils"
 	"flare-indexer/utils/chain"
@@ -34,9 +35,22 @@ type txBatchIndexer struct {
 	rpcClient chain.RPCClient
 
 	inOutIndexer    *shared.InputOutputIndexer
+	importIndexer   *shared.InputOutputIndexer // Imported inputs of ImportTxs, nil if not enabled
+	importUpdater   *importedInputUpdater
+	newBlocks       []*database.PChainBlock
 	newTxs          []*database.PChainTx
+	rewards         []*database.PChainStakeReward
+	rewardsOwners   []*database.PChainRewardsOwner
+	subnets         subnetEntities
+	l1              l1Entities
 	dataTransformer *PChainDataTransformer
 
+	// Height minus index of blocks, nil if not known
+	heightOffset *uint64
+
+	// Source of reward outputs of RewardValidatorTxs, the node by default
+	rewardOutputs func(ctx context.Context, txID string) ([]shared.Output, error)
+
 	durangoTime time.Time
 }
 
@@ -47,7 +61,7 @@ func NewPChainDataTransformer(txTransformer func(tx *database.PChainTx) *databas
 }
 
 func NewPChainBatchIndexer(
-	ctx context.IndexerContext,
+	ctx indexerctx.IndexerContext,
 	client chain.IndexerClient,
 	rpcClient chain.RPCClient,
 	dataTransformer *PChainDataTransformer,
@@ -58,60 +72,86 @@ func NewPChainBatchIndexer(
 	}
 
 	updater := newPChainInputUpdater(ctx, rpcClient)
+	var importUpdater *importedInputUpdater
+	var importIndexer *shared.InputOutputIndexer
+	if ctx.Config().PChainIndexer.ImportedInputs {
+		importUpdater = newImportedInputUpdater(ctx)
+		importIndexer = shared.NewInputOutputIndexer(importUpdater)
+	}
 	return &txBatchIndexer{
 		db:        ctx.DB(),
 		client:    client,
 		rpcClient: rpcClient,
 
 		inOutIndexer:    shared.NewInputOutputIndexer(updater),
+		importIndexer:   importIndexer,
+		importUpdater:   importUpdater,
+		newBlocks:       make([]*database.PChainBlock, 0),
 		newTxs:          make([]*database.PChainTx, 0),
 		dataTransformer: dataTransformer,
+		rewardOutputs: func(ctx context.Context, txID string) ([]shared.Output, error) {
+			return getRewardOutputs(ctx, rpcClient, txID)
+		},
 
 		durangoTime: durangoTime,
 	}
 }
 
 func (xi *txBatchIndexer) Reset(containerLen int) {
+	xi.newBlocks = make([]*database.PChainBlock, 0, containerLen)
 	xi.newTxs = make([]*database.PChainTx, 0, containerLen)
+	xi.rewards = nil
+	xi.rewardsOwners = nil
+	xi.subnets = subnetEntities{}
+	xi.l1 = l1Entities{}
 	xi.inOutIndexer.Reset(containerLen)
+	if xi.importIndexer != nil {
+		xi.importIndexer.Reset(containerLen)
+	}
 }
 
-func (xi *txBatchIndexer) AddContainer(index uint64, container indexer.Container) error {
+func (xi *txBatchIndexer) AddContainer(ctx context.Context, index uint64, container indexer.Container) error {
 	innerBlk, err := chain.ParsePChainBlock(container.Bytes)
 	if err != nil {
 		return err
 	}
 
+	var blockType database.PChainBlockType
+	var blockTime uint64
 	switch innerBlkType := innerBlk.(type) {
 	case *block.ApricotProposalBlock:
+		blockType = database.PChainProposalBlock
 		tx := innerBlkType.Tx
-		err = xi.addTx(&container, database.PChainProposalBlock, innerBlk.Height(), 0, tx)
+		err = xi.addTx(ctx, &container, database.PChainProposalBlock, innerBlk.Height(), 0, tx)
 	case *block.ApricotCommitBlock:
-		xi.addEmptyTx(&container, database.PChainCommitBlock, innerBlk.Height(), 0)
+		blockType = database.PChainCommitBlock
 	case *block.ApricotAbortBlock:
-		xi.addEmptyTx(&container, database.PChainAbortBlock, innerBlk.Height(), 0)
+		blockType = database.PChainAbortBlock
 	case *block.ApricotStandardBlock:
+		blockType = database.PChainStandardBlock
 		for _, tx := range innerBlkType.Txs() {
-			err = xi.addTx(&container, database.PChainStandardBlock, innerBlk.Height(), 0, tx)
+			err = xi.addTx(ctx, &container, database.PChainStandardBlock, innerBlk.Height(), 0, tx)
 			if err != nil {
 				break
 			}
 		}
 	// Banff blocks were introduced in Avalanche 1.9.0
 	case *block.BanffProposalBlock:
+		blockType, blockTime = database.PChainProposalBlock, innerBlkType.Time
 		for _, tx := range innerBlkType.Txs() {
-			err = xi.addTx(&container, database.PChainProposalBlock, innerBlk.Height(), innerBlkType.Time, tx)
+			err = xi.addTx(ctx, &container, database.PChainProposalBlock, innerBlk.Height(), innerBlkType.Time, tx)
 			if err != nil {
 				break
 			}
 		}
 	case *block.BanffCommitBlock:
-		xi.addEmptyTx(&container, database.PChainCommitBlock, innerBlk.Height(), innerBlkType.Time)
+		blockType, blockTime = database.PChainCommitBlock, innerBlkType.Time
 	case *block.BanffAbortBlock:
-		xi.addEmptyTx(&container, database.PChainAbortBlock, innerBlk.Height(), innerBlkType.Time)
+		blockType, blockTime = database.PChainAbortBlock, innerBlkType.Time
 	case *block.BanffStandardBlock:
+		blockType, blockTime = database.PChainStandardBlock, innerBlkType.Time
 		for _, tx := range innerBlkType.Txs() {
-			err = xi.addTx(&container, database.PChainStandardBlock, innerBlk.Height(), innerBlkType.Time, tx)
+			err = xi.addTx(ctx, &container, database.PChainStandardBlock, innerBlk.Height(), innerBlkType.Time, tx)
 			if err != nil {
 				break
 			}
@@ -119,14 +159,65 @@ func (xi *txBatchIndexer) AddContainer(index uint64, container indexer.Container
 	default:
 		err = fmt.Errorf("block %d has unexpected type %T", index, innerBlkType)
 	}
-	return err
+	if err != nil {
+		return err
+	}
+
+	dbBlock := &database.PChainBlock{
+		Height:    innerBlk.Height(),
+		BlockID:   container.ID.String(),
+		ParentID:  innerBlk.Parent().String(),
+		Type:      blockType,
+		BlockTime: blockTimeToTime(blockTime),
+		Timestamp: chain.TimestampToTime(container.Timestamp),
+		Bytes:     container.Bytes,
+	}
+	xi.newBlocks = append(xi.newBlocks, dbBlock)
+	return nil
+}
+
+func (xi *txBatchIndexer) ProcessBatch(ctx context.Context) error {
+	err := xi.inOutIndexer.ProcessBatch(ctx)
+	if err != nil || xi.importIndexer == nil {
+		return err
+	}
+	return xi.importIndexer.ProcessBatch(ctx)
+}
+
+// The database does not store indexer indexes. The block at index is the block at its height if
+// the offset of heights to indexes is known (backfill segments), otherwise it is the stored
+// block with the highest height. Commit and abort blocks indexed before p_chain_blocks stored
+// all blocks are missing, the fetched block is accepted if it is such a block following the
+// last stored proposal block.
+func (xi *txBatchIndexer) PersistedContainerID(db *gorm.DB, index uint64, container *indexer.Container) (string, error) {
+	if xi.heightOffset != nil {
+		return database.FetchPChainBlockIDAtHeight(db, index+*xi.heightOffset)
+	}
+	last, err := database.FetchPChainLastBlock(db)
+	if err != nil || last == nil {
+		return "", err
+	}
+	innerBlk, err := chain.ParsePChainBlock(container.Bytes)
+	if err != nil {
+		return "", err
+	}
+	if innerBlk.Height() == last.Height+1 && innerBlk.Parent().String() == last.BlockID &&
+		last.Type == database.PChainProposalBlock && isDecisionBlock(innerBlk) {
+		return container.ID.String(), nil
+	}
+	return last.BlockID, nil
 }
 
-func (xi *txBatchIndexer) ProcessBatch() error {
-	return xi.inOutIndexer.ProcessBatch()
+func isDecisionBlock(blk block.Block) bool {
+	switch blk.(type) {
+	case *block.ApricotCommitBlock, *block.ApricotAbortBlock, *block.BanffCommitBlock, *block.BanffAbortBlock:
+		return true
+	default:
+		return false
+	}
 }
 
-func (xi *txBatchIndexer) addTx(container *indexer.Container, blockType database.PChainBlockType, height uint64, blockTime uint64, tx *txs.Tx) error {
+func (xi *txBatchIndexer) addTx(ctx context.Context, container *indexer.Container, blockType database.PChainBlockType, height uint64, blockTime uint64, tx *txs.Tx) error {
 	txID := tx.ID().String()
 	dbTx := &database.PChainTx{}
 	dbTx.TxID = &txID
@@ -134,16 +225,12 @@ func (xi *txBatchIndexer) addTx(container *indexer.Container, blockType database
 	dbTx.BlockType = blockType
 	dbTx.BlockHeight = height
 	dbTx.Timestamp = chain.TimestampToTime(container.Timestamp)
-	dbTx.Bytes = container.Bytes
-	if blockTime != 0 {
-		time := time.Unix(int64(blockTime), 0)
-		dbTx.BlockTime = &time
-	}
+	dbTx.BlockTime = blockTimeToTime(blockTime)
 
 	var err error = nil
 	switch unsignedTx := tx.Unsigned.(type) {
 	case *txs.RewardValidatorTx:
-		err = xi.updateRewardValidatorTx(dbTx, unsignedTx)
+		err = xi.updateRewardValidatorTx(ctx, dbTx, unsignedTx)
 	case *txs.AddValidatorTx:
 		err = xi.updateAddValidatorTx(dbTx, unsignedTx)
 	case *txs.AddPermissionlessValidatorTx:
@@ -160,56 +247,54 @@ func (xi *txBatchIndexer) addTx(container *indexer.Container, blockType database
 		xi.updateAdvanceTimeTx(dbTx, unsignedTx)
 	case *txs.BaseTx:
 		err = xi.updateGeneralBaseTx(dbTx, database.PChainBaseTx, unsignedTx)
-
-	// We do not save specific info for the following transaction types.
-	// We may change this in the future if we need to index more data.
 	case *txs.CreateChainTx:
-		err = xi.updateGeneralBaseTx(dbTx, database.PChainCreateChainTx, &unsignedTx.BaseTx)
+		err = xi.updateCreateChainTx(dbTx, unsignedTx)
 	case *txs.CreateSubnetTx:
-		err = xi.updateGeneralBaseTx(dbTx, database.PChainCreateSubnetTx, &unsignedTx.BaseTx)
+		err = xi.updateCreateSubnetTx(dbTx, unsignedTx)
 	case *txs.RemoveSubnetValidatorTx:
-		err = xi.updateGeneralBaseTx(dbTx, database.PChainRemoveSubnetValidatorTx, &unsignedTx.BaseTx)
+		err = xi.updateRemoveSubnetValidatorTx(dbTx, unsignedTx)
 	case *txs.TransformSubnetTx:
-		err = xi.updateGeneralBaseTx(dbTx, database.PChainTransformSubnetTx, &unsignedTx.BaseTx)
+		err = xi.updateTransformSubnetTx(dbTx, unsignedTx)
 	case *txs.AddSubnetValidatorTx:
-		err = xi.updateGeneralBaseTx(dbTx, database.PChainAddSubnetValidatorTx, &unsignedTx.BaseTx)
+		err = xi.updateAddSubnetValidatorTx(dbTx, unsignedTx)
 	case *txs.ConvertSubnetToL1Tx:
-		err = xi.updateGeneralBaseTx(dbTx, database.PChainConvertSubnetToL1Tx, &unsignedTx.BaseTx)
+		err = xi.updateConvertSubnetToL1Tx(dbTx, unsignedTx)
 	case *txs.RegisterL1ValidatorTx:
-		err = xi.updateGeneralBaseTx(dbTx, database.PChainRegisterL1ValidatorTx, &unsignedTx.BaseTx)
+		err = xi.updateRegisterL1ValidatorTx(dbTx, unsignedTx)
 	case *txs.DisableL1ValidatorTx:
-		err = xi.updateGeneralBaseTx(dbTx, database.PChainDisableL1ValidatorTx, &unsignedTx.BaseTx)
+		err = xi.updateDisableL1ValidatorTx(dbTx, unsignedTx)
 	case *txs.SetL1ValidatorWeightTx:
-		err = xi.updateGeneralBaseTx(dbTx, database.PChainSetL1ValidatorWeightTx, &unsignedTx.BaseTx)
+		err = xi.updateSetL1ValidatorWeightTx(dbTx, unsignedTx)
 	case *txs.IncreaseL1ValidatorBalanceTx:
-		err = xi.updateGeneralBaseTx(dbTx, database.PChainIncreaseL1ValidatorBalanceTx, &unsignedTx.BaseTx)
+		err = xi.updateIncreaseL1ValidatorBalanceTx(dbTx, unsignedTx)
 	default:
 		err = fmt.Errorf("p-chain transaction %v with type %T in block %d is not indexed", dbTx.TxID, unsignedTx, height)
 	}
 	return err
 }
 
-func (xi *txBatchIndexer) addEmptyTx(container *indexer.Container, blockType database.PChainBlockType, height uint64, blockTime uint64) {
-	dbTx := &database.PChainTx{}
-	dbTx.BlockID = container.ID.String()
-	dbTx.BlockType = blockType
-	dbTx.BlockHeight = height
-	dbTx.Timestamp = chain.TimestampToTime(container.Timestamp)
-	dbTx.Bytes = container.Bytes
-	dbTx.TxID = nil
-	if blockTime != 0 {
-		time := time.Unix(int64(blockTime), 0)
-		dbTx.BlockTime = &time
+// Block time is 0 before Banff blocks
+func blockTimeToTime(blockTime uint64) *time.Time {
+	if blockTime == 0 {
+		return nil
 	}
-
-	xi.newTxs = append(xi.newTxs, dbTx)
+	time := time.Unix(int64(blockTime), 0)
+	return &time
 }
 
-func (xi *txBatchIndexer) updateRewardValidatorTx(dbTx *database.PChainTx, tx *txs.RewardValidatorTx) error {
+func (xi *txBatchIndexer) updateRewardValidatorTx(ctx context.Context, dbTx *database.PChainTx, tx *txs.RewardValidatorTx) error {
 	dbTx.Type = database.PChainRewardValidatorTx
 	dbTx.RewardTxID = tx.TxID.String()
 
-	outs, err := getRewardOutputs(xi.rpcClient, dbTx.RewardTxID)
+	outs, err := xi.rewardOutputs(ctx, dbTx.RewardTxID)
+	if err != nil {
+		return err
+	}
+	rewardOuts, err := utils.CastArray[*database.PChainTxOutput](outs)
+	if err != nil {
+		return err
+	}
+	err = xi.addStakeReward(ctx, dbTx, rewardOuts)
 	if err != nil {
 		return err
 	}
@@ -242,14 +327,22 @@ func (xi *txBatchIndexer) updateImportTx(dbTx *database.PChainTx, tx *txs.Import
 	dbTx.Type = database.PChainImportTx
 	dbTx.ChainID = tx.SourceChain.String()
 	xi.newTxs = append(xi.newTxs, dbTx)
+	if xi.importIndexer != nil {
+		xi.importIndexer.Add(nil, xi.importUpdater.addImportTx(*dbTx.TxID, tx))
+	}
 	return xi.inOutIndexer.AddNewFromBaseTx(*dbTx.TxID, &tx.BaseTx.BaseTx, PChainDefaultInputOutputCreator)
 }
 
 func (xi *txBatchIndexer) updateExportTx(dbTx *database.PChainTx, tx *txs.ExportTx) error {
 	dbTx.Type = database.PChainExportTx
 	dbTx.ChainID = tx.DestinationChain.String()
+	outs, err := getExportTxOutputs(*dbTx.TxID, tx)
+	if err != nil {
+		return err
+	}
 	xi.newTxs = append(xi.newTxs, dbTx)
-	return xi.inOutIndexer.AddNewFromBaseTx(*dbTx.TxID, &tx.BaseTx.BaseTx, PChainDefaultInputOutputCreator)
+	xi.inOutIndexer.Add(outs, shared.InputsFromTxIns(*dbTx.TxID, tx.Ins))
+	return nil
 }
 
 func (xi *txBatchIndexer) updateAdvanceTimeTx(dbTx *database.PChainTx, tx *txs.AdvanceTimeTx) {
@@ -267,12 +360,10 @@ func (xi *txBatchIndexer) updateGeneralBaseTx(dbTx *database.PChainTx, txType da
 
 // Persist all entities
 func (xi *txBatchIndexer) PersistEntities(db *gorm.DB) error {
-	updatableIns := xi.inOutIndexer.GetIns()
-	ins := make([]*database.PChainTxInput, 0, 2*len(updatableIns))
-	for _, in := range updatableIns {
-		for _, dbIn := range in.ToDbInputs() {
-			ins = append(ins, &database.PChainTxInput{TxInput: *dbIn})
-		}
+	ins := newDbInputs(xi.inOutIndexer.GetIns(), database.PChainDefaultInput)
+	var importedIns []*database.PChainTxInput
+	if xi.importIndexer != nil {
+		importedIns = newDbInputs(xi.importIndexer.GetIns(), database.PChainImportedInput)
 	}
 	outs, err := utils.CastArray[*database.PChainTxOutput](xi.inOutIndexer.GetNewOuts())
 	if err != nil {
@@ -285,7 +376,37 @@ func (xi *txBatchIndexer) PersistEntities(db *gorm.DB) error {
 	} else {
 		txs = xi.newTxs
 	}
-	return database.CreatePChainEntities(db, txs, ins, outs)
+	err = database.CreatePChainEntities(db, xi.newBlocks, txs, append(ins, importedIns...), outs)
+	if err != nil {
+		return err
+	}
+	err = persistUTXOChanges(db, newTxHeights(xi.newTxs), ins, outs)
+	if err != nil {
+		return err
+	}
+	err = database.CreatePChainStakeRewards(db, xi.rewards)
+	if err != nil {
+		return err
+	}
+	err = database.CreatePChainRewardsOwners(db, xi.rewardsOwners)
+	if err != nil {
+		return err
+	}
+	err = database.CreatePChainSubnetEntities(db, xi.subnets.subnets, xi.subnets.chains)
+	if err != nil {
+		return err
+	}
+	return database.CreatePChainL1Entities(db, xi.l1.conversions, xi.l1.validators, xi.l1.changes)
+}
+
+func newDbInputs(updatableIns []shared.UpdatableInput, inputType database.PChainInputType) []*database.PChainTxInput {
+	ins := make([]*database.PChainTxInput, 0, 2*len(updatableIns))
+	for _, in := range updatableIns {
+		for _, dbIn := range in.ToDbInputs() {
+			ins = append(ins, &database.PChainTxInput{TxInput: *dbIn, Type: inputType})
+		}
+	}
+	return ins
 }
 
 // Common code for addValidatorTx and addPermissionlessValidatorTx (ValidatorTx interface)
@@ -358,8 +479,13 @@ func (xi *txBatchIndexer) updateAddStakerTx(
 		return err
 	}
 	ins := shared.InputsFromTxIns(*dbTx.TxID, txIns)
+	owners, err := stakeRewardsOwners(*dbTx.TxID, tx)
+	if err != nil {
+		return err
+	}
 
 	xi.newTxs = append(xi.newTxs, dbTx)
+	xi.rewardsOwners = append(xi.rewardsOwners, owners...)
 	xi.inOutIndexer.Add(outs, ins)
 	return nil
 }
@@ -381,8 +507,34 @@ func getAddStakerTxOutputs(txID string, tx StakerTx) ([]shared.Output, error) {
 	return outs, nil
 }
 
-func getRewardOutputs(client chain.RPCClient, txID string) ([]shared.Output, error) {
-	utxos, err := CallPChainGetRewardUTXOsApi(client, txID)
+// Outputs of tx stored in its block, reward outputs of staking transactions are not included
+func getTxOutputs(txID string, tx txs.UnsignedTx) ([]shared.Output, error) {
+	switch utx := tx.(type) {
+	case StakerTx:
+		return getAddStakerTxOutputs(txID, utx)
+	case *txs.ExportTx:
+		return getExportTxOutputs(txID, utx)
+	default:
+		return shared.OutputsFromTxOuts(txID, tx.Outputs(), 0, PChainDefaultInputOutputCreator)
+	}
+}
+
+// Outputs of ExportTx followed by exported outputs. Indexes of exported outputs are their
+// UTXO indexes on the destination chain.
+func getExportTxOutputs(txID string, tx *txs.ExportTx) ([]shared.Output, error) {
+	outs, err := shared.OutputsFromTxOuts(txID, tx.Outs, 0, PChainDefaultInputOutputCreator)
+	if err != nil {
+		return nil, err
+	}
+	exportedOuts, err := shared.OutputsFromTxOuts(txID, tx.ExportedOutputs, len(tx.Outs), PChainExportedOutputCreator)
+	if err != nil {
+		return nil, err
+	}
+	return append(outs, exportedOuts...), nil
+}
+
+func getRewardOutputs(ctx context.Context, client chain.RPCClient, txID string) ([]shared.Output, error) {
+	utxos, err := CallPChainGetRewardUTXOsApi(ctx, client, txID)
 	if err != nil {
 		return nil, err
 	}
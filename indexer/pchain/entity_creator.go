@@ -10,6 +10,8 @@ var (
 	PChainDefaultInputOutputCreator = inputOutputCreator{outputType: database.PChainDefaultOutput}
 	PChainStakerInputOutputCreator  = inputOutputCreator{outputType: database.PChainStakeOutput}
 	PChainRewardOutputCreator       = inputOutputCreator{outputType: database.PChainRewardOutput}
+	PChainExportedOutputCreator     = inputOutputCreator{outputType: database.PChainExportedOutput}
+	PChainGenesisOutputCreator      = inputOutputCreator{outputType: database.PChainGenesisOutput}
 )
 
 type inputOutputCreator struct {
@@ -20,6 +22,7 @@ func (ioc inputOutputCreator) CreateInputs(in shared.UpdatableInput) []*database
 	return utils.Map(in.ToDbInputs(), func(dbIn *database.TxInput) *database.PChainTxInput {
 		return &database.PChainTxInput{
 			TxInput: *dbIn,
+			Type:    database.PChainDefaultInput,
 		}
 	})
 }
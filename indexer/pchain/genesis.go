@@ -0,0 +1,147 @@
+package pchain
+
+import (
+	"context"
+	"flare-indexer/database"
+	indexerctx "flare-indexer/indexer/context"
+	"flare-indexer/indexer/shared"
+	"flare-indexer/logger"
+	"flare-indexer/utils"
+	"flare-indexer/utils/chain"
+	"time"
+
+	"github.com/ava-labs/avalanchego/genesis"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/indexer"
+	"github.com/ava-labs/avalanchego/utils/hashing"
+	"github.com/ava-labs/avalanchego/vms/platformvm/block"
+	platformgenesis "github.com/ava-labs/avalanchego/vms/platformvm/genesis"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// State created when the genesis is loaded
+const GenesisStateName = "p_chain_genesis"
+
+// Load the network genesis if it is not loaded yet and persist its UTXOs, initial validators
+// and chains. Genesis transactions have height 0 and block type GENESIS, genesis UTXOs have
+// the empty transaction ID. Inputs spending genesis outputs are then resolved from the database
+// like inputs spending indexed outputs.
+func loadGenesis(ctx context.Context, ictx indexerctx.IndexerContext) error {
+	db := ictx.DB()
+	_, err := database.FetchState(db, GenesisStateName)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	cfg := ictx.Config()
+	genesisConfig, err := chain.GenesisConfig(cfg.Chain.ChainAddressHRP, cfg.PChainIndexer.GenesisFile)
+	if err != nil {
+		return errors.Wrap(err, "failed to load genesis config")
+	}
+	if genesisConfig == nil {
+		logger.Warn("No genesis is embedded for network %s, set genesis_file to index genesis UTXOs and validators",
+			cfg.Chain.ChainAddressHRP)
+		return nil
+	}
+	genesisBytes, _, err := genesis.FromConfig(genesisConfig)
+	if err != nil {
+		return errors.Wrap(err, "failed to build genesis")
+	}
+
+	// Genesis transactions have no inputs, no inputs need to be updated
+	xi := NewPChainBatchIndexer(ictx, nil, nil, nil)
+	xi.Reset(0)
+	err = xi.addGenesis(ctx, genesisBytes)
+	if err != nil {
+		return err
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		err := xi.PersistEntities(tx)
+		if err != nil {
+			return err
+		}
+		err = updateGenesisSpends(tx, xi.newTxs, xi.inOutIndexer.GetNewOuts())
+		if err != nil {
+			return err
+		}
+		return database.CreateState(tx, &database.State{Name: GenesisStateName, Updated: time.Now()})
+	})
+	if err != nil {
+		return err
+	}
+	logger.Info("Loaded genesis with %d transactions and %d outputs", len(xi.newTxs), len(xi.inOutIndexer.GetNewOuts()))
+	return nil
+}
+
+// Add validators, chains and UTXOs of the platform chain genesis
+func (xi *txBatchIndexer) addGenesis(ctx context.Context, genesisBytes []byte) error {
+	g, err := platformgenesis.Parse(genesisBytes)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse genesis")
+	}
+	// Genesis block is the commit block at height 0 with the genesis hash as parent
+	blk, err := block.NewApricotCommitBlock(hashing.ComputeHash256Array(genesisBytes), 0)
+	if err != nil {
+		return err
+	}
+	container := &indexer.Container{ID: blk.ID(), Timestamp: int64(g.Timestamp)}
+	for _, tx := range append(g.Validators, g.Chains...) {
+		err = xi.addTx(ctx, container, database.PChainGenesisBlock, 0, 0, tx)
+		if err != nil {
+			return err
+		}
+	}
+
+	txID := ids.Empty.String()
+	var outs []shared.Output
+	for _, utxo := range g.UTXOs {
+		dbOuts, err := shared.CreateTransferableOutputs(txID, utxo.OutputIndex, utxo.AssetID(), utxo.Out)
+		if err != nil {
+			return err
+		}
+		for _, dbOut := range dbOuts {
+			outs = append(outs, PChainGenesisOutputCreator.CreateOutput(dbOut))
+		}
+	}
+	xi.inOutIndexer.Add(outs, nil)
+	return nil
+}
+
+// Update entities indexed before the genesis was loaded: inputs spending genesis UTXOs get
+// their addresses, genesis outputs spent by indexed inputs are marked as spent and stakes of
+// genesis validators are returned at the height of their indexed reward transactions.
+func updateGenesisSpends(db *gorm.DB, genesisTxs []*database.PChainTx, newOuts []shared.Output) error {
+	for _, out := range newOuts {
+		dbOut := out.(*database.PChainTxOutput)
+		if dbOut.Type != database.PChainGenesisOutput {
+			continue
+		}
+		err := database.UpdatePChainInputAddresses(db, dbOut.TxID, dbOut.Idx, dbOut.Address)
+		if err != nil {
+			return err
+		}
+	}
+
+	txIDs := append(utils.Map(genesisTxs, func(tx *database.PChainTx) string { return *tx.TxID }), ids.Empty.String())
+	rewardTxs, err := database.FetchPChainRewardTxs(db, txIDs)
+	if err != nil {
+		return err
+	}
+	ins, err := database.FetchPChainInputsSpending(db, txIDs)
+	if err != nil {
+		return err
+	}
+	heights, err := fetchTxHeights(db, rewardTxs, utils.Map(ins, func(in *database.PChainTxInput) string { return in.TxID }))
+	if err != nil {
+		return err
+	}
+	err = database.UnstakePChainUTXOs(db, heights.rewards)
+	if err != nil {
+		return err
+	}
+	return database.SpendPChainUTXOs(db, heights.spends(ins))
+}
@@ -0,0 +1,104 @@
+package pchain
+
+import (
+	"context"
+	"flare-indexer/database"
+	"flare-indexer/indexer/shared"
+	"flare-indexer/utils/chain"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/genesis"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/constants"
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenesis(t *testing.T) {
+	chain.AddressHRP = constants.LocalHRP
+	cfg, err := chain.GenesisConfig(constants.LocalHRP, "")
+	require.NoError(t, err)
+	require.NotNil(t, cfg)
+	genesisBytes, _, err := genesis.FromConfig(cfg)
+	require.NoError(t, err)
+
+	xi := newL1TestBatchIndexer()
+	require.NoError(t, xi.addGenesis(context.Background(), genesisBytes))
+
+	// Initial validators and the X-chain and C-chain
+	require.Len(t, xi.newTxs, len(cfg.InitialStakers)+2)
+	validators := 0
+	for _, tx := range xi.newTxs {
+		require.Equal(t, database.PChainGenesisBlock, tx.BlockType)
+		require.Equal(t, uint64(0), tx.BlockHeight)
+		require.Equal(t, int64(cfg.StartTime), tx.Timestamp.Unix())
+		if tx.Type == database.PChainAddValidatorTx || tx.Type == database.PChainAddPermissionlessValidatorTx {
+			validators++
+			require.NotEmpty(t, tx.NodeID)
+			require.NotZero(t, tx.Weight)
+		}
+	}
+	require.Equal(t, len(cfg.InitialStakers), validators)
+	require.Len(t, xi.subnets.chains, 2)
+
+	var genesisOuts, stakeOuts int
+	for _, out := range xi.inOutIndexer.GetNewOuts() {
+		dbOut := out.(*database.PChainTxOutput)
+		switch dbOut.Type {
+		case database.PChainGenesisOutput:
+			genesisOuts++
+			require.Equal(t, ids.Empty.String(), dbOut.TxID)
+			require.NotZero(t, dbOut.Amount)
+			require.NotEmpty(t, dbOut.Address)
+		case database.PChainStakeOutput:
+			stakeOuts++
+		}
+	}
+	require.NotZero(t, genesisOuts)
+	require.NotZero(t, stakeOuts)
+
+	// No genesis is embedded for unknown networks
+	cfg, err = chain.GenesisConfig("unknown", "")
+	require.NoError(t, err)
+	require.Nil(t, cfg)
+}
+
+type testInput struct {
+	outTx     string
+	addresses []string
+}
+
+func (in *testInput) OutTx() string                      { return in.outTx }
+func (in *testInput) OutIndex() uint32                   { return 0 }
+func (in *testInput) UpdateAddresses(addresses []string) { in.addresses = addresses }
+func (in *testInput) ToDbInputs() []*database.TxInput    { return nil }
+
+// Inputs spending genesis outputs which are not stored are resolved without outputs (the
+// placeholder must not be a nil output)
+func TestUpdateFromChainGenesisInput(t *testing.T) {
+	in := &testInput{outTx: ids.Empty.String()}
+	iu := &pChainInputUpdater{}
+	missing, err := iu.updateFromChain(context.Background(), shared.NewInputList([]shared.UpdatableInput{in}),
+		mapset.NewSet(in.outTx))
+	require.NoError(t, err)
+	require.Empty(t, missing.ToSlice())
+	require.Equal(t, []string{ids.Empty.String()}, in.addresses)
+}
+
+// Inputs spending stored genesis outputs get the addresses of the outputs
+func TestUpdateFromDBGenesisInput(t *testing.T) {
+	cfg := database.TestDBConfig("flare_indexer_indexer")
+	db, err := database.ConnectAndInitializeTestDB(&cfg, true)
+	require.NoError(t, err)
+	require.NoError(t, database.CreatePChainEntities(db, nil, nil, nil, []*database.PChainTxOutput{{
+		TxOutput: database.TxOutput{TxID: ids.Empty.String(), Idx: 0, Amount: 10, Address: "genesis"},
+		Type:     database.PChainGenesisOutput,
+	}}))
+
+	in := &testInput{outTx: ids.Empty.String()}
+	iu := &pChainInputUpdater{db: db}
+	missing, err := iu.updateFromDB(shared.NewInputList([]shared.UpdatableInput{in}), mapset.NewSet(in.outTx))
+	require.NoError(t, err)
+	require.Empty(t, missing.ToSlice())
+	require.Equal(t, []string{"genesis"}, in.addresses)
+}
@@ -0,0 +1,196 @@
+package pchain
+
+import (
+	"context"
+	"encoding/json"
+	"flare-indexer/database"
+	indexerctx "flare-indexer/indexer/context"
+	"flare-indexer/indexer/shared"
+	"flare-indexer/utils/chain"
+	"fmt"
+
+	"github.com/ava-labs/avalanchego/api"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/formatting"
+	avmtxs "github.com/ava-labs/avalanchego/vms/avm/txs"
+	"github.com/ava-labs/avalanchego/vms/platformvm/txs"
+	"github.com/ava-labs/avalanchego/wallet/chain/x/builder"
+	"github.com/ava-labs/coreth/plugin/evm"
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+const (
+	xChainAlias = "X"
+	cChainAlias = "C"
+)
+
+// Input updater for imported inputs of ImportTxs. Imported inputs spend outputs exported by
+// the source chain. Outputs are read from the X-chain tables if the export was indexed by the
+// X-chain indexer, otherwise the export transaction is fetched from the source chain.
+type importedInputUpdater struct {
+	shared.BaseInputUpdater
+
+	db      *gorm.DB
+	clients map[string]chain.AtomicTxClient // chain alias -> client
+
+	// Source chains of export transactions referred by imported inputs of the batch
+	sources map[string]ids.ID
+
+	// Chain IDs of the X-chain and C-chain, fetched when needed
+	fetchChainID func(ctx context.Context, alias string) (ids.ID, error)
+	chainAliases map[ids.ID]string
+}
+
+func newImportedInputUpdater(ctx indexerctx.IndexerContext) *importedInputUpdater {
+	cfg := &ctx.Config().Chain
+	iu := &importedInputUpdater{
+		db: ctx.DB(),
+		clients: map[string]chain.AtomicTxClient{
+			xChainAlias: chain.NewAtomicTxClientFromConfig(cfg, "ext/bc/X", chain.XChainGetTxMethod,
+				"ext/index/P/block", "x_chain_atomic_tx_client"),
+			cChainAlias: chain.NewAtomicTxClientFromConfig(cfg, "ext/bc/C/avax", chain.CChainGetAtomicTxMethod,
+				"ext/index/P/block", "c_chain_atomic_tx_client"),
+		},
+		fetchChainID: func(ctx context.Context, alias string) (ids.ID, error) {
+			return chain.FetchBlockchainID(ctx, cfg, alias)
+		},
+	}
+	iu.InitCache()
+	iu.sources = make(map[string]ids.ID)
+	return iu
+}
+
+// Add imported inputs of tx to be updated, returns the inputs
+func (iu *importedInputUpdater) addImportTx(txID string, tx *txs.ImportTx) []shared.UpdatableInput {
+	for _, in := range tx.ImportedInputs {
+		iu.sources[in.TxID.String()] = tx.SourceChain
+	}
+	return shared.InputsFromTxInsAt(txID, tx.ImportedInputs, len(tx.Ins))
+}
+
+func (iu *importedInputUpdater) PurgeCache() {
+	iu.BaseInputUpdater.PurgeCache()
+	iu.sources = make(map[string]ids.ID)
+}
+
+func (iu *importedInputUpdater) UpdateInputs(ctx context.Context, inputs shared.InputList) (mapset.Set[string], error) {
+	missingTxIds := iu.UpdateInputsFromCache(inputs)
+	missingTxIds, err := iu.updateFromDB(inputs, missingTxIds)
+	if err != nil {
+		return nil, err
+	}
+	return iu.updateFromChain(ctx, inputs, missingTxIds)
+}
+
+// Update from outputs exported by X-chain transactions indexed by the X-chain indexer
+func (iu *importedInputUpdater) updateFromDB(
+	inputs shared.InputList,
+	missingTxIds mapset.Set[string],
+) (mapset.Set[string], error) {
+	outs, err := database.FetchXChainExportedOutputs(iu.db, missingTxIds.ToSlice())
+	if err != nil {
+		return nil, err
+	}
+	baseOuts := shared.NewOutputMap()
+	for i, out := range outs {
+		baseOuts.Add(shared.NewIdIndexKey(out.TxID, out.Index()), &outs[i].TxOutput)
+	}
+	return inputs.UpdateWithOutputs(baseOuts), nil
+}
+
+// Update from export transactions fetched from the source chain, fetched outputs are cached
+func (iu *importedInputUpdater) updateFromChain(
+	ctx context.Context,
+	inputs shared.InputList,
+	missingTxIds mapset.Set[string],
+) (mapset.Set[string], error) {
+	fetchedOuts := shared.NewOutputMap()
+	for txId := range missingTxIds.Iterator().C {
+		alias, err := iu.sourceChainAlias(ctx, iu.sources[txId])
+		if err != nil {
+			return nil, err
+		}
+		id, err := ids.FromString(txId)
+		if err != nil {
+			return nil, err
+		}
+		reply, err := iu.clients[alias].GetAtomicTx(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		txBytes, err := decodeTxReply(reply)
+		if err != nil {
+			return nil, err
+		}
+		outs, err := exportedOutputs(alias, txId, txBytes)
+		if err != nil {
+			return nil, err
+		}
+		iu.CacheOutputs(outs)
+		for _, out := range outs {
+			fetchedOuts.Add(shared.NewIdIndexKey(out.Tx(), out.Index()), out)
+		}
+	}
+	return inputs.UpdateWithOutputs(fetchedOuts), nil
+}
+
+// Returns the alias of the source chain (X or C)
+func (iu *importedInputUpdater) sourceChainAlias(ctx context.Context, chainID ids.ID) (string, error) {
+	if iu.chainAliases == nil {
+		aliases := make(map[ids.ID]string, len(iu.clients))
+		for alias := range iu.clients {
+			id, err := iu.fetchChainID(ctx, alias)
+			if err != nil {
+				return "", errors.Wrapf(err, "failed to fetch ID of chain %s", alias)
+			}
+			aliases[id] = alias
+		}
+		iu.chainAliases = aliases
+	}
+	alias, ok := iu.chainAliases[chainID]
+	if !ok {
+		return "", fmt.Errorf("unsupported source chain %s of imported inputs", chainID)
+	}
+	return alias, nil
+}
+
+// Outputs exported by an X-chain ExportTx or a C-chain atomic export transaction. Indexes of
+// outputs are UTXO indexes: X-chain exported outputs follow the outputs of the base tx.
+func exportedOutputs(alias string, txID string, txBytes []byte) ([]shared.Output, error) {
+	switch alias {
+	case xChainAlias:
+		tx, err := builder.Parser.ParseGenesisTx(txBytes)
+		if err != nil {
+			return nil, err
+		}
+		exportTx, ok := tx.Unsigned.(*avmtxs.ExportTx)
+		if !ok {
+			return nil, fmt.Errorf("X-chain transaction %s is not an export transaction", txID)
+		}
+		return shared.OutputsFromTxOuts(txID, exportTx.ExportedOuts, len(exportTx.Outs), PChainDefaultInputOutputCreator)
+	case cChainAlias:
+		tx, err := evm.ExtractAtomicTx(txBytes, evm.Codec)
+		if err != nil {
+			return nil, err
+		}
+		exportTx, ok := tx.UnsignedAtomicTx.(*evm.UnsignedExportTx)
+		if !ok {
+			return nil, fmt.Errorf("C-chain transaction %s is not an export transaction", txID)
+		}
+		return shared.OutputsFromTxOuts(txID, exportTx.ExportedOutputs, 0, PChainDefaultInputOutputCreator)
+	default:
+		return nil, fmt.Errorf("unsupported chain %s", alias)
+	}
+}
+
+// Decode hex encoded transaction bytes of the reply
+func decodeTxReply(reply *api.GetTxReply) ([]byte, error) {
+	var txString string
+	err := json.Unmarshal(reply.Tx, &txString)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal tx string")
+	}
+	return formatting.Decode(formatting.Hex, txString)
+}
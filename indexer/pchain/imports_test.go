@@ -0,0 +1,153 @@
+package pchain
+
+import (
+	"context"
+	"encoding/json"
+	"flare-indexer/database"
+	"flare-indexer/indexer/shared"
+	"flare-indexer/utils/chain"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/api"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/formatting"
+	avmtxs "github.com/ava-labs/avalanchego/vms/avm/txs"
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+	"github.com/ava-labs/avalanchego/vms/platformvm/txs"
+	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+	"github.com/ava-labs/avalanchego/wallet/chain/x/builder"
+	"github.com/ava-labs/coreth/plugin/evm"
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/stretchr/testify/require"
+)
+
+// Atomic tx client returning transaction bytes by ID
+type testAtomicTxClient map[ids.ID][]byte
+
+func (c testAtomicTxClient) GetAtomicTx(ctx context.Context, id ids.ID) (*api.GetTxReply, error) {
+	txString, err := formatting.Encode(formatting.Hex, c[id])
+	if err != nil {
+		return nil, err
+	}
+	txJson, err := json.Marshal(txString)
+	if err != nil {
+		return nil, err
+	}
+	return &api.GetTxReply{Tx: txJson, Encoding: formatting.Hex}, nil
+}
+
+func newTestExportedOutput(amount uint64, addr ids.ShortID) *avax.TransferableOutput {
+	return &avax.TransferableOutput{
+		Asset: avax.Asset{ID: ids.GenerateTestID()},
+		Out: &secp256k1fx.TransferOutput{
+			Amt:          amount,
+			OutputOwners: secp256k1fx.OutputOwners{Threshold: 1, Addrs: []ids.ShortID{addr}},
+		},
+	}
+}
+
+func TestImportedInputs(t *testing.T) {
+	chain.AddressHRP = "costwo"
+	xChainID, cChainID := ids.GenerateTestID(), ids.GenerateTestID()
+	xAddr, cAddr := ids.GenerateTestShortID(), ids.GenerateTestShortID()
+
+	xTx := &avmtxs.Tx{Unsigned: &avmtxs.ExportTx{
+		BaseTx:       avmtxs.BaseTx{BaseTx: avax.BaseTx{Outs: []*avax.TransferableOutput{newTestExportedOutput(1, xAddr)}}},
+		ExportedOuts: []*avax.TransferableOutput{newTestExportedOutput(10, xAddr)},
+	}}
+	require.NoError(t, xTx.Initialize(builder.Parser.Codec()))
+	cTx := &evm.Tx{UnsignedAtomicTx: &evm.UnsignedExportTx{
+		ExportedOutputs: []*avax.TransferableOutput{newTestExportedOutput(20, cAddr)},
+	}}
+	require.NoError(t, cTx.Sign(evm.Codec, nil))
+
+	iu := &importedInputUpdater{
+		clients: map[string]chain.AtomicTxClient{
+			xChainAlias: testAtomicTxClient{xTx.ID(): xTx.Bytes()},
+			cChainAlias: testAtomicTxClient{cTx.ID(): cTx.SignedBytes()},
+		},
+		fetchChainID: func(ctx context.Context, alias string) (ids.ID, error) {
+			return map[string]ids.ID{xChainAlias: xChainID, cChainAlias: cChainID}[alias], nil
+		},
+	}
+	iu.InitCache()
+	iu.PurgeCache()
+
+	xImport := &txs.ImportTx{
+		BaseTx:      txs.BaseTx{BaseTx: avax.BaseTx{Ins: []*avax.TransferableInput{{In: &secp256k1fx.TransferInput{Amt: 5}}}}},
+		SourceChain: xChainID,
+		ImportedInputs: []*avax.TransferableInput{{
+			UTXOID: avax.UTXOID{TxID: xTx.ID(), OutputIndex: 1},
+			In:     &secp256k1fx.TransferInput{Amt: 10},
+		}},
+	}
+	cImport := &txs.ImportTx{
+		SourceChain: cChainID,
+		ImportedInputs: []*avax.TransferableInput{{
+			UTXOID: avax.UTXOID{TxID: cTx.ID(), OutputIndex: 0},
+			In:     &secp256k1fx.TransferInput{Amt: 20},
+		}},
+	}
+	ins := append(iu.addImportTx("xImport", xImport), iu.addImportTx("cImport", cImport)...)
+
+	txIDs := mapset.NewSet(xTx.ID().String(), cTx.ID().String())
+	missing, err := iu.updateFromChain(context.Background(), shared.NewInputList(ins), txIDs)
+	require.NoError(t, err)
+	require.Zero(t, missing.Cardinality())
+
+	xAddress, err := chain.FormatAddressBytes(xAddr.Bytes())
+	require.NoError(t, err)
+	cAddress, err := chain.FormatAddressBytes(cAddr.Bytes())
+	require.NoError(t, err)
+	require.Equal(t, []*database.TxInput{{
+		InIdx: 1, TxID: "xImport", Amount: 10, Address: xAddress, OutTxID: xTx.ID().String(), OutIdx: 1,
+	}}, ins[0].ToDbInputs())
+	require.Equal(t, []*database.TxInput{{
+		InIdx: 0, TxID: "cImport", Amount: 20, Address: cAddress, OutTxID: cTx.ID().String(), OutIdx: 0,
+	}}, ins[1].ToDbInputs())
+
+	// Source chain which is neither the X-chain nor the C-chain
+	iu.sources[xTx.ID().String()] = ids.GenerateTestID()
+	_, err = iu.updateFromChain(context.Background(), shared.NewInputList(ins[:1]), mapset.NewSet(xTx.ID().String()))
+	require.Error(t, err)
+}
+
+// Inputs of both types are joined by the voting and staking queries
+func TestStakingQueriesWithImportedInputs(t *testing.T) {
+	cfg := database.TestDBConfig("flare_indexer_indexer")
+	db, err := database.ConnectAndInitializeTestDB(&cfg, true)
+	require.NoError(t, err)
+
+	txID := "stake"
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	require.NoError(t, database.CreatePChainEntities(db, nil,
+		[]*database.PChainTx{{
+			TxID: &txID, Type: database.PChainAddDelegatorTx, BlockID: "block", NodeID: "NodeID-1",
+			StartTime: &start, EndTime: &end, Weight: 100,
+		}},
+		[]*database.PChainTxInput{
+			{TxInput: database.TxInput{TxID: txID, InIdx: 0, Address: "base"}, Type: database.PChainDefaultInput},
+			{TxInput: database.TxInput{TxID: txID, InIdx: 1, Address: "imported"}, Type: database.PChainImportedInput},
+		},
+		nil,
+	))
+
+	data, err := database.FetchPChainVotingData(db, start, end)
+	require.NoError(t, err)
+	require.Len(t, data, 2)
+	require.Equal(t, database.PChainAddDelegatorTx, data[0].Type)
+
+	txTypes := []database.PChainTxType{database.PChainAddDelegatorTx}
+	at := start.Add(time.Minute)
+	txIDs, err := database.FetchPChainStakingTransactions(db, txTypes, "", "", at, 0, 10)
+	require.NoError(t, err)
+	require.Equal(t, []string{txID}, txIDs)
+	txIDs, err = database.FetchPChainStakingTransactions(db, txTypes, "NodeID-1", "imported", at, 0, 10)
+	require.NoError(t, err)
+	require.Equal(t, []string{txID}, txIDs)
+	txIDs, err = database.FetchPChainStakingTransactions(db, txTypes, "", "other", at, 0, 10)
+	require.NoError(t, err)
+	require.Empty(t, txIDs)
+}
@@ -1,8 +1,9 @@
 package pchain
 
 import (
+	"context"
 	"flare-indexer/database"
-	"flare-indexer/indexer/context"
+	indexerctx "flare-indexer/indexer/context"
 	"flare-indexer/indexer/shared"
 	"flare-indexer/utils/chain"
 
@@ -18,7 +19,7 @@ type pChainInputUpdater struct {
 	client chain.RPCClient
 }
 
-func newPChainInputUpdater(ctx context.IndexerContext, client chain.RPCClient) *pChainInputUpdater {
+func newPChainInputUpdater(ctx indexerctx.IndexerContext, client chain.RPCClient) *pChainInputUpdater {
 	ioUpdater := pChainInputUpdater{
 		db:     ctx.DB(),
 		client: client,
@@ -27,13 +28,13 @@ func newPChainInputUpdater(ctx context.IndexerContext, client chain.RPCClient) *
 	return &ioUpdater
 }
 
-func (iu *pChainInputUpdater) UpdateInputs(inputs shared.InputList) (mapset.Set[string], error) {
+func (iu *pChainInputUpdater) UpdateInputs(ctx context.Context, inputs shared.InputList) (mapset.Set[string], error) {
 	missingTxIds := iu.UpdateInputsFromCache(inputs)
 	missingTxIds, err := iu.updateFromDB(inputs, missingTxIds)
 	if err != nil {
 		return nil, err
 	}
-	return iu.updateFromChain(inputs, missingTxIds)
+	return iu.updateFromChain(ctx, inputs, missingTxIds)
 }
 
 // notUpdated is a map from *output* id to inputs referring this output
@@ -54,31 +55,32 @@ func (iu *pChainInputUpdater) updateFromDB(
 
 // notUpdated is a map from *output* id to inputs referring this output
 func (iu *pChainInputUpdater) updateFromChain(
+	ctx context.Context,
 	inputs shared.InputList,
 	missingTxIds mapset.Set[string],
 ) (mapset.Set[string], error) {
 	fetchedOuts := shared.NewOutputMap()
 	for txId := range missingTxIds.Iterator().C {
-		tx, err := CallPChainGetTxApi(iu.client, txId)
+		tx, err := CallPChainGetTxApi(ctx, iu.client, txId)
 		if err != nil {
 			return nil, err
 		}
 		if tx == nil {
-			// Genesis tx
-			fetchedOuts.Add(shared.NewIdIndexKey(txId, 0), nil)
+			// Genesis tx, outputs of the genesis are stored if the genesis is loaded
+			fetchedOuts[shared.NewIdIndexKey(txId, 0)] = nil
 			continue
 		}
 
 		var outs []shared.Output
 		switch unsignedTx := tx.Unsigned.(type) {
 		case *txs.AddValidatorTx:
-			outs, err = iu.getAddStakerTxAndRewardTxOutputs(txId, unsignedTx)
+			outs, err = iu.getAddStakerTxAndRewardTxOutputs(ctx, txId, unsignedTx)
 		case *txs.AddPermissionlessValidatorTx:
-			outs, err = iu.getAddStakerTxAndRewardTxOutputs(txId, unsignedTx)
+			outs, err = iu.getAddStakerTxAndRewardTxOutputs(ctx, txId, unsignedTx)
 		case *txs.AddDelegatorTx:
-			outs, err = iu.getAddStakerTxAndRewardTxOutputs(txId, unsignedTx)
+			outs, err = iu.getAddStakerTxAndRewardTxOutputs(ctx, txId, unsignedTx)
 		case *txs.AddPermissionlessDelegatorTx:
-			outs, err = iu.getAddStakerTxAndRewardTxOutputs(txId, unsignedTx)
+			outs, err = iu.getAddStakerTxAndRewardTxOutputs(ctx, txId, unsignedTx)
 		default:
 			txOuts := tx.Unsigned.Outputs()
 			outs, err = shared.OutputsFromTxOuts(txId, txOuts, 0, PChainDefaultInputOutputCreator)
@@ -93,12 +95,12 @@ func (iu *pChainInputUpdater) updateFromChain(
 	return inputs.UpdateWithOutputs(fetchedOuts), nil
 }
 
-func (iu *pChainInputUpdater) getAddStakerTxAndRewardTxOutputs(txId string, tx StakerTx) ([]shared.Output, error) {
+func (iu *pChainInputUpdater) getAddStakerTxAndRewardTxOutputs(ctx context.Context, txId string, tx StakerTx) ([]shared.Output, error) {
 	outs, err := getAddStakerTxOutputs(txId, tx)
 	if err != nil {
 		return nil, err
 	}
-	rewardOuts, err := getRewardOutputs(iu.client, txId)
+	rewardOuts, err := getRewardOutputs(ctx, iu.client, txId)
 	if err != nil {
 		return nil, err
 	}
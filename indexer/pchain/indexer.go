@@ -1,10 +1,10 @@
 package pchain
 
 import (
+	"context"
 	"flare-indexer/config"
-	"flare-indexer/indexer/context"
+	indexerctx "flare-indexer/indexer/context"
 	"flare-indexer/indexer/shared"
-	"flare-indexer/utils"
 	"flare-indexer/utils/chain"
 )
 
@@ -16,33 +16,80 @@ type pChainBlockIndexer struct {
 	shared.ChainIndexerBase
 }
 
-func CreatePChainBlockIndexer(ctx context.IndexerContext) *pChainBlockIndexer {
-	config := ctx.Config().PChainIndexer
-	client := newIndexerClient(&ctx.Config().Chain)
-	rpcClient := newJsonRpcClient(&ctx.Config().Chain)
+func CreatePChainBlockIndexer(ctx context.Context, ictx indexerctx.IndexerContext) (*pChainBlockIndexer, error) {
+	config := ictx.Config().PChainIndexer
+	client, archive, err := shared.NewArchiveClient(config, newIndexerClient(&ictx.Config().Chain))
+	if err != nil {
+		return nil, err
+	}
+	rpcClient := newJsonRpcClient(&ictx.Config().Chain)
+
+	if config.Enabled {
+		err = loadGenesis(ctx, ictx)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	idxr := pChainBlockIndexer{}
 	idxr.StateName = StateName
 	idxr.IndexerName = "P-chain Blocks"
 	idxr.Client = client
-	idxr.DB = ctx.DB()
+	idxr.Archive = archive
+	idxr.DB = ictx.DB()
 	idxr.Config = config
 	idxr.InitMetrics(StateName)
 
-	idxr.BatchIndexer = NewPChainBatchIndexer(ctx, client, rpcClient, nil)
+	idxr.BatchIndexer = NewPChainBatchIndexer(ictx, client, rpcClient, nil)
+
+	return &idxr, nil
+}
+
+// Create an indexer for the closed index range [start, end] with its own state. Used by
+// backfill workers, has no metrics. Containers are read from the archive in replay mode,
+// but they are never archived (workers would write to the same segment files). Blocks at
+// index i have height i + heightOffset.
+func CreatePChainSegmentIndexer(ctx indexerctx.IndexerContext, stateName string, start, end, heightOffset uint64) (*pChainBlockIndexer, error) {
+	config := ctx.Config().PChainIndexer
+	config.StartIndex = start
+	client, err := newContainerClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rpcClient := newJsonRpcClient(&ctx.Config().Chain)
+
+	idxr := pChainBlockIndexer{}
+	idxr.StateName = stateName
+	idxr.IndexerName = "P-chain Backfill " + stateName
+	idxr.Client = client
+	idxr.DB = ctx.DB()
+	idxr.Config = config
+	idxr.EndIndex = end
 
-	return &idxr
+	batchIndexer := NewPChainBatchIndexer(ctx, client, rpcClient, nil)
+	batchIndexer.heightOffset = &heightOffset
+	idxr.BatchIndexer = batchIndexer
+
+	return &idxr, nil
+}
+
+func (xi *pChainBlockIndexer) Run(ctx context.Context) {
+	xi.ChainIndexerBase.Run(ctx)
 }
 
-func (xi *pChainBlockIndexer) Run() {
-	xi.ChainIndexerBase.Run()
+// Client for reading containers: the archive in replay mode, the node(s) otherwise
+func newContainerClient(ctx indexerctx.IndexerContext) (chain.IndexerClient, error) {
+	config := ctx.Config().PChainIndexer
+	if config.ArchiveReplay && len(config.ArchiveDir) > 0 {
+		return chain.NewFileIndexerClient(config.ArchiveDir)
+	}
+	return newIndexerClient(&ctx.Config().Chain), nil
 }
 
 func newIndexerClient(cfg *config.ChainConfig) chain.IndexerClient {
-	return chain.NewAvalancheIndexerClient(utils.JoinPaths(cfg.NodeURL, "ext/index/P/block"),
-		chain.ClientOptions(cfg.ApiKey)...)
+	return chain.NewIndexerClientFromConfig(cfg, "ext/index/P/block", "p_chain_block_client")
 }
 
 func newJsonRpcClient(cfg *config.ChainConfig) chain.RPCClient {
-	return chain.NewAvalancheRPCClient(utils.JoinPaths(cfg.NodeURL, "ext/bc/P"+chain.RPCClientOptions(cfg.ApiKey)))
+	return chain.NewRPCClientFromConfig(cfg, "ext/bc/P", "ext/index/P/block", "p_chain_rpc_client")
 }
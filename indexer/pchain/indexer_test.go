@@ -1,20 +1,20 @@
-//go:build integration
-// +build integration
-
 package pchain
 
 import (
+	"context"
 	"encoding/hex"
 	"flare-indexer/database"
-	"flare-indexer/indexer/context"
+	indexerctx "flare-indexer/indexer/context"
+	"flare-indexer/indexer/shared"
 	"fmt"
 	"testing"
 
 	"github.com/ava-labs/avalanchego/utils/formatting/address"
+	"github.com/stretchr/testify/require"
 )
 
 func createPChainTestBlockIndexer(t *testing.T, batchSize int, startIndex uint64) *pChainBlockIndexer {
-	ctx, err := context.BuildTestContext(pchainIndexerTestConfig(batchSize, startIndex))
+	ctx, err := indexerctx.BuildTestContext(pchainIndexerTestConfig(batchSize, startIndex))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -43,7 +43,7 @@ func TestPChainBlockIndexerAll(t *testing.T) {
 	idxr := createPChainTestBlockIndexer(t, 10, 0)
 
 	// run one batch
-	err := idxr.IndexBatch()
+	err := idxr.IndexBatch(context.Background())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -53,12 +53,21 @@ func TestPChainBlockIndexerAll(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if len(txes) != 4 {
-		t.Fatalf("expected 4 txes, got %d", len(txes))
+	if len(txes) != 2 {
+		t.Fatalf("expected 2 txes, got %d", len(txes))
+	}
+
+	blocks, err := database.FetchPChainBlocksByHeights(idxr.DB, []uint64{1, 2, 3, 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(blocks) != 4 {
+		t.Fatalf("expected 4 blocks, got %d", len(blocks))
 	}
 
 	// run another batch
-	err = idxr.IndexBatch()
+	err = idxr.IndexBatch(context.Background())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -68,8 +77,17 @@ func TestPChainBlockIndexerAll(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if len(txes) != 5 {
-		t.Fatalf("expected 5 txes, got %d", len(txes))
+	if len(txes) != 3 {
+		t.Fatalf("expected 3 txes, got %d", len(txes))
+	}
+
+	blocks, err = database.FetchPChainBlocksByHeights(idxr.DB, []uint64{16, 17, 18, 19, 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(blocks) != 5 {
+		t.Fatalf("expected 5 blocks, got %d", len(blocks))
 	}
 
 }
@@ -80,7 +98,7 @@ func TestPChainBlockIndexerPartial(t *testing.T) {
 	idxr := createPChainTestBlockIndexer(t, 10, 20)
 
 	// run one batch
-	err := idxr.IndexBatch()
+	err := idxr.IndexBatch(context.Background())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -90,12 +108,21 @@ func TestPChainBlockIndexerPartial(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if len(txes) != 4 {
-		t.Fatalf("expected 4 txes, got %d", len(txes))
+	if len(txes) != 3 {
+		t.Fatalf("expected 3 txes, got %d", len(txes))
+	}
+
+	blocks, err := database.FetchPChainBlocksByHeights(idxr.DB, []uint64{21, 22, 23, 24})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(blocks) != 4 {
+		t.Fatalf("expected 4 blocks, got %d", len(blocks))
 	}
 
 	// run another batch
-	err = idxr.IndexBatch()
+	err = idxr.IndexBatch(context.Background())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -105,8 +132,17 @@ func TestPChainBlockIndexerPartial(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if len(txes) != 5 {
-		t.Fatalf("expected 5 txes, got %d", len(txes))
+	if len(txes) != 2 {
+		t.Fatalf("expected 2 txes, got %d", len(txes))
+	}
+
+	blocks, err = database.FetchPChainBlocksByHeights(idxr.DB, []uint64{26, 27, 28, 29, 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(blocks) != 5 {
+		t.Fatalf("expected 5 blocks, got %d", len(blocks))
 	}
 
 }
@@ -115,8 +151,41 @@ func TestIndexAllBlocks(t *testing.T) {
 	idxr := createPChainTestBlockIndexer(t, 200, 0)
 
 	// run batch
-	err := idxr.IndexBatch()
+	err := idxr.IndexBatch(context.Background())
 	if err != nil {
 		t.Fatal(err)
 	}
 }
+
+// Node index shifted against the indexed blocks is detected and a diagnostic record is stored
+func TestIndexerShiftedNodeIndex(t *testing.T) {
+	idxr := createPChainTestBlockIndexer(t, 10, 0)
+	require.NoError(t, idxr.IndexBatch(context.Background()))
+	state, err := database.FetchState(idxr.DB, StateName)
+	require.NoError(t, err)
+	require.Equal(t, uint64(10), state.NextDBIndex)
+
+	// Container at the last indexed index has the height of a block that is not the last indexed one
+	state.NextDBIndex = 13
+	require.NoError(t, database.UpdateState(idxr.DB, &state))
+	err = idxr.IndexBatch(context.Background())
+	require.ErrorIs(t, err, shared.ErrChainMismatch)
+	diagnostic, err := database.FetchLastIndexerDiagnostic(idxr.DB, StateName)
+	require.NoError(t, err)
+	require.NotNil(t, diagnostic)
+	require.Equal(t, database.IndexerDiagnosticContainerMismatch, diagnostic.Type)
+	require.Equal(t, uint64(12), diagnostic.Index)
+	require.NotEqual(t, diagnostic.StoredID, diagnostic.ChainID)
+
+	// No block is stored for the last indexed index
+	idxr = createPChainTestBlockIndexer(t, 10, 0)
+	state, err = database.FetchState(idxr.DB, StateName)
+	require.NoError(t, err)
+	state.NextDBIndex = 5
+	require.NoError(t, database.UpdateState(idxr.DB, &state))
+	err = idxr.IndexBatch(context.Background())
+	require.ErrorIs(t, err, shared.ErrChainMismatch)
+	diagnostic, err = database.FetchLastIndexerDiagnostic(idxr.DB, StateName)
+	require.NoError(t, err)
+	require.Empty(t, diagnostic.StoredID)
+}
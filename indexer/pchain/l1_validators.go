@@ -0,0 +1,193 @@
+package pchain
+
+import (
+	"encoding/hex"
+	"flare-indexer/database"
+	"flare-indexer/indexer/shared"
+	"fmt"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/vms/platformvm/txs"
+	"github.com/ava-labs/avalanchego/vms/platformvm/warp"
+	"github.com/ava-labs/avalanchego/vms/platformvm/warp/message"
+	"github.com/ava-labs/avalanchego/vms/platformvm/warp/payload"
+)
+
+// L1 validator registry entities of a batch
+type l1Entities struct {
+	conversions []*database.PChainL1Conversion
+	validators  []*database.PChainL1Validator
+	changes     []*database.PChainL1ValidatorChange
+}
+
+func (xi *txBatchIndexer) updateConvertSubnetToL1Tx(dbTx *database.PChainTx, tx *txs.ConvertSubnetToL1Tx) error {
+	blockTime, err := l1TxTime(dbTx)
+	if err != nil {
+		return err
+	}
+	dbTx.SubnetID = tx.Subnet.String()
+	dbTx.ChainID = tx.ChainID.String()
+
+	xi.l1.conversions = append(xi.l1.conversions, &database.PChainL1Conversion{
+		TxID:           *dbTx.TxID,
+		SubnetID:       tx.Subnet.String(),
+		ManagerChainID: tx.ChainID.String(),
+		ManagerAddress: hex.EncodeToString(tx.Address),
+		BlockHeight:    dbTx.BlockHeight,
+		Time:           blockTime,
+	})
+	for i, v := range tx.Validators {
+		validator, err := newL1Validator(dbTx, tx.Subnet.Append(uint32(i)), tx.Subnet, v.NodeID, v.Weight, v.Balance,
+			v.Signer.PublicKey[:], &v.RemainingBalanceOwner, &v.DeactivationOwner)
+		if err != nil {
+			return err
+		}
+		validator.StartTime = blockTime
+		xi.l1.validators = append(xi.l1.validators, validator)
+	}
+	return xi.updateGeneralBaseTx(dbTx, database.PChainConvertSubnetToL1Tx, &tx.BaseTx)
+}
+
+func (xi *txBatchIndexer) updateRegisterL1ValidatorTx(dbTx *database.PChainTx, tx *txs.RegisterL1ValidatorTx) error {
+	blockTime, err := l1TxTime(dbTx)
+	if err != nil {
+		return err
+	}
+	msgPayload, err := parseL1WarpPayload(tx.Message)
+	if err != nil {
+		return err
+	}
+	msg, err := message.ParseRegisterL1Validator(msgPayload)
+	if err != nil {
+		return err
+	}
+
+	validator, err := newL1Validator(dbTx, msg.ValidationID(), msg.SubnetID, msg.NodeID, msg.Weight, tx.Balance,
+		msg.BLSPublicKey[:], &msg.RemainingBalanceOwner, &msg.DisableOwner)
+	if err != nil {
+		return err
+	}
+	validator.StartTime = blockTime
+	xi.l1.validators = append(xi.l1.validators, validator)
+
+	dbTx.SubnetID = validator.SubnetID
+	dbTx.NodeID = validator.NodeID
+	dbTx.Weight = msg.Weight
+	return xi.updateGeneralBaseTx(dbTx, database.PChainRegisterL1ValidatorTx, &tx.BaseTx)
+}
+
+func (xi *txBatchIndexer) updateSetL1ValidatorWeightTx(dbTx *database.PChainTx, tx *txs.SetL1ValidatorWeightTx) error {
+	msgPayload, err := parseL1WarpPayload(tx.Message)
+	if err != nil {
+		return err
+	}
+	msg, err := message.ParseL1ValidatorWeight(msgPayload)
+	if err != nil {
+		return err
+	}
+	err = xi.addL1ValidatorChange(dbTx, msg.ValidationID, &database.PChainL1ValidatorChange{
+		Type:   database.PChainL1ValidatorWeightChange,
+		Weight: msg.Weight,
+		Nonce:  msg.Nonce,
+	})
+	if err != nil {
+		return err
+	}
+	dbTx.Weight = msg.Weight
+	return xi.updateGeneralBaseTx(dbTx, database.PChainSetL1ValidatorWeightTx, &tx.BaseTx)
+}
+
+func (xi *txBatchIndexer) updateIncreaseL1ValidatorBalanceTx(dbTx *database.PChainTx, tx *txs.IncreaseL1ValidatorBalanceTx) error {
+	err := xi.addL1ValidatorChange(dbTx, tx.ValidationID, &database.PChainL1ValidatorChange{
+		Type:    database.PChainL1ValidatorBalanceIncrease,
+		Balance: tx.Balance,
+	})
+	if err != nil {
+		return err
+	}
+	return xi.updateGeneralBaseTx(dbTx, database.PChainIncreaseL1ValidatorBalanceTx, &tx.BaseTx)
+}
+
+func (xi *txBatchIndexer) updateDisableL1ValidatorTx(dbTx *database.PChainTx, tx *txs.DisableL1ValidatorTx) error {
+	err := xi.addL1ValidatorChange(dbTx, tx.ValidationID, &database.PChainL1ValidatorChange{
+		Type: database.PChainL1ValidatorDisable,
+	})
+	if err != nil {
+		return err
+	}
+	return xi.updateGeneralBaseTx(dbTx, database.PChainDisableL1ValidatorTx, &tx.BaseTx)
+}
+
+func (xi *txBatchIndexer) addL1ValidatorChange(dbTx *database.PChainTx, validationID ids.ID, change *database.PChainL1ValidatorChange) error {
+	blockTime, err := l1TxTime(dbTx)
+	if err != nil {
+		return err
+	}
+	change.TxID = *dbTx.TxID
+	change.ValidationID = validationID.String()
+	change.BlockHeight = dbTx.BlockHeight
+	change.Time = blockTime
+	xi.l1.changes = append(xi.l1.changes, change)
+	return nil
+}
+
+func newL1Validator(
+	dbTx *database.PChainTx,
+	validationID ids.ID,
+	subnetID ids.ID,
+	nodeIDBytes []byte,
+	weight uint64,
+	balance uint64,
+	blsPublicKey []byte,
+	remainingBalanceOwner *message.PChainOwner,
+	deactivationOwner *message.PChainOwner,
+) (*database.PChainL1Validator, error) {
+	nodeID, err := ids.ToNodeID(nodeIDBytes)
+	if err != nil {
+		return nil, err
+	}
+	remainingBalanceAddresses, err := shared.FormatAddresses(remainingBalanceOwner.Addresses)
+	if err != nil {
+		return nil, err
+	}
+	deactivationAddresses, err := shared.FormatAddresses(deactivationOwner.Addresses)
+	if err != nil {
+		return nil, err
+	}
+	return &database.PChainL1Validator{
+		ValidationID:                   validationID.String(),
+		SubnetID:                       subnetID.String(),
+		NodeID:                         nodeID.String(),
+		TxID:                           *dbTx.TxID,
+		Weight:                         weight,
+		Balance:                        balance,
+		BLSPublicKey:                   hex.EncodeToString(blsPublicKey),
+		RemainingBalanceOwnerThreshold: remainingBalanceOwner.Threshold,
+		RemainingBalanceOwnerAddresses: remainingBalanceAddresses,
+		DeactivationOwnerThreshold:     deactivationOwner.Threshold,
+		DeactivationOwnerAddresses:     deactivationAddresses,
+		BlockHeight:                    dbTx.BlockHeight,
+	}, nil
+}
+
+// Payload of the addressed call in a signed warp message of an L1 transaction
+func parseL1WarpPayload(warpMessage []byte) ([]byte, error) {
+	msg, err := warp.ParseMessage(warpMessage)
+	if err != nil {
+		return nil, err
+	}
+	addressedCall, err := payload.ParseAddressedCall(msg.Payload)
+	if err != nil {
+		return nil, err
+	}
+	return addressedCall.Payload, nil
+}
+
+// L1 transactions are issued after the Etna upgrade, so blocks always have a block time
+func l1TxTime(dbTx *database.PChainTx) (time.Time, error) {
+	if dbTx.BlockTime == nil {
+		return time.Time{}, fmt.Errorf("L1 transaction %s in block %d without block time", *dbTx.TxID, dbTx.BlockHeight)
+	}
+	return *dbTx.BlockTime, nil
+}
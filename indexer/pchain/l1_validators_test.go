@@ -0,0 +1,95 @@
+package pchain
+
+import (
+	"flare-indexer/database"
+	"flare-indexer/indexer/shared"
+	"flare-indexer/utils/chain"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/vms/platformvm/txs"
+	"github.com/ava-labs/avalanchego/vms/platformvm/warp"
+	"github.com/ava-labs/avalanchego/vms/platformvm/warp/message"
+	"github.com/ava-labs/avalanchego/vms/platformvm/warp/payload"
+	"github.com/stretchr/testify/require"
+)
+
+func signedL1Message(t *testing.T, p message.Payload) []byte {
+	addressedCall, err := payload.NewAddressedCall([]byte{1, 2, 3}, p.Bytes())
+	require.NoError(t, err)
+	unsigned, err := warp.NewUnsignedMessage(1, ids.GenerateTestID(), addressedCall.Bytes())
+	require.NoError(t, err)
+	msg, err := warp.NewMessage(unsigned, &warp.BitSetSignature{})
+	require.NoError(t, err)
+	return msg.Bytes()
+}
+
+func newL1TestBatchIndexer() *txBatchIndexer {
+	updater := &pChainInputUpdater{}
+	updater.InitCache()
+	return &txBatchIndexer{inOutIndexer: shared.NewInputOutputIndexer(updater)}
+}
+
+func newL1TestTx(txID string) *database.PChainTx {
+	blockTime := time.Unix(1733000000, 0)
+	return &database.PChainTx{TxID: &txID, BlockHeight: 100, BlockTime: &blockTime}
+}
+
+func TestRegisterL1ValidatorTx(t *testing.T) {
+	chain.AddressHRP = "costwo"
+	subnetID := ids.GenerateTestID()
+	nodeID := ids.GenerateTestNodeID()
+	owner := message.PChainOwner{Threshold: 1, Addresses: []ids.ShortID{ids.GenerateTestShortID()}}
+	msg, err := message.NewRegisterL1Validator(subnetID, nodeID, [48]byte{1}, 1733000100, owner, message.PChainOwner{}, 50)
+	require.NoError(t, err)
+
+	xi := newL1TestBatchIndexer()
+	dbTx := newL1TestTx("tx1")
+	err = xi.updateRegisterL1ValidatorTx(dbTx, &txs.RegisterL1ValidatorTx{
+		Balance: 1000,
+		Message: signedL1Message(t, msg),
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, database.PChainRegisterL1ValidatorTx, dbTx.Type)
+	require.Len(t, xi.l1.validators, 1)
+	v := xi.l1.validators[0]
+	require.Equal(t, msg.ValidationID().String(), v.ValidationID)
+	require.Equal(t, subnetID.String(), v.SubnetID)
+	require.Equal(t, nodeID.String(), v.NodeID)
+	require.Equal(t, uint64(50), v.Weight)
+	require.Equal(t, uint64(1000), v.Balance)
+	require.Equal(t, uint32(1), v.RemainingBalanceOwnerThreshold)
+	require.Contains(t, v.RemainingBalanceOwnerAddresses, "costwo1")
+	require.Empty(t, v.DeactivationOwnerAddresses)
+	require.Equal(t, *dbTx.BlockTime, v.StartTime)
+}
+
+func TestL1ValidatorChangeTxs(t *testing.T) {
+	validationID := ids.GenerateTestID()
+	msg, err := message.NewL1ValidatorWeight(validationID, 3, 0)
+	require.NoError(t, err)
+
+	xi := newL1TestBatchIndexer()
+	require.NoError(t, xi.updateSetL1ValidatorWeightTx(newL1TestTx("tx1"), &txs.SetL1ValidatorWeightTx{
+		Message: signedL1Message(t, msg),
+	}))
+	require.NoError(t, xi.updateIncreaseL1ValidatorBalanceTx(newL1TestTx("tx2"), &txs.IncreaseL1ValidatorBalanceTx{
+		ValidationID: validationID,
+		Balance:      10,
+	}))
+	require.NoError(t, xi.updateDisableL1ValidatorTx(newL1TestTx("tx3"), &txs.DisableL1ValidatorTx{
+		ValidationID: validationID,
+	}))
+
+	require.Len(t, xi.l1.changes, 3)
+	require.Equal(t, database.PChainL1ValidatorWeightChange, xi.l1.changes[0].Type)
+	require.Equal(t, uint64(3), xi.l1.changes[0].Nonce)
+	require.Equal(t, database.PChainL1ValidatorBalanceIncrease, xi.l1.changes[1].Type)
+	require.Equal(t, uint64(10), xi.l1.changes[1].Balance)
+	require.Equal(t, database.PChainL1ValidatorDisable, xi.l1.changes[2].Type)
+	for _, c := range xi.l1.changes {
+		require.Equal(t, validationID.String(), c.ValidationID)
+	}
+}
@@ -1,6 +1,3 @@
-//go:build integration
-// +build integration
-
 package pchain
 
 import (
@@ -50,14 +47,7 @@ func pchainIndexerTestConfig(batchSize int, startIndex uint64) *config.Config {
 				Timeout: 60 * time.Second,
 			},
 		},
-		DB: globalConfig.DBConfig{
-			Username:   database.MysqlTestUser,
-			Password:   database.MysqlTestPassword,
-			Host:       database.MysqlTestHost,
-			Port:       database.MysqlTestPort,
-			Database:   "flare_indexer_indexer",
-			LogQueries: false,
-		},
+		DB: database.TestDBConfig("flare_indexer_indexer"),
 	}
 	return cfg
 }
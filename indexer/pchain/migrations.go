@@ -3,20 +3,34 @@ package pchain
 import (
 	"flare-indexer/database"
 	"flare-indexer/indexer/migrations"
+	"flare-indexer/utils"
+	"flare-indexer/utils/chain"
 	"time"
 
+	"github.com/ava-labs/avalanchego/vms/platformvm/txs"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// Number of blocks read by a single query of migrations processing indexed blocks
+const migrationBlockBatchSize = 100
+
 func init() {
 	migrations.Container.Add("2023-02-10-00-00", "Create initial state for P-Chain transactions", createPChainTxState)
 	migrations.Container.Add("2024-11-07-00-00", "Alter type column size in p_chain_txes table", alterPChainTxType)
 	migrations.Container.Add("2025-09-30-00-00", "Delete all P-chain transactions", deleteTransactions)
-	migrations.Container.Add("2026-02-17-00-00", "Add composite index on p_chain_txes for staking queries", addPChainTxesCompositeIndex)
+	migrations.Container.AddWithDown("2026-02-17-00-00", "Add composite index on p_chain_txes for staking queries", addPChainTxesCompositeIndex, dropPChainTxesCompositeIndex)
+	migrations.Container.Add("2026-10-18-00-00", "Move P-chain block data from p_chain_txes to p_chain_blocks", movePChainBlocks)
+	migrations.Container.AddWithDown("2026-10-18-01-00", "Create stake rewards of indexed reward transactions", createStakeRewards, deleteAll(&database.PChainStakeReward{}))
+	migrations.Container.Add("2026-10-18-02-00", "Create UTXO state of indexed outputs and inputs", createPChainUTXOs)
+	migrations.Container.AddWithDown("2026-10-18-04-00", "Set asset ID, lock times and owners of indexed P-chain outputs", updatePChainOutputOwners, migrations.KeepValues)
+	migrations.Container.AddWithDown("2026-10-18-05-00", "Create rewards owners of indexed staking transactions", createRewardsOwners, deleteAll(&database.PChainRewardsOwner{}))
+	migrations.Container.AddWithDown("2026-10-18-06-00", "Set type of indexed P-chain inputs", setPChainInputType, migrations.KeepValues)
+	migrations.Container.AddWithDown("2026-10-18-07-00", "Create exported outputs of indexed export transactions", createPChainExportedOutputs, deletePChainExportedOutputs)
 }
 
 func createPChainTxState(db *gorm.DB) error {
-	return database.CreateState(db, &database.State{
+	return database.CreateStateIfMissing(db, &database.State{
 		Name:           StateName,
 		NextDBIndex:    0,
 		LastChainIndex: 0,
@@ -24,14 +38,22 @@ func createPChainTxState(db *gorm.DB) error {
 	})
 }
 
+// Column type is altered to the type of the entity (varchar(40)) in the syntax of the dialect
 func alterPChainTxType(db *gorm.DB) error {
-	return db.Exec("ALTER TABLE p_chain_txes CHANGE COLUMN type type VARCHAR(40)").Error
+	return db.Migrator().AlterColumn(&database.PChainTx{}, "Type")
 }
 
 func addPChainTxesCompositeIndex(db *gorm.DB) error {
+	if db.Migrator().HasIndex(&database.PChainTx{}, "idx_type_end_start_id") {
+		return nil
+	}
 	return db.Exec("CREATE INDEX idx_type_end_start_id ON p_chain_txes (type, end_time, start_time, id)").Error
 }
 
+func dropPChainTxesCompositeIndex(db *gorm.DB) error {
+	return db.Migrator().DropIndex(&database.PChainTx{}, "idx_type_end_start_id")
+}
+
 // Delete all P-chain transactions and reset the state to start indexing from the beginning
 func deleteTransactions(db *gorm.DB) error {
 	return db.Transaction(func(tx *gorm.DB) error {
@@ -50,3 +72,276 @@ func deleteTransactions(db *gorm.DB) error {
 		return nil
 	})
 }
+
+// Block data of the first transaction row of each block, as stored before the p_chain_blocks table
+type legacyPChainBlockRow struct {
+	ID          uint64
+	BlockHeight uint64
+	BlockID     string
+	BlockType   database.PChainBlockType
+	BlockTime   *time.Time
+	Timestamp   time.Time
+	Bytes       []byte
+}
+
+// Create a row in p_chain_blocks for each block height in p_chain_txes, delete the placeholder
+// rows of commit and abort blocks and drop the bytes column of p_chain_txes
+func movePChainBlocks(db *gorm.DB) error {
+	if !db.Migrator().HasColumn(&database.PChainTx{}, "bytes") {
+		return nil
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var rows []legacyPChainBlockRow
+		firstRows := tx.Table("p_chain_txes").Select("MIN(id)").Group("block_height")
+		err := tx.Table("p_chain_txes").
+			Where("id IN (?)", firstRows).
+			FindInBatches(&rows, 1000, func(batch *gorm.DB, _ int) error {
+				blocks := make([]*database.PChainBlock, len(rows))
+				for i, r := range rows {
+					blk, err := chain.ParsePChainBlock(r.Bytes)
+					if err != nil {
+						return err
+					}
+					blocks[i] = &database.PChainBlock{
+						Height:    r.BlockHeight,
+						BlockID:   r.BlockID,
+						ParentID:  blk.Parent().String(),
+						Type:      r.BlockType,
+						BlockTime: r.BlockTime,
+						Timestamp: r.Timestamp,
+						Bytes:     r.Bytes,
+					}
+				}
+				return tx.Create(blocks).Error
+			}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Exec("DELETE FROM p_chain_txes WHERE tx_id IS NULL").Error
+	})
+	if err != nil {
+		return err
+	}
+	return db.Migrator().DropColumn(&database.PChainTx{}, "bytes")
+}
+
+// Create p_chain_stake_rewards rows from indexed RewardValidatorTxs and their reward outputs.
+// Rewards of staking transactions which are not indexed (e.g., genesis validators) and rewards
+// created by an earlier run are skipped.
+func createStakeRewards(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var rewardTxs []*database.PChainTx
+		return tx.Where("type = ?", database.PChainRewardValidatorTx).
+			FindInBatches(&rewardTxs, 1000, func(batch *gorm.DB, _ int) error {
+				stakeTxIDs := make([]string, len(rewardTxs))
+				for i, rewardTx := range rewardTxs {
+					stakeTxIDs[i] = rewardTx.RewardTxID
+				}
+				var stakeTxs []*database.PChainTx
+				err := tx.Where("tx_id IN ?", stakeTxIDs).Find(&stakeTxs).Error
+				if err != nil {
+					return err
+				}
+				var outs []*database.PChainTxOutput
+				err = tx.Where("tx_id IN ?", stakeTxIDs).Where("type = ?", database.PChainRewardOutput).Find(&outs).Error
+				if err != nil {
+					return err
+				}
+
+				stakeTxMap := make(map[string]*database.PChainTx, len(stakeTxs))
+				for _, stakeTx := range stakeTxs {
+					stakeTxMap[*stakeTx.TxID] = stakeTx
+				}
+				outMap := make(map[string][]*database.PChainTxOutput)
+				for _, out := range outs {
+					outMap[out.TxID] = append(outMap[out.TxID], out)
+				}
+				rewards := make([]*database.PChainStakeReward, 0, len(rewardTxs))
+				for _, rewardTx := range rewardTxs {
+					if stakeTx, ok := stakeTxMap[rewardTx.RewardTxID]; ok {
+						rewards = append(rewards, newStakeReward(rewardTx, stakeTx, outMap[rewardTx.RewardTxID]))
+					}
+				}
+				return database.CreatePChainStakeRewards(skipExisting(tx), rewards)
+			}).Error
+	})
+}
+
+// Create p_chain_utxos rows from indexed outputs (skipping existing rows) and mark outputs spent
+// by indexed inputs. Each batch of outputs and inputs is committed in its own transaction, the
+// migration can be repeated if it fails.
+func createPChainUTXOs(db *gorm.DB) error {
+	var rewardTxs []*database.PChainTx
+	err := db.Where("type = ?", database.PChainRewardValidatorTx).
+		Select("tx_id", "type", "reward_tx_id", "block_height").
+		Find(&rewardTxs).Error
+	if err != nil {
+		return err
+	}
+
+	var outs []*database.PChainTxOutput
+	err = db.FindInBatches(&outs, 1000, func(batch *gorm.DB, _ int) error {
+		return db.Transaction(func(tx *gorm.DB) error {
+			heights, err := fetchTxHeights(tx, rewardTxs, utils.Map(outs, func(out *database.PChainTxOutput) string { return out.TxID }))
+			if err != nil {
+				return err
+			}
+			return database.CreatePChainUTXOs(skipExisting(tx), heights.utxos(outs))
+		})
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	var ins []*database.PChainTxInput
+	return db.FindInBatches(&ins, 1000, func(batch *gorm.DB, _ int) error {
+		return db.Transaction(func(tx *gorm.DB) error {
+			heights, err := fetchTxHeights(tx, rewardTxs, utils.Map(ins, func(in *database.PChainTxInput) string { return in.TxID }))
+			if err != nil {
+				return err
+			}
+			return database.SpendPChainUTXOs(tx, heights.spends(ins))
+		})
+	}).Error
+}
+
+// Heights of transactions with ids txIDs and of all reward transactions
+func fetchTxHeights(db *gorm.DB, rewardTxs []*database.PChainTx, txIDs []string) (*txHeights, error) {
+	var txs []*database.PChainTx
+	err := db.Where("tx_id IN ?", txIDs).Select("tx_id", "type", "reward_tx_id", "block_height").Find(&txs).Error
+	if err != nil {
+		return nil, err
+	}
+	return newTxHeights(append(txs, rewardTxs...)), nil
+}
+
+// Set asset ID, lock times, threshold and owner group of indexed outputs and UTXO state rows
+// from transactions of indexed blocks. Reward outputs are not stored in blocks, so those
+// indexed before this migration keep empty values. Each batch of blocks is updated in its own
+// transaction, the migration can be repeated if it fails.
+func updatePChainOutputOwners(db *gorm.DB) error {
+	return forEachIndexedTxBatch(db, func(tx *gorm.DB, blkTxs []*txs.Tx) error {
+		var dbOuts []*database.TxOutput
+		for _, blkTx := range blkTxs {
+			outs, err := getTxOutputs(blkTx.ID().String(), blkTx.Unsigned)
+			if err != nil {
+				return err
+			}
+			for _, out := range outs {
+				dbOuts = append(dbOuts, &out.(*database.PChainTxOutput).TxOutput)
+			}
+		}
+		err := database.UpdateOutputOwners(tx, &database.PChainTxOutput{}, dbOuts)
+		if err != nil {
+			return err
+		}
+		return database.UpdateOutputOwners(tx, &database.PChainUTXO{}, dbOuts)
+	})
+}
+
+// Create p_chain_rewards_owners rows of staking transactions of indexed blocks, existing rows
+// are skipped
+func createRewardsOwners(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		return forEachIndexedTx(tx, func(blkTx *txs.Tx) error {
+			owners, err := stakeRewardsOwners(blkTx.ID().String(), blkTx.Unsigned)
+			if err != nil {
+				return err
+			}
+			return database.CreatePChainRewardsOwners(skipExisting(tx), owners)
+		})
+	})
+}
+
+// Inputs indexed before imported inputs were recorded are inputs of base transactions
+func setPChainInputType(db *gorm.DB) error {
+	return db.Model(&database.PChainTxInput{}).
+		Where("type IS NULL OR type = ''").
+		Update("type", database.PChainDefaultInput).Error
+}
+
+// Create outputs exported by ExportTxs of indexed blocks, replacing exported outputs created by
+// an earlier run
+func createPChainExportedOutputs(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := deletePChainExportedOutputs(tx); err != nil {
+			return err
+		}
+		return forEachIndexedTx(tx, func(blkTx *txs.Tx) error {
+			exportTx, ok := blkTx.Unsigned.(*txs.ExportTx)
+			if !ok {
+				return nil
+			}
+			outs, err := getExportTxOutputs(blkTx.ID().String(), exportTx)
+			if err != nil {
+				return err
+			}
+			var exportedOuts []*database.PChainTxOutput
+			for _, out := range outs {
+				if dbOut := out.(*database.PChainTxOutput); dbOut.Type == database.PChainExportedOutput {
+					exportedOuts = append(exportedOuts, dbOut)
+				}
+			}
+			return database.CreatePChainEntities(tx, nil, nil, nil, exportedOuts)
+		})
+	})
+}
+
+func deletePChainExportedOutputs(db *gorm.DB) error {
+	return db.Where("type = ?", database.PChainExportedOutput).Delete(&database.PChainTxOutput{}).Error
+}
+
+// Down function of a migration creating all rows of model from indexed blocks, the rows are
+// created again when the migration is executed
+func deleteAll(model interface{}) func(*gorm.DB) error {
+	return func(db *gorm.DB) error {
+		return db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(model).Error
+	}
+}
+
+// Inserts of rows violating a unique constraint are skipped, so that a migration creating rows can
+// be applied again
+func skipExisting(db *gorm.DB) *gorm.DB {
+	return db.Clauses(clause.OnConflict{DoNothing: true})
+}
+
+// Call f with transactions of each batch of indexed blocks, in a separate transaction for
+// each batch
+func forEachIndexedTxBatch(db *gorm.DB, f func(db *gorm.DB, blkTxs []*txs.Tx) error) error {
+	var blocks []*database.PChainBlock
+	return db.Select("id", "bytes").
+		FindInBatches(&blocks, migrationBlockBatchSize, func(batch *gorm.DB, _ int) error {
+			var blkTxs []*txs.Tx
+			for _, b := range blocks {
+				blk, err := chain.ParsePChainBlock(b.Bytes)
+				if err != nil {
+					return err
+				}
+				blkTxs = append(blkTxs, blk.Txs()...)
+			}
+			return db.Transaction(func(tx *gorm.DB) error {
+				return f(tx, blkTxs)
+			})
+		}).Error
+}
+
+// Call f for each transaction of indexed blocks, blocks are read in batches
+func forEachIndexedTx(db *gorm.DB, f func(tx *txs.Tx) error) error {
+	var blocks []*database.PChainBlock
+	return db.Select("id", "bytes").
+		FindInBatches(&blocks, migrationBlockBatchSize, func(batch *gorm.DB, _ int) error {
+			for _, b := range blocks {
+				blk, err := chain.ParsePChainBlock(b.Bytes)
+				if err != nil {
+					return err
+				}
+				for _, blkTx := range blk.Txs() {
+					err = f(blkTx)
+					if err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		}).Error
+}
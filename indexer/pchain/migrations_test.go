@@ -0,0 +1,90 @@
+package pchain
+
+import (
+	"context"
+	"flare-indexer/database"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// Migrations creating rows from indexed blocks leave the database unchanged when applied again
+func TestMigrationsIdempotent(t *testing.T) {
+	idxr := createPChainTestBlockIndexer(t, 200, 0)
+	require.NoError(t, idxr.IndexBatch(context.Background()))
+
+	models := []interface{}{
+		&database.State{},
+		&database.PChainTxOutput{},
+		&database.PChainUTXO{},
+		&database.PChainStakeReward{},
+		&database.PChainRewardsOwner{},
+	}
+	counts := func(db *gorm.DB) []int64 {
+		result := make([]int64, len(models))
+		for i, m := range models {
+			require.NoError(t, db.Model(m).Count(&result[i]).Error)
+		}
+		return result
+	}
+	before := counts(idxr.DB)
+	require.NotZero(t, before[1])
+
+	for _, migrate := range []func(*gorm.DB) error{
+		createPChainTxState,
+		alterPChainTxType,
+		addPChainTxesCompositeIndex,
+		movePChainBlocks,
+		createStakeRewards,
+		createPChainUTXOs,
+		updatePChainOutputOwners,
+		createRewardsOwners,
+		setPChainInputType,
+		createPChainExportedOutputs,
+	} {
+		require.NoError(t, migrate(idxr.DB))
+	}
+	require.Equal(t, before, counts(idxr.DB))
+}
+
+// Rows deleted by down functions are created again by the migrations
+func TestMigrationsDown(t *testing.T) {
+	idxr := createPChainTestBlockIndexer(t, 200, 0)
+	require.NoError(t, idxr.IndexBatch(context.Background()))
+
+	var before, after int64
+	require.NoError(t, idxr.DB.Model(&database.PChainRewardsOwner{}).Count(&before).Error)
+	require.NoError(t, deleteAll(&database.PChainRewardsOwner{})(idxr.DB))
+	require.NoError(t, idxr.DB.Model(&database.PChainRewardsOwner{}).Count(&after).Error)
+	require.Zero(t, after)
+	require.NoError(t, createRewardsOwners(idxr.DB))
+	require.NoError(t, idxr.DB.Model(&database.PChainRewardsOwner{}).Count(&after).Error)
+	require.Equal(t, before, after)
+
+	require.NoError(t, dropPChainTxesCompositeIndex(idxr.DB))
+	require.False(t, idxr.DB.Migrator().HasIndex(&database.PChainTx{}, "idx_type_end_start_id"))
+	require.NoError(t, addPChainTxesCompositeIndex(idxr.DB))
+	require.True(t, idxr.DB.Migrator().HasIndex(&database.PChainTx{}, "idx_type_end_start_id"))
+}
+
+// Owners of outputs are set again from indexed blocks (reward outputs are not stored in blocks)
+func TestUpdatePChainOutputOwners(t *testing.T) {
+	idxr := createPChainTestBlockIndexer(t, 200, 0)
+	require.NoError(t, idxr.IndexBatch(context.Background()))
+
+	for _, model := range []interface{}{&database.PChainTxOutput{}, &database.PChainUTXO{}} {
+		var before []database.TxOutput
+		require.NoError(t, idxr.DB.Model(model).Order("id").Find(&before).Error)
+		require.NotEmpty(t, before)
+		require.NotEmpty(t, before[0].OwnerGroup)
+
+		require.NoError(t, idxr.DB.Model(model).Where("type <> ?", database.PChainRewardOutput).
+			Updates(map[string]interface{}{"asset_id": "", "threshold": 0, "owner_group": ""}).Error)
+		require.NoError(t, updatePChainOutputOwners(idxr.DB))
+
+		var after []database.TxOutput
+		require.NoError(t, idxr.DB.Model(model).Order("id").Find(&after).Error)
+		require.Equal(t, before, after)
+	}
+}
@@ -0,0 +1,315 @@
+package pchain
+
+import (
+	"context"
+	"flare-indexer/database"
+	indexerctx "flare-indexer/indexer/context"
+	"flare-indexer/indexer/shared"
+	"flare-indexer/utils"
+	"flare-indexer/utils/chain"
+	"strings"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/indexer"
+	"github.com/ava-labs/avalanchego/vms/platformvm/txs"
+	"github.com/pkg/errors"
+	"golang.org/x/exp/slices"
+	"gorm.io/gorm"
+)
+
+// Table rebuilt from the entities of re-decoded blocks. Rows of the batch are deleted and
+// created again (transactions are updated in place to keep their IDs).
+type redecodeTable struct {
+	name    string
+	persist func(r *Redecoder, db *gorm.DB, b *redecodeBatch) error
+}
+
+// Transactions of a re-decoded batch
+type redecodeBatch struct {
+	txIDs       []string // IDs of all transactions
+	rewardTxIDs []string // IDs of RewardValidatorTxs
+	stakeTxIDs  []string // IDs of staking transactions rewarded by RewardValidatorTxs
+}
+
+// Tables which can be rebuilt, in the order they are persisted
+var redecodeTables = []redecodeTable{
+	{"p_chain_txes", func(r *Redecoder, db *gorm.DB, b *redecodeBatch) error {
+		return database.UpsertPChainTxs(db, r.xi.newTxs, r.columns)
+	}},
+	{"p_chain_tx_inputs", func(r *Redecoder, db *gorm.DB, b *redecodeBatch) error {
+		ins := newDbInputs(r.xi.inOutIndexer.GetIns(), database.PChainDefaultInput)
+		inputTypes := []database.PChainInputType{database.PChainDefaultInput}
+		if r.xi.importIndexer != nil {
+			ins = append(ins, newDbInputs(r.xi.importIndexer.GetIns(), database.PChainImportedInput)...)
+			inputTypes = append(inputTypes, database.PChainImportedInput)
+		}
+		if err := database.DeletePChainTxInputs(db, b.txIDs, inputTypes); err != nil {
+			return err
+		}
+		return database.CreatePChainEntities(db, nil, nil, ins, nil)
+	}},
+	{"p_chain_tx_outputs", func(r *Redecoder, db *gorm.DB, b *redecodeBatch) error {
+		outs, err := utils.CastArray[*database.PChainTxOutput](r.xi.inOutIndexer.GetNewOuts())
+		if err != nil {
+			return err
+		}
+		if err := database.DeletePChainTxOutputs(db, b.txIDs, b.stakeTxIDs); err != nil {
+			return err
+		}
+		return database.CreatePChainEntities(db, nil, nil, nil, outs)
+	}},
+	{"p_chain_stake_rewards", func(r *Redecoder, db *gorm.DB, b *redecodeBatch) error {
+		if err := database.DeletePChainRows(db, &database.PChainStakeReward{}, "reward_tx_id", b.rewardTxIDs); err != nil {
+			return err
+		}
+		return database.CreatePChainStakeRewards(db, r.xi.rewards)
+	}},
+	{"p_chain_rewards_owners", func(r *Redecoder, db *gorm.DB, b *redecodeBatch) error {
+		if err := database.DeletePChainRows(db, &database.PChainRewardsOwner{}, "tx_id", b.txIDs); err != nil {
+			return err
+		}
+		return database.CreatePChainRewardsOwners(db, r.xi.rewardsOwners)
+	}},
+	{"p_chain_subnets", func(r *Redecoder, db *gorm.DB, b *redecodeBatch) error {
+		if err := database.DeletePChainRows(db, &database.PChainSubnet{}, "subnet_id", b.txIDs); err != nil {
+			return err
+		}
+		return database.CreatePChainSubnetEntities(db, r.xi.subnets.subnets, nil)
+	}},
+	{"p_chain_chains", func(r *Redecoder, db *gorm.DB, b *redecodeBatch) error {
+		if err := database.DeletePChainRows(db, &database.PChainChain{}, "chain_id", b.txIDs); err != nil {
+			return err
+		}
+		return database.CreatePChainSubnetEntities(db, nil, r.xi.subnets.chains)
+	}},
+	{"p_chain_l1_conversions", func(r *Redecoder, db *gorm.DB, b *redecodeBatch) error {
+		if err := database.DeletePChainRows(db, &database.PChainL1Conversion{}, "tx_id", b.txIDs); err != nil {
+			return err
+		}
+		return database.CreatePChainL1Entities(db, r.xi.l1.conversions, nil, nil)
+	}},
+	{"p_chain_l1_validators", func(r *Redecoder, db *gorm.DB, b *redecodeBatch) error {
+		if err := database.DeletePChainRows(db, &database.PChainL1Validator{}, "tx_id", b.txIDs); err != nil {
+			return err
+		}
+		return database.CreatePChainL1Entities(db, nil, r.xi.l1.validators, nil)
+	}},
+	{"p_chain_l1_validator_changes", func(r *Redecoder, db *gorm.DB, b *redecodeBatch) error {
+		if err := database.DeletePChainRows(db, &database.PChainL1ValidatorChange{}, "tx_id", b.txIDs); err != nil {
+			return err
+		}
+		return database.CreatePChainL1Entities(db, nil, nil, r.xi.l1.changes)
+	}},
+}
+
+// Names of tables which can be rebuilt by the redecoder
+func RedecodeTableNames() []string {
+	return utils.Map(redecodeTables, func(t redecodeTable) string { return t.name })
+}
+
+// Rebuilds tables from blocks stored in p_chain_blocks by replaying the batch indexer over
+// them. Data which is not in blocks is read from the database and fetched from the node only
+// if it is not stored: outputs spent by inputs, rewarded staking transactions and reward
+// outputs (unless nodeRewards is set). The UTXO state (p_chain_utxos) is not rebuilt.
+type Redecoder struct {
+	db      *gorm.DB
+	xi      *txBatchIndexer
+	tables  []redecodeTable
+	columns []string // Updated columns of p_chain_txes
+
+	nodeRewards bool
+	fetchReward func(ctx context.Context, txID string) ([]shared.Output, error)
+}
+
+// Create a redecoder of tables (names from RedecodeTableNames). Columns select the updated
+// columns of p_chain_txes, all columns are updated if empty. Reward outputs are always fetched
+// from the node if nodeRewards is set.
+func NewRedecoder(ctx indexerctx.IndexerContext, tables []string, columns []string, nodeRewards bool) (*Redecoder, error) {
+	selected, err := selectRedecodeTables(tables)
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) > 0 && !slices.Contains(tables, "p_chain_txes") {
+		return nil, errors.New("columns can only be selected when p_chain_txes is rebuilt")
+	}
+	allColumns, err := database.PChainTxColumns(ctx.DB())
+	if err != nil {
+		return nil, err
+	}
+	columns, err = selectTxColumns(allColumns, columns)
+	if err != nil {
+		return nil, err
+	}
+
+	rpcClient := newJsonRpcClient(&ctx.Config().Chain)
+	xi := NewPChainBatchIndexer(ctx, nil, rpcClient, nil)
+	return &Redecoder{
+		db:          ctx.DB(),
+		xi:          xi,
+		tables:      selected,
+		columns:     columns,
+		nodeRewards: nodeRewards,
+		fetchReward: xi.rewardOutputs,
+	}, nil
+}
+
+// Tables with the given names in the persisting order
+func selectRedecodeTables(names []string) ([]redecodeTable, error) {
+	if len(names) == 0 {
+		return nil, errors.New("no tables to rebuild")
+	}
+	for _, name := range names {
+		if !slices.ContainsFunc(redecodeTables, func(t redecodeTable) bool { return t.name == name }) {
+			return nil, errors.Errorf("table %s cannot be rebuilt, tables are %s", name, strings.Join(RedecodeTableNames(), ", "))
+		}
+	}
+	var tables []redecodeTable
+	for _, t := range redecodeTables {
+		if slices.Contains(names, t.name) {
+			tables = append(tables, t)
+		}
+	}
+	return tables, nil
+}
+
+// Updated columns of p_chain_txes, all columns except the row ID and the transaction ID if
+// columns is empty
+func selectTxColumns(allColumns []string, columns []string) ([]string, error) {
+	var decoded []string
+	for _, c := range allColumns {
+		if c != "id" && c != "tx_id" {
+			decoded = append(decoded, c)
+		}
+	}
+	if len(columns) == 0 {
+		return decoded, nil
+	}
+	for _, c := range columns {
+		if !slices.Contains(decoded, c) {
+			return nil, errors.Errorf("column %s of p_chain_txes cannot be updated", c)
+		}
+	}
+	return columns, nil
+}
+
+// Rebuild tables from stored blocks with heights in [from, to], at most limit blocks are
+// re-decoded. Returns the height following the last re-decoded block, to + 1 if there are
+// no more blocks in the range. Rows of a batch are replaced in a single database transaction.
+func (r *Redecoder) RedecodeBatch(ctx context.Context, from uint64, to uint64, limit int) (uint64, error) {
+	blocks, err := database.FetchPChainBlocks(r.db, from, to, limit)
+	if err != nil {
+		return 0, err
+	}
+	if len(blocks) == 0 {
+		return to + 1, nil
+	}
+
+	rewards, err := r.storedRewards(blocks)
+	if err != nil {
+		return 0, err
+	}
+	r.xi.rewardOutputs = rewards.outputs
+	r.xi.Reset(len(blocks))
+	for _, b := range blocks {
+		blockID, err := ids.FromString(b.BlockID)
+		if err != nil {
+			return 0, err
+		}
+		container := indexer.Container{ID: blockID, Bytes: b.Bytes, Timestamp: b.Timestamp.UnixNano()}
+		if err := r.xi.AddContainer(ctx, b.Height, container); err != nil {
+			return 0, err
+		}
+	}
+	if err := r.xi.ProcessBatch(ctx); err != nil {
+		return 0, err
+	}
+
+	batch := newRedecodeBatch(r.xi.newTxs)
+	err = r.db.Transaction(func(db *gorm.DB) error {
+		for _, t := range r.tables {
+			if err := t.persist(r, db, batch); err != nil {
+				return errors.Wrapf(err, "failed to rebuild %s", t.name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return blocks[len(blocks)-1].Height + 1, nil
+}
+
+func newRedecodeBatch(newTxs []*database.PChainTx) *redecodeBatch {
+	b := &redecodeBatch{}
+	for _, tx := range newTxs {
+		b.txIDs = append(b.txIDs, *tx.TxID)
+		if tx.Type == database.PChainRewardValidatorTx {
+			b.rewardTxIDs = append(b.rewardTxIDs, *tx.TxID)
+			b.stakeTxIDs = append(b.stakeTxIDs, tx.RewardTxID)
+		}
+	}
+	return b
+}
+
+// Reward outputs of staking transactions rewarded in blocks, read from the database
+func (r *Redecoder) storedRewards(blocks []*database.PChainBlock) (*storedRewardOutputs, error) {
+	rewards := &storedRewardOutputs{
+		outs:     make(map[string][]shared.Output),
+		rewarded: make(map[string]bool),
+		fetch:    r.fetchReward,
+	}
+	if r.nodeRewards {
+		return rewards, nil
+	}
+	var stakeTxIDs []string
+	for _, b := range blocks {
+		blk, err := chain.ParsePChainBlock(b.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		for _, tx := range blk.Txs() {
+			if rewardTx, ok := tx.Unsigned.(*txs.RewardValidatorTx); ok {
+				stakeTxIDs = append(stakeTxIDs, rewardTx.TxID.String())
+			}
+		}
+	}
+	if len(stakeTxIDs) == 0 {
+		return rewards, nil
+	}
+
+	outs, err := database.FetchPChainRewardOutputs(r.db, stakeTxIDs)
+	if err != nil {
+		return nil, err
+	}
+	rewarded, err := database.FetchPChainRewardedStakeTxIDs(r.db, stakeTxIDs)
+	if err != nil {
+		return nil, err
+	}
+	rewards.add(outs, rewarded)
+	return rewards, nil
+}
+
+// Reward outputs stored in the database by staking transaction ID. Outputs of staking
+// transactions which are not stored are fetched.
+type storedRewardOutputs struct {
+	outs     map[string][]shared.Output
+	rewarded map[string]bool // Staking transactions with a stored reward, possibly without outputs
+	fetch    func(ctx context.Context, txID string) ([]shared.Output, error)
+}
+
+func (s *storedRewardOutputs) add(outs []*database.PChainTxOutput, rewarded []string) {
+	for _, out := range outs {
+		out.ID = 0 // outputs are created again if p_chain_tx_outputs is rebuilt
+		s.outs[out.TxID] = append(s.outs[out.TxID], out)
+	}
+	for _, txID := range rewarded {
+		s.rewarded[txID] = true
+	}
+}
+
+func (s *storedRewardOutputs) outputs(ctx context.Context, txID string) ([]shared.Output, error) {
+	if outs, ok := s.outs[txID]; ok || s.rewarded[txID] {
+		return outs, nil
+	}
+	return s.fetch(ctx, txID)
+}
@@ -0,0 +1,82 @@
+package pchain
+
+import (
+	"context"
+	"flare-indexer/database"
+	"flare-indexer/indexer/shared"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectRedecodeTables(t *testing.T) {
+	tables, err := selectRedecodeTables([]string{"p_chain_tx_outputs", "p_chain_txes"})
+	require.NoError(t, err)
+	require.Len(t, tables, 2)
+	// Tables are persisted in the fixed order
+	require.Equal(t, "p_chain_txes", tables[0].name)
+	require.Equal(t, "p_chain_tx_outputs", tables[1].name)
+
+	_, err = selectRedecodeTables([]string{"p_chain_utxos"})
+	require.Error(t, err)
+	_, err = selectRedecodeTables(nil)
+	require.Error(t, err)
+}
+
+func TestSelectTxColumns(t *testing.T) {
+	all := []string{"id", "type", "tx_id", "node_id"}
+	columns, err := selectTxColumns(all, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"type", "node_id"}, columns)
+
+	columns, err = selectTxColumns(all, []string{"node_id"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"node_id"}, columns)
+
+	_, err = selectTxColumns(all, []string{"tx_id"})
+	require.Error(t, err)
+}
+
+func TestStoredRewardOutputs(t *testing.T) {
+	var fetched []string
+	rewards := &storedRewardOutputs{
+		outs:     make(map[string][]shared.Output),
+		rewarded: make(map[string]bool),
+		fetch: func(ctx context.Context, txID string) ([]shared.Output, error) {
+			fetched = append(fetched, txID)
+			return nil, nil
+		},
+	}
+	out := &database.PChainTxOutput{
+		TxOutput: database.TxOutput{BaseEntity: database.BaseEntity{ID: 5}, TxID: "stored", Amount: 10},
+		Type:     database.PChainRewardOutput,
+	}
+	rewards.add([]*database.PChainTxOutput{out}, []string{"stored", "notRewarded"})
+
+	outs, err := rewards.outputs(context.Background(), "stored")
+	require.NoError(t, err)
+	require.Equal(t, []shared.Output{out}, outs)
+	require.Zero(t, out.ID)
+
+	// Stored reward without outputs
+	outs, err = rewards.outputs(context.Background(), "notRewarded")
+	require.NoError(t, err)
+	require.Empty(t, outs)
+	require.Empty(t, fetched)
+
+	_, err = rewards.outputs(context.Background(), "unknown")
+	require.NoError(t, err)
+	require.Equal(t, []string{"unknown"}, fetched)
+}
+
+func TestRedecodeBatch(t *testing.T) {
+	stakeID, rewardID, baseID := "stake", "reward", "base"
+	b := newRedecodeBatch([]*database.PChainTx{
+		{TxID: &stakeID, Type: database.PChainAddDelegatorTx},
+		{TxID: &rewardID, Type: database.PChainRewardValidatorTx, RewardTxID: stakeID},
+		{TxID: &baseID, Type: database.PChainBaseTx},
+	})
+	require.Equal(t, []string{stakeID, rewardID, baseID}, b.txIDs)
+	require.Equal(t, []string{rewardID}, b.rewardTxIDs)
+	require.Equal(t, []string{stakeID}, b.stakeTxIDs)
+}
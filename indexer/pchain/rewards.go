@@ -0,0 +1,119 @@
+package pchain
+
+import (
+	"context"
+	"flare-indexer/database"
+	"flare-indexer/indexer/shared"
+	"fmt"
+	"sort"
+
+	"github.com/ava-labs/avalanchego/vms/platformvm/txs"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// Add the reward record of the staking transaction rewarded by dbTx (RewardValidatorTx)
+func (xi *txBatchIndexer) addStakeReward(ctx context.Context, dbTx *database.PChainTx, outs []*database.PChainTxOutput) error {
+	stakeTx, err := xi.fetchStakeTx(ctx, dbTx.RewardTxID)
+	if err != nil {
+		return err
+	}
+	xi.rewards = append(xi.rewards, newStakeReward(dbTx, stakeTx, outs))
+	return nil
+}
+
+// Staking transaction with id txID, looked up in the current batch, the database and on
+// the chain (in this order)
+func (xi *txBatchIndexer) fetchStakeTx(ctx context.Context, txID string) (*database.PChainTx, error) {
+	for _, tx := range xi.newTxs {
+		if *tx.TxID == txID {
+			return tx, nil
+		}
+	}
+	dbTx, err := database.FetchPChainTx(xi.db, txID)
+	if err == nil {
+		return dbTx, nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	tx, err := CallPChainGetTxApi(ctx, xi.rpcClient, txID)
+	if err != nil {
+		return nil, err
+	}
+	if tx == nil {
+		return nil, fmt.Errorf("rewarded staking transaction %s not found", txID)
+	}
+	return stakeTxFromChain(txID, tx)
+}
+
+// Staking transaction data needed for rewards (type, node ID, end time and rewards owners)
+func stakeTxFromChain(txID string, tx *txs.Tx) (*database.PChainTx, error) {
+	dbTx := &database.PChainTx{TxID: &txID}
+	switch tx.Unsigned.(type) {
+	case *txs.AddValidatorTx:
+		dbTx.Type = database.PChainAddValidatorTx
+	case *txs.AddPermissionlessValidatorTx:
+		dbTx.Type = database.PChainAddPermissionlessValidatorTx
+	case *txs.AddDelegatorTx:
+		dbTx.Type = database.PChainAddDelegatorTx
+	case *txs.AddPermissionlessDelegatorTx:
+		dbTx.Type = database.PChainAddPermissionlessDelegatorTx
+	default:
+		return nil, fmt.Errorf("rewarded transaction %s has unexpected type %T", txID, tx.Unsigned)
+	}
+
+	var err error
+	switch stakerTx := tx.Unsigned.(type) {
+	case ValidatorTx:
+		dbTx.RewardsOwner, err = shared.RewardsOwnerAddress(stakerTx.ValidationRewardsOwner())
+		if err != nil {
+			return nil, err
+		}
+		dbTx.DelegationRewardsOwner, err = shared.RewardsOwnerAddress(stakerTx.DelegationRewardsOwner())
+	case DelegatorTx:
+		dbTx.RewardsOwner, err = shared.RewardsOwnerAddress(stakerTx.RewardsOwner())
+	}
+	if err != nil {
+		return nil, err
+	}
+	stakerTx := tx.Unsigned.(StakerTx)
+	endTime := stakerTx.EndTime()
+	dbTx.NodeID = stakerTx.NodeID().String()
+	dbTx.EndTime = &endTime
+	return dbTx, nil
+}
+
+// Split reward outputs of stakeTx paid by rewardTx. The node creates the output with the reward for
+// the stake first and the output with the delegation fee second, but either may be missing. A single
+// output of a validator is the delegation fee if it is paid to the delegation rewards owner only (the
+// validator reward is not paid if the RewardValidatorTx is aborted, accrued delegation fees are).
+func newStakeReward(rewardTx *database.PChainTx, stakeTx *database.PChainTx, outs []*database.PChainTxOutput) *database.PChainStakeReward {
+	reward := &database.PChainStakeReward{
+		StakeTxID:   *stakeTx.TxID,
+		RewardTxID:  *rewardTx.TxID,
+		StakeTxType: stakeTx.Type,
+		NodeID:      stakeTx.NodeID,
+		BlockHeight: rewardTx.BlockHeight,
+	}
+	if rewardTx.BlockTime != nil {
+		reward.Time = *rewardTx.BlockTime
+	} else if stakeTx.EndTime != nil {
+		reward.Time = *stakeTx.EndTime
+	}
+
+	sort.Slice(outs, func(i, j int) bool { return outs[i].Idx < outs[j].Idx })
+	for i, out := range outs {
+		reward.Amount += out.Amount
+		isFee := i > 0 || (len(outs) == 1 &&
+			out.Address == stakeTx.DelegationRewardsOwner && out.Address != stakeTx.RewardsOwner)
+		if isFee {
+			reward.DelegationFee += out.Amount
+			reward.DelegationFeeAddress = out.Address
+		} else {
+			reward.StakerReward = out.Amount
+			reward.StakerRewardAddress = out.Address
+		}
+	}
+	return reward
+}
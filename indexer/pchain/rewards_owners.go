@@ -0,0 +1,55 @@
+package pchain
+
+import (
+	"flare-indexer/database"
+	"flare-indexer/utils/chain"
+	"fmt"
+
+	"github.com/ava-labs/avalanchego/vms/platformvm/fx"
+	"github.com/ava-labs/avalanchego/vms/platformvm/txs"
+	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+)
+
+// Rewards owners of a staking transaction, no rows are returned for other transactions
+func stakeRewardsOwners(txID string, tx txs.UnsignedTx) ([]*database.PChainRewardsOwner, error) {
+	switch stakerTx := tx.(type) {
+	case ValidatorTx:
+		owners, err := newRewardsOwners(txID, database.PChainStakeRewardsOwner, stakerTx.ValidationRewardsOwner())
+		if err != nil {
+			return nil, err
+		}
+		feeOwners, err := newRewardsOwners(txID, database.PChainDelegationFeeRewardsOwner, stakerTx.DelegationRewardsOwner())
+		if err != nil {
+			return nil, err
+		}
+		return append(owners, feeOwners...), nil
+	case DelegatorTx:
+		return newRewardsOwners(txID, database.PChainStakeRewardsOwner, stakerTx.RewardsOwner())
+	default:
+		return nil, nil
+	}
+}
+
+// One row per address of owner
+func newRewardsOwners(txID string, ownerType database.PChainRewardsOwnerType, owner fx.Owner) ([]*database.PChainRewardsOwner, error) {
+	oo, ok := owner.(*secp256k1fx.OutputOwners)
+	if !ok {
+		return nil, fmt.Errorf("rewards owner of %s has unsupported type %T", txID, owner)
+	}
+	owners := make([]*database.PChainRewardsOwner, len(oo.Addrs))
+	for i, addr := range oo.Addrs {
+		address, err := chain.FormatAddressBytes(addr.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		owners[i] = &database.PChainRewardsOwner{
+			TxID:       txID,
+			Type:       ownerType,
+			AddressIdx: uint32(i),
+			Address:    address,
+			Threshold:  oo.Threshold,
+			Locktime:   oo.Locktime,
+		}
+	}
+	return owners, nil
+}
@@ -0,0 +1,149 @@
+package pchain
+
+import (
+	"context"
+	"flare-indexer/database"
+	"flare-indexer/utils/chain"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/vms/platformvm/txs"
+	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRewardOutput(idx uint32, amount uint64, address string) *database.PChainTxOutput {
+	return &database.PChainTxOutput{
+		TxOutput: database.TxOutput{TxID: "stake", Idx: idx, Amount: amount, Address: address},
+		Type:     database.PChainRewardOutput,
+	}
+}
+
+func newTestStakeTx(txType database.PChainTxType) *database.PChainTx {
+	stakeTx := newL1TestTx("stake")
+	stakeTx.Type = txType
+	stakeTx.NodeID = "NodeID-1"
+	stakeTx.RewardsOwner = "owner"
+	stakeTx.DelegationRewardsOwner = "delegationOwner"
+	return stakeTx
+}
+
+func TestNewStakeReward(t *testing.T) {
+	rewardTx := newL1TestTx("reward")
+	stakeTx := newTestStakeTx(database.PChainAddValidatorTx)
+
+	reward := newStakeReward(rewardTx, stakeTx, []*database.PChainTxOutput{
+		newTestRewardOutput(3, 20, "delegationOwner"),
+		newTestRewardOutput(2, 100, "owner"),
+	})
+	require.Equal(t, "stake", reward.StakeTxID)
+	require.Equal(t, "reward", reward.RewardTxID)
+	require.Equal(t, "NodeID-1", reward.NodeID)
+	require.Equal(t, uint64(120), reward.Amount)
+	require.Equal(t, uint64(100), reward.StakerReward)
+	require.Equal(t, "owner", reward.StakerRewardAddress)
+	require.Equal(t, uint64(20), reward.DelegationFee)
+	require.Equal(t, "delegationOwner", reward.DelegationFeeAddress)
+	require.Equal(t, *rewardTx.BlockTime, reward.Time)
+
+	// Aborted reward of a validator pays accrued delegation fees only
+	reward = newStakeReward(rewardTx, stakeTx, []*database.PChainTxOutput{
+		newTestRewardOutput(2, 20, "delegationOwner"),
+	})
+	require.Equal(t, uint64(0), reward.StakerReward)
+	require.Equal(t, uint64(20), reward.DelegationFee)
+
+	// Delegation rewards owner is not considered for delegators
+	stakeTx = newTestStakeTx(database.PChainAddDelegatorTx)
+	stakeTx.DelegationRewardsOwner = ""
+	reward = newStakeReward(rewardTx, stakeTx, []*database.PChainTxOutput{
+		newTestRewardOutput(2, 50, "delegationOwner"),
+	})
+	require.Equal(t, uint64(50), reward.StakerReward)
+	require.Equal(t, uint64(0), reward.DelegationFee)
+
+	// Before Banff blocks the reward is paid at the end time of the stake
+	endTime := time.Unix(1700000000, 0)
+	stakeTx.EndTime = &endTime
+	rewardTx.BlockTime = nil
+	reward = newStakeReward(rewardTx, stakeTx, nil)
+	require.Equal(t, uint64(0), reward.Amount)
+	require.Equal(t, endTime, reward.Time)
+}
+
+func TestStakeRewardFromBatch(t *testing.T) {
+	xi := newL1TestBatchIndexer()
+	stakeTx := newTestStakeTx(database.PChainAddDelegatorTx)
+	xi.newTxs = append(xi.newTxs, stakeTx)
+
+	rewardTx := newL1TestTx("reward")
+	rewardTx.RewardTxID = "stake"
+	err := xi.addStakeReward(context.Background(), rewardTx, []*database.PChainTxOutput{
+		newTestRewardOutput(2, 50, "owner"),
+	})
+	require.NoError(t, err)
+	require.Len(t, xi.rewards, 1)
+	require.Equal(t, database.PChainAddDelegatorTx, xi.rewards[0].StakeTxType)
+	require.Equal(t, uint64(50), xi.rewards[0].StakerReward)
+}
+
+func TestStakeTxFromChain(t *testing.T) {
+	chain.AddressHRP = "costwo"
+	nodeID := ids.GenerateTestNodeID()
+	owner := &secp256k1fx.OutputOwners{Threshold: 1, Addrs: []ids.ShortID{ids.GenerateTestShortID()}}
+	tx := &txs.Tx{Unsigned: &txs.AddPermissionlessDelegatorTx{
+		Validator:              txs.Validator{NodeID: nodeID, End: 1700000000},
+		DelegationRewardsOwner: owner,
+	}}
+
+	stakeTx, err := stakeTxFromChain("stake", tx)
+	require.NoError(t, err)
+	require.Equal(t, database.PChainAddPermissionlessDelegatorTx, stakeTx.Type)
+	require.Equal(t, nodeID.String(), stakeTx.NodeID)
+	require.Equal(t, time.Unix(1700000000, 0), *stakeTx.EndTime)
+	require.Contains(t, stakeTx.RewardsOwner, "costwo1")
+
+	_, err = stakeTxFromChain("base", &txs.Tx{Unsigned: &txs.BaseTx{}})
+	require.Error(t, err)
+}
+
+func TestStakeRewardsOwners(t *testing.T) {
+	chain.AddressHRP = "costwo"
+	multisig := &secp256k1fx.OutputOwners{
+		Locktime:  100,
+		Threshold: 2,
+		Addrs:     []ids.ShortID{ids.GenerateTestShortID(), ids.GenerateTestShortID()},
+	}
+	feeOwner := &secp256k1fx.OutputOwners{Threshold: 1, Addrs: []ids.ShortID{ids.GenerateTestShortID()}}
+
+	owners, err := stakeRewardsOwners("validator", &txs.AddPermissionlessValidatorTx{
+		ValidatorRewardsOwner: multisig,
+		DelegatorRewardsOwner: feeOwner,
+	})
+	require.NoError(t, err)
+	require.Len(t, owners, 3)
+	for i, owner := range owners[:2] {
+		address, err := chain.FormatAddressBytes(multisig.Addrs[i].Bytes())
+		require.NoError(t, err)
+		require.Equal(t, database.PChainRewardsOwner{
+			TxID:       "validator",
+			Type:       database.PChainStakeRewardsOwner,
+			AddressIdx: uint32(i),
+			Address:    address,
+			Threshold:  2,
+			Locktime:   100,
+		}, *owner)
+	}
+	require.Equal(t, database.PChainDelegationFeeRewardsOwner, owners[2].Type)
+	require.Equal(t, uint32(0), owners[2].AddressIdx)
+
+	owners, err = stakeRewardsOwners("delegator", &txs.AddPermissionlessDelegatorTx{DelegationRewardsOwner: multisig})
+	require.NoError(t, err)
+	require.Len(t, owners, 2)
+	require.Equal(t, database.PChainStakeRewardsOwner, owners[1].Type)
+
+	owners, err = stakeRewardsOwners("base", &txs.BaseTx{})
+	require.NoError(t, err)
+	require.Empty(t, owners)
+}
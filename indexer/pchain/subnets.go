@@ -0,0 +1,83 @@
+package pchain
+
+import (
+	"encoding/hex"
+	"flare-indexer/database"
+	"flare-indexer/indexer/shared"
+	"fmt"
+	"strings"
+
+	"github.com/ava-labs/avalanchego/utils/hashing"
+	"github.com/ava-labs/avalanchego/vms/platformvm/txs"
+	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+)
+
+// Subnet and chain entities of a batch
+type subnetEntities struct {
+	subnets []*database.PChainSubnet
+	chains  []*database.PChainChain
+}
+
+func (xi *txBatchIndexer) updateCreateSubnetTx(dbTx *database.PChainTx, tx *txs.CreateSubnetTx) error {
+	owner, ok := tx.Owner.(*secp256k1fx.OutputOwners)
+	if !ok {
+		return fmt.Errorf("owner of subnet %s has unsupported type %T", *dbTx.TxID, tx.Owner)
+	}
+	addresses, err := shared.FormatAddresses(owner.Addrs)
+	if err != nil {
+		return err
+	}
+	dbTx.SubnetID = *dbTx.TxID
+
+	xi.subnets.subnets = append(xi.subnets.subnets, &database.PChainSubnet{
+		SubnetID:       *dbTx.TxID,
+		OwnerThreshold: owner.Threshold,
+		OwnerLocktime:  owner.Locktime,
+		OwnerAddresses: addresses,
+		BlockHeight:    dbTx.BlockHeight,
+		BlockTime:      dbTx.BlockTime,
+	})
+	return xi.updateGeneralBaseTx(dbTx, database.PChainCreateSubnetTx, &tx.BaseTx)
+}
+
+func (xi *txBatchIndexer) updateCreateChainTx(dbTx *database.PChainTx, tx *txs.CreateChainTx) error {
+	fxIDs := make([]string, len(tx.FxIDs))
+	for i, id := range tx.FxIDs {
+		fxIDs[i] = id.String()
+	}
+	dbTx.SubnetID = tx.SubnetID.String()
+
+	xi.subnets.chains = append(xi.subnets.chains, &database.PChainChain{
+		ChainID:     *dbTx.TxID,
+		SubnetID:    tx.SubnetID.String(),
+		ChainName:   tx.ChainName,
+		VMID:        tx.VMID.String(),
+		FxIDs:       strings.Join(fxIDs, ","),
+		GenesisHash: hex.EncodeToString(hashing.ComputeHash256(tx.GenesisData)),
+		BlockHeight: dbTx.BlockHeight,
+		BlockTime:   dbTx.BlockTime,
+	})
+	return xi.updateGeneralBaseTx(dbTx, database.PChainCreateChainTx, &tx.BaseTx)
+}
+
+func (xi *txBatchIndexer) updateAddSubnetValidatorTx(dbTx *database.PChainTx, tx *txs.AddSubnetValidatorTx) error {
+	startTime := tx.StartTime()
+	endTime := tx.EndTime()
+	dbTx.SubnetID = tx.Subnet.String()
+	dbTx.NodeID = tx.Validator.NodeID.String()
+	dbTx.StartTime = &startTime
+	dbTx.EndTime = &endTime
+	dbTx.Weight = tx.Wght
+	return xi.updateGeneralBaseTx(dbTx, database.PChainAddSubnetValidatorTx, &tx.BaseTx)
+}
+
+func (xi *txBatchIndexer) updateRemoveSubnetValidatorTx(dbTx *database.PChainTx, tx *txs.RemoveSubnetValidatorTx) error {
+	dbTx.SubnetID = tx.Subnet.String()
+	dbTx.NodeID = tx.NodeID.String()
+	return xi.updateGeneralBaseTx(dbTx, database.PChainRemoveSubnetValidatorTx, &tx.BaseTx)
+}
+
+func (xi *txBatchIndexer) updateTransformSubnetTx(dbTx *database.PChainTx, tx *txs.TransformSubnetTx) error {
+	dbTx.SubnetID = tx.Subnet.String()
+	return xi.updateGeneralBaseTx(dbTx, database.PChainTransformSubnetTx, &tx.BaseTx)
+}
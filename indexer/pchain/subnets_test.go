@@ -0,0 +1,55 @@
+package pchain
+
+import (
+	"flare-indexer/database"
+	"flare-indexer/utils/chain"
+	"strings"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/vms/platformvm/txs"
+	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateSubnetAndChainTxs(t *testing.T) {
+	chain.AddressHRP = "costwo"
+	xi := newL1TestBatchIndexer()
+
+	subnetTx := newL1TestTx(ids.GenerateTestID().String())
+	err := xi.updateCreateSubnetTx(subnetTx, &txs.CreateSubnetTx{
+		Owner: &secp256k1fx.OutputOwners{
+			Threshold: 2,
+			Locktime:  10,
+			Addrs:     []ids.ShortID{ids.GenerateTestShortID(), ids.GenerateTestShortID()},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, xi.subnets.subnets, 1)
+	subnet := xi.subnets.subnets[0]
+	require.Equal(t, *subnetTx.TxID, subnet.SubnetID)
+	require.Equal(t, *subnetTx.TxID, subnetTx.SubnetID)
+	require.Equal(t, uint32(2), subnet.OwnerThreshold)
+	require.Equal(t, uint64(10), subnet.OwnerLocktime)
+	require.Len(t, strings.Split(subnet.OwnerAddresses, ","), 2)
+
+	subnetID, err := ids.FromString(subnet.SubnetID)
+	require.NoError(t, err)
+	chainTx := newL1TestTx(ids.GenerateTestID().String())
+	err = xi.updateCreateChainTx(chainTx, &txs.CreateChainTx{
+		SubnetID:    subnetID,
+		ChainName:   "test",
+		VMID:        ids.GenerateTestID(),
+		FxIDs:       []ids.ID{ids.GenerateTestID(), ids.GenerateTestID()},
+		GenesisData: []byte("genesis"),
+	})
+	require.NoError(t, err)
+	require.Len(t, xi.subnets.chains, 1)
+	c := xi.subnets.chains[0]
+	require.Equal(t, *chainTx.TxID, c.ChainID)
+	require.Equal(t, subnet.SubnetID, c.SubnetID)
+	require.Equal(t, subnet.SubnetID, chainTx.SubnetID)
+	require.Equal(t, "test", c.ChainName)
+	require.Len(t, c.GenesisHash, 64)
+	require.Equal(t, database.PChainCreateChainTx, chainTx.Type)
+}
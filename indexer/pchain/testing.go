@@ -0,0 +1,29 @@
+package pchain
+
+import (
+	"context"
+	indexerctx "flare-indexer/indexer/context"
+	"flare-indexer/utils/chain"
+)
+
+// Index recorded P-chain blocks of the test network (resources/test) into the database of ctx,
+// used to fill the test database of other packages
+func IndexTestBlocks(ctx indexerctx.IndexerContext) error {
+	client, err := chain.PChainTestClient()
+	if err != nil {
+		return err
+	}
+	rpcClient, err := chain.PChainTestRPCClient()
+	if err != nil {
+		return err
+	}
+
+	idxr := pChainBlockIndexer{}
+	idxr.StateName = StateName
+	idxr.IndexerName = "P-chain Test Blocks"
+	idxr.Client = client
+	idxr.DB = ctx.DB()
+	idxr.Config = ctx.Config().PChainIndexer
+	idxr.BatchIndexer = NewPChainBatchIndexer(ctx, client, rpcClient, nil)
+	return idxr.IndexBatch(context.Background())
+}
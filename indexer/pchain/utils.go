@@ -1,7 +1,7 @@
 package pchain
 
 import (
-	"encoding/json"
+	"context"
 	"flare-indexer/utils/chain"
 
 	"github.com/ava-labs/avalanchego/ids"
@@ -10,10 +10,9 @@ import (
 	"github.com/ava-labs/avalanchego/vms/components/avax"
 	"github.com/ava-labs/avalanchego/vms/platformvm/genesis"
 	"github.com/ava-labs/avalanchego/vms/platformvm/txs"
-	"github.com/pkg/errors"
 )
 
-func CallPChainGetTxApi(client chain.RPCClient, txID string) (*txs.Tx, error) {
+func CallPChainGetTxApi(ctx context.Context, client chain.RPCClient, txID string) (*txs.Tx, error) {
 	id, err := ids.FromString(txID)
 	if err != nil {
 		return nil, err
@@ -25,18 +24,13 @@ func CallPChainGetTxApi(client chain.RPCClient, txID string) (*txs.Tx, error) {
 	}
 
 	// Fetch from chain
-	reply, err := client.GetTx(id)
+	reply, err := client.GetTx(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
 	// Parse from hex string
-	var txString string
-	err = json.Unmarshal(reply.Tx, &txString)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to unmarshal tx string")
-	}
-	txData, err := formatting.Decode(formatting.Hex, txString)
+	txData, err := decodeTxReply(reply)
 	if err != nil {
 		return nil, err
 	}
@@ -58,14 +52,14 @@ type GetRewardUTXOsReply struct {
 	Encoding   formatting.Encoding `json:"encoding"`
 }
 
-func CallPChainGetRewardUTXOsApi(client chain.RPCClient, txID string) ([]*avax.UTXO, error) {
+func CallPChainGetRewardUTXOsApi(ctx context.Context, client chain.RPCClient, txID string) ([]*avax.UTXO, error) {
 	id, err := ids.FromString(txID)
 	if err != nil {
 		return nil, err
 	}
 
 	// Fetch from chain
-	reply, err := client.GetRewardUTXOs(id)
+	reply, err := client.GetRewardUTXOs(ctx, id)
 	if err != nil {
 		return nil, err
 	}
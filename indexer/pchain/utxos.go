@@ -0,0 +1,121 @@
+package pchain
+
+import (
+	"flare-indexer/database"
+	"flare-indexer/indexer/shared"
+	"flare-indexer/logger"
+
+	"gorm.io/gorm"
+)
+
+// Create UTXO state rows of new outputs, return stakes of rewarded staking transactions
+// and mark outputs spent by inputs
+func persistUTXOChanges(db *gorm.DB, heights *txHeights, ins []*database.PChainTxInput, outs []*database.PChainTxOutput) error {
+	err := database.CreatePChainUTXOs(db, heights.utxos(outs))
+	if err != nil {
+		return err
+	}
+	err = database.UnstakePChainUTXOs(db, heights.rewards)
+	if err != nil {
+		return err
+	}
+	return database.SpendPChainUTXOs(db, heights.spends(ins))
+}
+
+// Apply spends and unstakes by transactions in blocks [fromHeight, toHeight] which were persisted
+// before the outputs they change. Outputs of different backfill segments are persisted in any
+// order, so this is done when all segments are persisted.
+func ReconcileUTXOs(db *gorm.DB, fromHeight, toHeight uint64) error {
+	unstakeHeights, err := database.FetchMissingPChainUnstakes(db, fromHeight, toHeight)
+	if err != nil {
+		return err
+	}
+	err = database.UnstakePChainUTXOs(db, unstakeHeights)
+	if err != nil {
+		return err
+	}
+	spends, err := database.FetchMissingPChainUTXOSpends(db, fromHeight, toHeight)
+	if err != nil {
+		return err
+	}
+	if len(spends) > 0 || len(unstakeHeights) > 0 {
+		logger.Info("Applied %d missing spends and %d missing unstakes of P-chain outputs", len(spends), len(unstakeHeights))
+	}
+	return database.SpendPChainUTXOs(db, spends)
+}
+
+// Block heights of transactions creating and spending outputs
+type txHeights struct {
+	txs     map[string]uint64 // transaction ID -> block height
+	rewards map[string]uint64 // staking transaction ID -> block height of its RewardValidatorTx
+}
+
+func newTxHeights(txs []*database.PChainTx) *txHeights {
+	h := &txHeights{
+		txs:     make(map[string]uint64, len(txs)),
+		rewards: make(map[string]uint64),
+	}
+	for _, tx := range txs {
+		h.txs[*tx.TxID] = tx.BlockHeight
+		if tx.Type == database.PChainRewardValidatorTx {
+			h.rewards[tx.RewardTxID] = tx.BlockHeight
+		}
+	}
+	return h
+}
+
+// UTXO state rows of outputs. Reward outputs have the ID of the staking transaction
+// but are created by its RewardValidatorTx. Exported outputs are UTXOs of the destination
+// chain and are skipped.
+func (h *txHeights) utxos(outs []*database.PChainTxOutput) []*database.PChainUTXO {
+	utxos := make([]*database.PChainUTXO, 0, len(outs))
+	for _, out := range outs {
+		if out.Type == database.PChainExportedOutput {
+			continue
+		}
+		utxo := &database.PChainUTXO{
+			TxID:    out.TxID,
+			Idx:     out.Idx,
+			Address: out.Address,
+			Amount:  out.Amount,
+			Type:    out.Type,
+
+			AssetID:           out.AssetID,
+			Locktime:          out.Locktime,
+			Threshold:         out.Threshold,
+			OwnerGroup:        out.OwnerGroup,
+			StakeableLocktime: out.StakeableLocktime,
+		}
+		if out.Type == database.PChainRewardOutput {
+			utxo.BlockHeight = h.rewards[out.TxID]
+		} else {
+			utxo.BlockHeight = h.txs[out.TxID]
+		}
+		if height, ok := h.rewards[out.TxID]; ok && out.Type == database.PChainStakeOutput {
+			utxo.UnstakeHeight = &height
+		}
+		utxos = append(utxos, utxo)
+	}
+	return utxos
+}
+
+// Spent outputs of inputs. Inputs with multiple addresses spend the same output, so
+// spends are deduplicated.
+func (h *txHeights) spends(ins []*database.PChainTxInput) []*database.PChainUTXOSpend {
+	spent := make(map[shared.IdIndexKey]bool, len(ins))
+	spends := make([]*database.PChainUTXOSpend, 0, len(ins))
+	for _, in := range ins {
+		key := shared.NewIdIndexKey(in.OutTxID, in.OutIdx)
+		if spent[key] {
+			continue
+		}
+		spent[key] = true
+		spends = append(spends, &database.PChainUTXOSpend{
+			OutTxID:     in.OutTxID,
+			OutIdx:      in.OutIdx,
+			SpentTxID:   in.TxID,
+			SpentHeight: h.txs[in.TxID],
+		})
+	}
+	return spends
+}
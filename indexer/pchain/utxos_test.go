@@ -0,0 +1,200 @@
+package pchain
+
+import (
+	"flare-indexer/database"
+	"flare-indexer/indexer/shared"
+	"flare-indexer/utils/chain"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+	"github.com/ava-labs/avalanchego/vms/platformvm/stakeable"
+	"github.com/ava-labs/avalanchego/vms/platformvm/txs"
+	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxHeights(t *testing.T) {
+	stakeTx := newL1TestTx("stake")
+	stakeTx.BlockHeight = 10
+	rewardTx := newL1TestTx("reward")
+	rewardTx.Type = database.PChainRewardValidatorTx
+	rewardTx.RewardTxID = "stake"
+	rewardTx.BlockHeight = 20
+	spendTx := newL1TestTx("spend")
+	spendTx.BlockHeight = 30
+	heights := newTxHeights([]*database.PChainTx{stakeTx, rewardTx, spendTx})
+
+	utxos := heights.utxos([]*database.PChainTxOutput{
+		{TxOutput: database.TxOutput{TxID: "stake", Idx: 0, Amount: 5}, Type: database.PChainDefaultOutput},
+		{TxOutput: database.TxOutput{TxID: "stake", Idx: 1, Amount: 100}, Type: database.PChainStakeOutput},
+		{TxOutput: database.TxOutput{TxID: "stake", Idx: 2, Amount: 7}, Type: database.PChainRewardOutput},
+	})
+	require.Len(t, utxos, 3)
+	require.Equal(t, uint64(10), utxos[0].BlockHeight)
+	require.Nil(t, utxos[0].UnstakeHeight)
+	require.Equal(t, uint64(10), utxos[1].BlockHeight)
+	require.Equal(t, uint64(20), *utxos[1].UnstakeHeight)
+	require.Equal(t, uint64(20), utxos[2].BlockHeight)
+
+	// Input with two addresses spends a single output
+	in := database.TxInput{TxID: "spend", OutTxID: "stake", OutIdx: 1}
+	in2 := in
+	in2.Address = "other"
+	spends := heights.spends([]*database.PChainTxInput{{TxInput: in}, {TxInput: in2}})
+	require.Len(t, spends, 1)
+	require.Equal(t, database.PChainUTXOSpend{OutTxID: "stake", OutIdx: 1, SpentTxID: "spend", SpentHeight: 30}, *spends[0])
+}
+
+func TestStakeableLockedOutputs(t *testing.T) {
+	chain.AddressHRP = "costwo"
+	assetID := ids.GenerateTestID()
+	owners := secp256k1fx.OutputOwners{
+		Locktime:  100,
+		Threshold: 2,
+		Addrs:     []ids.ShortID{ids.GenerateTestShortID(), ids.GenerateTestShortID()},
+	}
+	tx := &txs.BaseTx{BaseTx: avax.BaseTx{Outs: []*avax.TransferableOutput{{
+		Asset: avax.Asset{ID: assetID},
+		Out: &stakeable.LockOut{
+			Locktime:        200,
+			TransferableOut: &secp256k1fx.TransferOutput{Amt: 10, OutputOwners: owners},
+		},
+	}}}}
+
+	outs, err := getTxOutputs("tx", tx)
+	require.NoError(t, err)
+	require.Len(t, outs, 2)
+	for _, out := range outs {
+		dbOut := out.(*database.PChainTxOutput)
+		require.Equal(t, uint64(10), dbOut.Amount)
+		require.Equal(t, assetID.String(), dbOut.AssetID)
+		require.Equal(t, uint64(100), dbOut.Locktime)
+		require.Equal(t, uint32(2), dbOut.Threshold)
+		require.Equal(t, uint64(200), dbOut.StakeableLocktime)
+		require.Equal(t, shared.OwnerGroupID(&owners), dbOut.OwnerGroup)
+	}
+	require.NotEqual(t, outs[0].(*database.PChainTxOutput).Address, outs[1].(*database.PChainTxOutput).Address)
+
+	// Owner group does not depend on the order of addresses
+	reversed := owners
+	reversed.Addrs = []ids.ShortID{owners.Addrs[1], owners.Addrs[0]}
+	require.Equal(t, shared.OwnerGroupID(&owners), shared.OwnerGroupID(&reversed))
+}
+
+func TestExportedOutputs(t *testing.T) {
+	chain.AddressHRP = "costwo"
+	newOut := func(amount uint64) *avax.TransferableOutput {
+		return &avax.TransferableOutput{Out: &secp256k1fx.TransferOutput{
+			Amt:          amount,
+			OutputOwners: secp256k1fx.OutputOwners{Threshold: 1, Addrs: []ids.ShortID{ids.GenerateTestShortID()}},
+		}}
+	}
+	tx := &txs.ExportTx{
+		BaseTx:           txs.BaseTx{BaseTx: avax.BaseTx{Outs: []*avax.TransferableOutput{newOut(1)}}},
+		ExportedOutputs:  []*avax.TransferableOutput{newOut(10), newOut(20)},
+		DestinationChain: ids.GenerateTestID(),
+	}
+
+	outs, err := getTxOutputs("export", tx)
+	require.NoError(t, err)
+	dbOuts := make([]*database.PChainTxOutput, len(outs))
+	for i, out := range outs {
+		dbOuts[i] = out.(*database.PChainTxOutput)
+	}
+	require.Len(t, dbOuts, 3)
+	require.Equal(t, database.PChainDefaultOutput, dbOuts[0].Type)
+	require.Equal(t, database.PChainExportedOutput, dbOuts[1].Type)
+	require.Equal(t, uint32(1), dbOuts[1].Idx)
+	require.Equal(t, uint64(20), dbOuts[2].Amount)
+	require.Equal(t, uint32(2), dbOuts[2].Idx)
+
+	// Exported outputs are not P-chain UTXOs
+	exportTx := newL1TestTx("export")
+	utxos := newTxHeights([]*database.PChainTx{exportTx}).utxos(dbOuts)
+	require.Len(t, utxos, 1)
+	require.Equal(t, uint64(1), utxos[0].Amount)
+}
+
+func TestSpendPersistedBeforeOutput(t *testing.T) {
+	cfg := database.TestDBConfig("flare_indexer_indexer")
+	db, err := database.ConnectAndInitializeTestDB(&cfg, true)
+	require.NoError(t, err)
+
+	stakeTx := newL1TestTx("stake")
+	stakeTx.Type = database.PChainAddDelegatorTx
+	stakeTx.BlockHeight = 10
+	rewardTx := newL1TestTx("reward")
+	rewardTx.Type = database.PChainRewardValidatorTx
+	rewardTx.RewardTxID = "stake"
+	rewardTx.BlockHeight = 20
+	spendTx := newL1TestTx("spend")
+	spendTx.BlockHeight = 30
+	outs := []*database.PChainTxOutput{
+		{TxOutput: database.TxOutput{TxID: "stake", Idx: 0, Address: "a", Amount: 5}, Type: database.PChainDefaultOutput},
+		{TxOutput: database.TxOutput{TxID: "stake", Idx: 1, Address: "a", Amount: 100}, Type: database.PChainStakeOutput},
+	}
+	ins := []*database.PChainTxInput{
+		{TxInput: database.TxInput{TxID: "spend", Address: "a", OutTxID: "stake", OutIdx: 0}, Type: database.PChainDefaultInput},
+	}
+
+	// Segment with the reward and the spend is persisted before the segment with the outputs
+	require.NoError(t, database.CreatePChainEntities(db, nil, []*database.PChainTx{rewardTx, spendTx}, ins, nil))
+	require.NoError(t, persistUTXOChanges(db, newTxHeights([]*database.PChainTx{rewardTx, spendTx}), ins, nil))
+	require.NoError(t, database.CreatePChainEntities(db, nil, []*database.PChainTx{stakeTx}, nil, outs))
+	require.NoError(t, persistUTXOChanges(db, newTxHeights([]*database.PChainTx{stakeTx}), nil, outs))
+
+	utxos, err := database.FetchPChainUnspentUTXOs(db, "a", 40, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, utxos, 2)
+
+	require.NoError(t, ReconcileUTXOs(db, 10, 30))
+	utxos, err = database.FetchPChainUnspentUTXOs(db, "a", 40, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, utxos, 1)
+	require.Equal(t, uint32(1), utxos[0].Idx)
+	require.Equal(t, uint64(20), *utxos[0].UnstakeHeight)
+	utxos, err = database.FetchPChainUnspentUTXOs(db, "a", 25, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, utxos, 2)
+
+	// Nothing left to reconcile
+	spends, err := database.FetchMissingPChainUTXOSpends(db, 10, 30)
+	require.NoError(t, err)
+	require.Empty(t, spends)
+}
+
+func TestSpendPChainUTXOs(t *testing.T) {
+	cfg := database.TestDBConfig("flare_indexer_indexer")
+	db, err := database.ConnectAndInitializeTestDB(&cfg, true)
+	require.NoError(t, err)
+
+	require.NoError(t, database.CreatePChainUTXOs(db, []*database.PChainUTXO{
+		{TxID: "out", Idx: 0, Address: "a", Amount: 1, Type: database.PChainDefaultOutput},
+		{TxID: "out", Idx: 1, Address: "a", Amount: 2, Type: database.PChainDefaultOutput},
+		{TxID: "out", Idx: 2, Address: "a", Amount: 3, Type: database.PChainDefaultOutput},
+		{TxID: "out", Idx: 3, Address: "a", Amount: 4, Type: database.PChainDefaultOutput},
+	}))
+
+	// Outputs of the same transaction spent by different transactions at the same and different heights
+	require.NoError(t, database.SpendPChainUTXOs(db, []*database.PChainUTXOSpend{
+		{OutTxID: "out", OutIdx: 0, SpentTxID: "spend1", SpentHeight: 10},
+		{OutTxID: "out", OutIdx: 2, SpentTxID: "spend2", SpentHeight: 10},
+		{OutTxID: "out", OutIdx: 3, SpentTxID: "spend3", SpentHeight: 11},
+		{OutTxID: "genesis", OutIdx: 0, SpentTxID: "spend3", SpentHeight: 11},
+	}))
+
+	var utxos []*database.PChainUTXO
+	require.NoError(t, db.Order("idx").Find(&utxos).Error)
+	require.Len(t, utxos, 4)
+	spent := func(u *database.PChainUTXO) []interface{} {
+		if u.SpentTxID == nil {
+			return nil
+		}
+		return []interface{}{*u.SpentTxID, *u.SpentHeight}
+	}
+	require.Equal(t, []interface{}{"spend1", uint64(10)}, spent(utxos[0]))
+	require.Nil(t, spent(utxos[1]))
+	require.Equal(t, []interface{}{"spend2", uint64(10)}, spent(utxos[2]))
+	require.Equal(t, []interface{}{"spend3", uint64(11)}, spent(utxos[3]))
+}
@@ -0,0 +1,92 @@
+package redecode
+
+import (
+	"context"
+	"flag"
+	"flare-indexer/database"
+	indexerctx "flare-indexer/indexer/context"
+	"flare-indexer/indexer/pchain"
+	"flare-indexer/logger"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const defaultBatchSize = 100
+
+// Run the redecode command with its command line arguments:
+//
+//	redecode --tables <table,...|all> [--columns <column,...>] [--from <height>] [--to <height>]
+//	         [--batch-size <n>] [--node-rewards]
+//
+// Tables are rebuilt in place from P-chain blocks stored in p_chain_blocks, which are re-decoded
+// in height order, so data added by new decoding features is filled in without re-indexing from
+// the node. Rows of p_chain_txes are updated (only the given columns if set), rows of other
+// tables are replaced. The range defaults to all stored blocks. An interrupted redecode is
+// resumed by running the command again with --from set to the logged height. The P-chain
+// indexer should not run during the redecode.
+func Run(ctx context.Context, ictx indexerctx.IndexerContext, args []string) error {
+	fs := flag.NewFlagSet("redecode", flag.ContinueOnError)
+	tables := fs.String("tables", "", "Comma-separated tables to rebuild or all: "+strings.Join(pchain.RedecodeTableNames(), ", "))
+	columns := fs.String("columns", "", "Comma-separated columns of p_chain_txes to update (all if empty)")
+	from := fs.Uint64("from", 0, "First P-chain block height to re-decode")
+	to := fs.Uint64("to", 0, "Last P-chain block height to re-decode (last stored block if 0)")
+	batchSize := fs.Int("batch-size", defaultBatchSize, "Number of blocks re-decoded in a database transaction")
+	nodeRewards := fs.Bool("node-rewards", false, "Fetch reward outputs from the node even if they are stored")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *batchSize <= 0 {
+		return errors.New("batch size must be positive")
+	}
+	tableNames := splitList(*tables)
+	if len(tableNames) == 1 && tableNames[0] == "all" {
+		tableNames = pchain.RedecodeTableNames()
+	}
+
+	r, err := pchain.NewRedecoder(ictx, tableNames, splitList(*columns), *nodeRewards)
+	if err != nil {
+		return err
+	}
+	heights, err := database.FetchHeightRange(ictx.DB(), "p_chain_blocks", "height")
+	if err != nil {
+		return err
+	}
+	if heights.Count == 0 {
+		logger.Info("No P-chain blocks are stored, nothing to re-decode")
+		return nil
+	}
+	if *to == 0 || *to > heights.Max {
+		*to = heights.Max
+	}
+	if *from > *to {
+		return errors.Errorf("invalid height range [%d, %d]", *from, *to)
+	}
+
+	logger.Info("Re-decoding P-chain heights [%d, %d] into %s", *from, *to, strings.Join(tableNames, ", "))
+	for next := *from; next <= *to; {
+		if ctx.Err() != nil {
+			logger.Info("Re-decoding interrupted, resume with --from %d", next)
+			return ctx.Err()
+		}
+		last, err := r.RedecodeBatch(ctx, next, *to, *batchSize)
+		if err != nil {
+			return errors.Wrapf(err, "failed to re-decode blocks from height %d", next)
+		}
+		logger.Info("Re-decoded P-chain heights [%d, %d]", next, last-1)
+		next = last
+	}
+	logger.Info("Re-decoding done")
+	return nil
+}
+
+// Non-empty comma-separated items of s
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -1,36 +1,113 @@
 package runner
 
 import (
-	"flare-indexer/indexer/context"
+	"context"
+	indexerctx "flare-indexer/indexer/context"
 	"flare-indexer/indexer/cronjob"
 	"flare-indexer/indexer/pchain"
 	"flare-indexer/indexer/xchain"
+	"flare-indexer/logger"
 	"log"
+	"sort"
+	"sync"
+	"time"
 )
 
-func Start(ctx context.IndexerContext) {
-	xIndexer := xchain.CreateXChainTxIndexer(ctx)
-	pIndexer := pchain.CreatePChainBlockIndexer(ctx)
+// Maximal time to wait for indexers and cronjobs to stop after shutdown is requested.
+// Should be longer than the time needed to wait for a submitted vote to be mined.
+const ShutdownTimeout = 90 * time.Second
 
-	votingCronjob, err := cronjob.NewVotingCronjob(ctx)
+// Tracks running components so that shutdown can wait for them
+type Components struct {
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	running map[string]bool
+}
+
+// Start indexers and cronjobs; they run until ctx is cancelled
+func Start(ctx context.Context, ictx indexerctx.IndexerContext) *Components {
+	xIndexer, err := xchain.CreateXChainTxIndexer(ictx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	xBlockIndexer, err := xchain.CreateXChainBlockIndexer(ictx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pIndexer, err := pchain.CreatePChainBlockIndexer(ctx, ictx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	votingCronjob, err := cronjob.NewVotingCronjob(ictx)
 	if err != nil {
 		log.Fatal(err)
 	}
-	mirrorCronjob, err := cronjob.NewMirrorCronjob(ctx)
+	mirrorCronjob, err := cronjob.NewMirrorCronjob(ictx)
 	if err != nil {
 		log.Fatal(err)
 	}
-	uptimeCronjob := cronjob.NewUptimeCronjob(ctx)
-	uptimeVotingCronjob, err := cronjob.NewUptimeVotingCronjob(ctx)
+	uptimeCronjob := cronjob.NewUptimeCronjob(ictx)
+	uptimeVotingCronjob, err := cronjob.NewUptimeVotingCronjob(ictx)
 	if err != nil {
 		log.Fatal(err)
 	}
+	transferCronjob := cronjob.NewTransferCronjob(ictx)
 
-	go xIndexer.Run()
-	go pIndexer.Run()
+	c := &Components{running: make(map[string]bool)}
 
-	go cronjob.RunCronjob(uptimeCronjob)
-	go cronjob.RunCronjob(votingCronjob)
-	go cronjob.RunCronjob(mirrorCronjob)
-	go cronjob.RunCronjob(uptimeVotingCronjob)
+	c.run("x_chain_indexer", func() { xIndexer.Run(ctx) })
+	c.run("x_chain_block_indexer", func() { xBlockIndexer.Run(ctx) })
+	c.run("p_chain_indexer", func() { pIndexer.Run(ctx) })
+
+	for _, job := range []cronjob.Cronjob{uptimeCronjob, votingCronjob, mirrorCronjob, uptimeVotingCronjob, transferCronjob} {
+		job := job
+		c.run(job.Name(), func() { cronjob.RunCronjob(ctx, job) })
+	}
+	return c
+}
+
+func (c *Components) run(name string, f func()) {
+	c.mu.Lock()
+	c.running[name] = true
+	c.mu.Unlock()
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer func() {
+			c.mu.Lock()
+			delete(c.running, name)
+			c.mu.Unlock()
+		}()
+		f()
+	}()
+}
+
+// Wait for all components to stop, at most for timeout. Returns the names of
+// components that were still running when the timeout expired.
+func (c *Components) Wait(timeout time.Duration) []string {
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-time.After(timeout):
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	names := make([]string, 0, len(c.running))
+	for name := range c.running {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		logger.Warn("%s did not stop in %v and was interrupted", name, timeout)
+	}
+	return names
 }
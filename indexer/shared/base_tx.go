@@ -1,17 +1,24 @@
 package shared
 
 import (
+	"encoding/binary"
 	"flare-indexer/database"
 	"flare-indexer/utils/chain"
 	"fmt"
+	"strings"
 
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils"
+	"github.com/ava-labs/avalanchego/utils/hashing"
 	"github.com/ava-labs/avalanchego/vms/components/avax"
 	"github.com/ava-labs/avalanchego/vms/components/verify"
 	"github.com/ava-labs/avalanchego/vms/platformvm/fx"
+	"github.com/ava-labs/avalanchego/vms/platformvm/stakeable"
 	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
 )
 
 // Create database outputs from TransferableOutputs, provided their type is *secp256k1fx.TransferOutput
+// or a stakeable locked *secp256k1fx.TransferOutput
 func OutputsFromTxOuts(
 	txID string,
 	outs []*avax.TransferableOutput,
@@ -20,7 +27,7 @@ func OutputsFromTxOuts(
 ) ([]Output, error) {
 	txOuts := make([]Output, 0, len(outs))
 	for outi, cout := range outs {
-		dbOuts, err := CreateTransferableOutputs(txID, uint32(outi+startIndex), cout.Out)
+		dbOuts, err := CreateTransferableOutputs(txID, uint32(outi+startIndex), cout.AssetID(), cout.Out)
 		if err != nil {
 			return nil, err
 		}
@@ -41,7 +48,7 @@ func OutputsFromUTXO(txID string, utxos []*avax.UTXO, creator OutputCreator) ([]
 			TxID: txID,
 			Idx:  utxo.OutputIndex,
 		}
-		err := UpdateTransferableOutput(dbOut, utxo.Out)
+		err := UpdateTransferableOutput(dbOut, utxo.AssetID(), utxo.Out)
 		if err != nil {
 			return nil, err
 		}
@@ -52,10 +59,10 @@ func OutputsFromUTXO(txID string, utxos []*avax.UTXO, creator OutputCreator) ([]
 
 // Update database output from out provided its type is *secp256k1fx.TransferOutput and the
 // number of addresses is 1. Error is returned if these two conditions are not met.
-func UpdateTransferableOutput(dbOut *database.TxOutput, out verify.State) error {
-	to, ok := out.(*secp256k1fx.TransferOutput)
-	if !ok {
-		return fmt.Errorf("TransferableOutput has unsupported type")
+func UpdateTransferableOutput(dbOut *database.TxOutput, assetID ids.ID, out verify.State) error {
+	to, stakeableLocktime, err := transferOutput(out)
+	if err != nil {
+		return err
 	}
 	if len(to.Addrs) != 1 {
 		return fmt.Errorf("TransferableOutput has 0 or more than one address")
@@ -67,19 +74,22 @@ func UpdateTransferableOutput(dbOut *database.TxOutput, out verify.State) error
 	}
 	dbOut.Amount = to.Amount()
 	dbOut.Address = addr
+	setOutputOwners(dbOut, assetID, &to.OutputOwners, stakeableLocktime)
 	return nil
 }
 
-// Create database outputs from out provided its type is *secp256k1fx.TransferOutput.
-// Note that multiple database outputs are created if there are multiple addresses in out.
+// Create database outputs from out provided its type is *secp256k1fx.TransferOutput or a
+// stakeable locked *secp256k1fx.TransferOutput. Note that multiple database outputs are
+// created if there are multiple addresses in out. They share the same owner group.
 func CreateTransferableOutputs(
 	txID string,
 	idx uint32,
+	assetID ids.ID,
 	out verify.State,
 ) ([]*database.TxOutput, error) {
-	to, ok := out.(*secp256k1fx.TransferOutput)
-	if !ok {
-		return nil, fmt.Errorf("TransferableOutput has unsupported type")
+	to, stakeableLocktime, err := transferOutput(out)
+	if err != nil {
+		return nil, err
 	}
 	dbOuts := make([]*database.TxOutput, len(to.Addrs))
 	for i, addr := range to.Addrs {
@@ -93,31 +103,103 @@ func CreateTransferableOutputs(
 			Amount:  to.Amount(),
 			Address: formattedAddr,
 		}
+		setOutputOwners(dbOut, assetID, &to.OutputOwners, stakeableLocktime)
 		dbOuts[i] = dbOut
 	}
 	return dbOuts, nil
 }
 
-// Return address from Owner interface provided its type is *secp256k1fx.OutputOwners and the
-// number of addresses is 1. Error is returned if these two conditions are not met.
+// Return the transfer output and the stakeable lock time (0 if out is not stakeable locked)
+func transferOutput(out verify.State) (*secp256k1fx.TransferOutput, uint64, error) {
+	var stakeableLocktime uint64
+	if lockOut, ok := out.(*stakeable.LockOut); ok {
+		stakeableLocktime = lockOut.Locktime
+		out = lockOut.TransferableOut
+	}
+	to, ok := out.(*secp256k1fx.TransferOutput)
+	if !ok {
+		return nil, 0, fmt.Errorf("TransferableOutput has unsupported type")
+	}
+	return to, stakeableLocktime, nil
+}
+
+func setOutputOwners(dbOut *database.TxOutput, assetID ids.ID, owners *secp256k1fx.OutputOwners, stakeableLocktime uint64) {
+	dbOut.AssetID = assetID.String()
+	dbOut.Locktime = owners.Locktime
+	dbOut.Threshold = owners.Threshold
+	dbOut.OwnerGroup = OwnerGroupID(owners)
+	dbOut.StakeableLocktime = stakeableLocktime
+}
+
+// Identifier of the set of owners of an output, the hash of the threshold and the sorted
+// owner addresses. Outputs with the same owner group can be spent by the same signers.
+func OwnerGroupID(owners *secp256k1fx.OutputOwners) string {
+	addrs := make([]ids.ShortID, len(owners.Addrs))
+	copy(addrs, owners.Addrs)
+	utils.Sort(addrs)
+
+	bytes := binary.BigEndian.AppendUint32(nil, owners.Threshold)
+	for _, addr := range addrs {
+		bytes = append(bytes, addr.Bytes()...)
+	}
+	return ids.ID(hashing.ComputeHash256Array(bytes)).String()
+}
+
+// Return address from Owner interface provided its type is *secp256k1fx.OutputOwners. Error is
+// returned if the type is not supported. Empty string is returned if the number of addresses is
+// not 1, all owners of staking transactions are stored in the rewards owners table.
 func RewardsOwnerAddress(owner fx.Owner) (string, error) {
 	oo, ok := owner.(*secp256k1fx.OutputOwners)
 	if !ok {
 		return "", fmt.Errorf("rewards owner has unsupported type")
 	}
 	if len(oo.Addrs) != 1 {
-		return "", fmt.Errorf("rewards owner has 0 or more than one address")
+		return "", nil
 	}
 	return chain.FormatAddressBytes(oo.Addrs[0].Bytes())
 }
 
+// Return comma-separated bech32 addresses
+func FormatAddresses(addrs []ids.ShortID) (string, error) {
+	formatted := make([]string, len(addrs))
+	for i, addr := range addrs {
+		var err error
+		formatted[i], err = chain.FormatAddressBytes(addr.Bytes())
+		if err != nil {
+			return "", err
+		}
+	}
+	return strings.Join(formatted, ","), nil
+}
+
 // Create inputs to BaseTx. Note that addresses of inputs are are not set. They should be updated from
 // cached outputs, outputs from the database or outputs from the chain
 func InputsFromTxIns(txID string, ins []*avax.TransferableInput) []UpdatableInput {
+	return InputsFromTxInsAt(txID, ins, 0)
+}
+
+// Create inputs spending utxoIDs with indexes starting at startIndex, e.g., inputs of operations
+// of OperationTx. Amounts of inputs are not known and are set to 0.
+func InputsFromUTXOIDs(txID string, utxoIDs []*avax.UTXOID, startIndex int) []UpdatableInput {
+	txIns := make([]UpdatableInput, len(utxoIDs))
+	for i, utxoID := range utxoIDs {
+		txIns[i] = &updatableInput{
+			InIdx:   uint32(i + startIndex),
+			TxID:    txID,
+			OutTxID: utxoID.TxID.String(),
+			OutIdx:  utxoID.OutputIndex,
+		}
+	}
+	return txIns
+}
+
+// Create inputs with indexes starting at startIndex, e.g., imported inputs of ImportTx
+// following the inputs of its BaseTx
+func InputsFromTxInsAt(txID string, ins []*avax.TransferableInput, startIndex int) []UpdatableInput {
 	txIns := make([]UpdatableInput, len(ins))
 	for ini, in := range ins {
 		txIns[ini] = &updatableInput{
-			InIdx:   uint32(ini),
+			InIdx:   uint32(ini + startIndex),
 			TxID:    txID,
 			Amount:  in.In.Amount(),
 			OutTxID: in.TxID.String(),
@@ -2,6 +2,7 @@ package shared
 
 import (
 	"container/list"
+	"context"
 	"flare-indexer/utils"
 
 	mapset "github.com/deckarep/golang-set/v2"
@@ -10,7 +11,7 @@ import (
 type InputUpdater interface {
 	// Update inputs with addresses. Updater can get outputs from cache, db, chain (indexer, api), ...
 	// Updated inputs should be removed from the list, missing output tx ids are returned
-	UpdateInputs(inputs InputList) (mapset.Set[string], error)
+	UpdateInputs(ctx context.Context, inputs InputList) (mapset.Set[string], error)
 
 	// Put outputs of a transaction to cache -- to avoid updating from chain or database
 	CacheOutputs(outs []Output)
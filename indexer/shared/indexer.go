@@ -1,21 +1,34 @@
 package shared
 
 import (
+	"context"
 	"flare-indexer/database"
 	"flare-indexer/indexer/config"
 	"flare-indexer/logger"
 	"flare-indexer/utils/chain"
+	"fmt"
 	"time"
 
 	"github.com/ava-labs/avalanchego/indexer"
+	"github.com/pkg/errors"
 	"gorm.io/gorm"
 )
 
+var (
+	// Returned when the node's index does not continue the history stored in the database
+	ErrChainMismatch = errors.New("node index does not match indexed data")
+)
+
 type ContainerBatchIndexer interface {
 	Reset(containerLen int)
-	AddContainer(index uint64, container indexer.Container) error
-	ProcessBatch() error
+	AddContainer(ctx context.Context, index uint64, container indexer.Container) error
+	ProcessBatch(ctx context.Context) error
 	PersistEntities(db *gorm.DB) error
+
+	// Returns the ID of the persisted container with the given index (the last indexed one),
+	// or an empty string if it is not stored. Container is the container fetched from the
+	// chain at index.
+	PersistedContainerID(db *gorm.DB, index uint64, container *indexer.Container) (string, error)
 }
 
 type ChainIndexerBase struct {
@@ -26,12 +39,25 @@ type ChainIndexerBase struct {
 	Client chain.IndexerClient
 	Config config.IndexerConfig
 
+	// Last index to be indexed, 0 for no limit (used by backfill workers)
+	EndIndex uint64
+
 	BatchIndexer ContainerBatchIndexer
 
+	// Archive of fetched containers, nil if archiving is disabled
+	Archive *chain.ContainerArchive
+
 	metrics *metrics
 }
 
-func (ci *ChainIndexerBase) IndexBatch() error {
+// Containers fetched from the chain starting at index from
+type containerBatch struct {
+	from       uint64
+	lastIndex  uint64 // Last accepted index on the chain at the time of fetching
+	containers []indexer.Container
+}
+
+func (ci *ChainIndexerBase) IndexBatch(ctx context.Context) error {
 	startTime := time.Now()
 
 	// Get current state of tx indexer from db
@@ -40,25 +66,70 @@ func (ci *ChainIndexerBase) IndexBatch() error {
 		return err
 	}
 
-	var nextIndex uint64
-	if currentState.NextDBIndex < ci.Config.StartIndex {
-		nextIndex = ci.Config.StartIndex
+	batch, err := ci.fetchBatch(ctx, ci.nextIndex(&currentState))
+	if err != nil {
+		return err
+	}
+	return ci.indexFetchedBatch(ctx, startTime, &currentState, batch)
+}
+
+func (ci *ChainIndexerBase) nextIndex(state *database.State) uint64 {
+	if state.NextDBIndex < ci.Config.StartIndex {
+		return ci.Config.StartIndex
 	} else {
-		nextIndex = currentState.NextDBIndex
+		return state.NextDBIndex
 	}
+}
 
+// Fetch at most BatchSize containers starting at nextIndex. The returned batch has no containers
+// if there are no new containers on the chain.
+func (ci *ChainIndexerBase) fetchBatch(ctx context.Context, nextIndex uint64) (*containerBatch, error) {
 	// Fetch last accepted index on chain
-	_, lastIndex, err := chain.FetchLastAcceptedContainer(ci.Client)
+	_, lastIndex, err := chain.FetchLastAcceptedContainer(ctx, ci.Client)
 	if err != nil {
+		return nil, err
+	}
+	batch := &containerBatch{from: nextIndex, lastIndex: lastIndex}
+	if lastIndex < nextIndex || (ci.EndIndex > 0 && ci.EndIndex < nextIndex) {
+		return batch, nil
+	}
+
+	// Get MaxBatch containers from the chain
+	batchSize := ci.Config.BatchSize
+	if ci.EndIndex > 0 && ci.EndIndex-nextIndex+1 < uint64(batchSize) {
+		batchSize = int(ci.EndIndex - nextIndex + 1)
+	}
+	batch.containers, err = chain.FetchContainerRangeFromIndexer(ctx, ci.Client, nextIndex, batchSize)
+	if err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+// Process and persist a batch of containers. The batch must start at the next index of currentState.
+// Cancelling ctx aborts fetching and processing, but once the batch is processed it is always persisted.
+func (ci *ChainIndexerBase) indexFetchedBatch(ctx context.Context, startTime time.Time, currentState *database.State, batch *containerBatch) error {
+	nextIndex := ci.nextIndex(currentState)
+	if batch.from != nextIndex {
+		return fmt.Errorf("%s indexer received batch starting at index %d, expected %d", ci.IndexerName, batch.from, nextIndex)
+	}
+	lastIndex := batch.lastIndex
+	if err := ctx.Err(); err != nil {
 		return err
 	}
-	if lastIndex < nextIndex {
+
+	if nextIndex > ci.Config.StartIndex {
+		if err := ci.verifyLastIndexed(ctx, nextIndex-1, lastIndex); err != nil {
+			return err
+		}
+	}
+	if len(batch.containers) == 0 {
 		// Nothing to do; no new containers
 		logger.Debug("Nothing to do. Last index %d < next to process %d", lastIndex, nextIndex)
 
 		// Update time of last run (for other clients to know that the indexer is running)
 		currentState.UpdateTime()
-		if err := database.UpdateState(ci.DB, &currentState); err != nil {
+		if err := database.UpdateState(ci.DB, currentState); err != nil {
 			return err
 		}
 
@@ -69,22 +140,23 @@ func (ci *ChainIndexerBase) IndexBatch() error {
 		return nil
 	}
 
-	// Get MaxBatch containers from the chain
-	containers, err := chain.FetchContainerRangeFromIndexer(ci.Client, nextIndex, ci.Config.BatchSize)
+	lastProcessedIndex, err := ci.ProcessContainers(ctx, nextIndex, batch.containers)
 	if err != nil {
 		return err
 	}
 
-	lastProcessedIndex, err := ci.ProcessContainers(nextIndex, containers)
-	if err != nil {
-		return err
+	// Archive before persisting, so that the archive has no gaps if the batch is repeated
+	if ci.Archive != nil {
+		if err := ci.Archive.Write(nextIndex, batch.containers); err != nil {
+			return fmt.Errorf("%s indexer failed to archive containers: %w", ci.IndexerName, err)
+		}
 	}
 
 	err = database.DoInTransaction(ci.DB,
 		func(db *gorm.DB) error { return ci.BatchIndexer.PersistEntities(db) },
 		func(db *gorm.DB) error {
 			currentState.Update(lastProcessedIndex+1, lastIndex)
-			return database.UpdateState(db, &currentState)
+			return database.UpdateState(db, currentState)
 		},
 	)
 	if err != nil {
@@ -102,20 +174,82 @@ func (ci *ChainIndexerBase) IndexBatch() error {
 	return nil
 }
 
-func (ci *ChainIndexerBase) ProcessContainers(nextIndex uint64, containers []indexer.Container) (uint64, error) {
+// Check that the container at index (the last one we have indexed) is still the same on the node.
+// If it is not, a diagnostic record is written and ErrChainMismatch is returned. A last accepted
+// index below index is first re-checked against the best node if the client uses several nodes.
+func (ci *ChainIndexerBase) verifyLastIndexed(ctx context.Context, index uint64, lastChainIndex uint64) error {
+	diagnostic := &database.IndexerDiagnostic{
+		StateName:      ci.StateName,
+		Index:          index,
+		LastChainIndex: lastChainIndex,
+		Timestamp:      time.Now(),
+	}
+	if lastChainIndex < index {
+		bestIndex, ok, err := chain.FetchBestLastAcceptedIndex(ctx, ci.Client)
+		if err != nil {
+			return err
+		}
+		if ok && bestIndex >= index {
+			// Index was reported by a node that lags behind, the batch is repeated with the best node
+			return fmt.Errorf("%s indexer received last accepted index %d below last indexed index %d from a lagging node",
+				ci.IndexerName, lastChainIndex, index)
+		}
+		diagnostic.Type = database.IndexerDiagnosticIndexRewind
+		return ci.reportMismatch(diagnostic)
+	}
+
+	ctx, cancelCtx := context.WithTimeout(ctx, chain.IndexerTimeout)
+	defer cancelCtx()
+	container, err := ci.Client.GetContainerByIndex(ctx, index)
+	if err != nil {
+		return err
+	}
+	storedID, err := ci.BatchIndexer.PersistedContainerID(ci.DB, index, &container)
+	if err != nil {
+		return err
+	}
+	if storedID != container.ID.String() {
+		diagnostic.Type = database.IndexerDiagnosticContainerMismatch
+		diagnostic.StoredID = storedID
+		diagnostic.ChainID = container.ID.String()
+		return ci.reportMismatch(diagnostic)
+	}
+	return nil
+}
+
+func (ci *ChainIndexerBase) reportMismatch(diagnostic *database.IndexerDiagnostic) error {
+	if err := database.CreateIndexerDiagnostic(ci.DB, diagnostic); err != nil {
+		logger.Error("%s indexer failed to write diagnostic record: %v", ci.IndexerName, err)
+	}
+	ci.SetStatus(HealthStatusChainMismatch)
+	switch diagnostic.Type {
+	case database.IndexerDiagnosticIndexRewind:
+		return errors.Wrapf(ErrChainMismatch, "last accepted index %d is below last indexed index %d",
+			diagnostic.LastChainIndex, diagnostic.Index)
+	default:
+		if len(diagnostic.StoredID) == 0 {
+			return errors.Wrapf(ErrChainMismatch, "container at index %d with id %s is not stored",
+				diagnostic.Index, diagnostic.ChainID)
+		}
+		return errors.Wrapf(ErrChainMismatch, "container at index %d has id %s, indexed id is %s",
+			diagnostic.Index, diagnostic.ChainID, diagnostic.StoredID)
+	}
+}
+
+func (ci *ChainIndexerBase) ProcessContainers(ctx context.Context, nextIndex uint64, containers []indexer.Container) (uint64, error) {
 	ci.BatchIndexer.Reset(len(containers))
 
 	var index uint64
 	for i, container := range containers {
 		index = nextIndex + uint64(i)
 
-		err := ci.BatchIndexer.AddContainer(index, container)
+		err := ci.BatchIndexer.AddContainer(ctx, index, container)
 		if err != nil {
 			return 0, err
 		}
 	}
 
-	err := ci.BatchIndexer.ProcessBatch()
+	err := ci.BatchIndexer.ProcessBatch(ctx)
 	if err != nil {
 		return 0, err
 	}
@@ -123,15 +257,37 @@ func (ci *ChainIndexerBase) ProcessContainers(nextIndex uint64, containers []ind
 	return index, nil
 }
 
-func (ci *ChainIndexerBase) Run() {
+// Run the indexer until ctx is cancelled. The batch in progress at that time is either
+// persisted completely or not at all.
+func (ci *ChainIndexerBase) Run(ctx context.Context) {
 	if !ci.Config.Enabled {
 		logger.Debug("%s indexer is disabled", ci.IndexerName)
 		ci.SetStatus(HealthStatusOk)
 		return
 	}
+	if ci.Config.PrefetchDepth > 0 {
+		ci.runPipelined(ctx)
+		return
+	}
 	ticker := time.NewTicker(ci.Config.Timeout)
-	for range ticker.C {
-		err := ci.IndexBatch()
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("%s indexer stopped", ci.IndexerName)
+			return
+		case <-ticker.C:
+		}
+
+		err := ci.IndexBatch(ctx)
+		if err != nil && ctx.Err() != nil {
+			logger.Warn("%s indexer interrupted, current batch was not persisted: %v", ci.IndexerName, err)
+			return
+		}
+		if errors.Is(err, ErrChainMismatch) {
+			logger.Error("%s indexer stopped: %v", ci.IndexerName, err)
+			return
+		}
 		if err != nil {
 			logger.Error("%s indexer error %v", ci.IndexerName, err)
 			ci.SetStatus(HealthStatusError)
@@ -139,6 +295,22 @@ func (ci *ChainIndexerBase) Run() {
 	}
 }
 
+// Return the client containers should be read from and the archive they should be written to:
+// the archive is read instead of client if ArchiveReplay is set, otherwise containers read from
+// client are archived if ArchiveDir is set.
+func NewArchiveClient(cfg config.IndexerConfig, client chain.IndexerClient) (chain.IndexerClient, *chain.ContainerArchive, error) {
+	switch {
+	case len(cfg.ArchiveDir) == 0:
+		return client, nil, nil
+	case cfg.ArchiveReplay:
+		fileClient, err := chain.NewFileIndexerClient(cfg.ArchiveDir)
+		return fileClient, nil, err
+	default:
+		archive, err := chain.NewContainerArchive(cfg.ArchiveDir, cfg.ArchiveSegmentSize)
+		return client, archive, err
+	}
+}
+
 func (ci *ChainIndexerBase) InitMetrics(namespace string) {
 	ci.metrics = newMetrics(namespace)
 }
@@ -1,6 +1,7 @@
 package shared
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/ava-labs/avalanchego/vms/components/avax"
@@ -53,9 +54,9 @@ func (iox *InputOutputIndexer) Add(outs []Output, ins []UpdatableInput) {
 	iox.ins = append(iox.ins, ins...)
 }
 
-func (iox *InputOutputIndexer) UpdateInputs(inputs []UpdatableInput) error {
+func (iox *InputOutputIndexer) UpdateInputs(ctx context.Context, inputs []UpdatableInput) error {
 	list := NewInputList(inputs)
-	notUpdated, err := iox.inUpdater.UpdateInputs(list)
+	notUpdated, err := iox.inUpdater.UpdateInputs(ctx, list)
 	if err != nil {
 		return err
 	}
@@ -65,9 +66,9 @@ func (iox *InputOutputIndexer) UpdateInputs(inputs []UpdatableInput) error {
 	return nil
 }
 
-func (iox *InputOutputIndexer) ProcessBatch() error {
+func (iox *InputOutputIndexer) ProcessBatch(ctx context.Context) error {
 	iox.inUpdater.CacheOutputs(iox.outs)
-	return iox.UpdateInputs(iox.ins)
+	return iox.UpdateInputs(ctx, iox.ins)
 }
 
 func (iox *InputOutputIndexer) GetIns() []UpdatableInput {
@@ -15,10 +15,11 @@ import (
 type HealthStatus int
 
 const (
-	HealthStatusInitializing HealthStatus = 0 // Default prometheus Gauge value, thus it indicates that it was not updated yet
-	HealthStatusOk           HealthStatus = 1
-	HealthStatusError        HealthStatus = -1
-	HealthStatusSyncing      HealthStatus = -2
+	HealthStatusInitializing  HealthStatus = 0 // Default prometheus Gauge value, thus it indicates that it was not updated yet
+	HealthStatusOk            HealthStatus = 1
+	HealthStatusError         HealthStatus = -1
+	HealthStatusSyncing       HealthStatus = -2
+	HealthStatusChainMismatch HealthStatus = -3 // Node index does not match indexed data, indexer is stopped
 )
 
 type MetricsBase struct {
@@ -31,7 +32,7 @@ func NewMetricsBase(namespace string) *MetricsBase {
 		status: promauto.NewGauge(prometheus.GaugeOpts{
 			Namespace: namespace,
 			Name:      "health_status",
-			Help:      "Status of the client (0 - initializing, 1 - ok, -1 - error, -2 - syncing)",
+			Help:      "Status of the client (0 - initializing, 1 - ok, -1 - error, -2 - syncing, -3 - chain mismatch)",
 		}),
 	}
 	m.status.Set(float64(HealthStatusInitializing))
@@ -0,0 +1,100 @@
+package shared
+
+import (
+	"context"
+	"flare-indexer/database"
+	"flare-indexer/logger"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Result of fetching a batch in the prefetch goroutine
+type prefetchResult struct {
+	batch *containerBatch
+	err   error
+}
+
+// Run the indexer in pipelined mode: container ranges are fetched ahead into a queue
+// of at most PrefetchDepth batches while the previous batches are processed and persisted.
+// Batches are indexed strictly in order, the pipeline is restarted from the database state
+// after every error. Returns when ctx is cancelled.
+func (ci *ChainIndexerBase) runPipelined(ctx context.Context) {
+	for {
+		err := ci.indexPipelined(ctx)
+		if ctx.Err() != nil {
+			if err != nil {
+				logger.Warn("%s indexer interrupted, current batch was not persisted: %v", ci.IndexerName, err)
+			} else {
+				logger.Info("%s indexer stopped", ci.IndexerName)
+			}
+			return
+		}
+		if errors.Is(err, ErrChainMismatch) {
+			logger.Error("%s indexer stopped: %v", ci.IndexerName, err)
+			return
+		}
+		if err != nil {
+			logger.Error("%s indexer error %v", ci.IndexerName, err)
+			ci.SetStatus(HealthStatusError)
+		}
+		select {
+		case <-time.After(ci.Config.Timeout):
+		case <-ctx.Done():
+		}
+	}
+}
+
+// Index batches delivered by the prefetch goroutine until an error occurs or ctx is cancelled
+func (ci *ChainIndexerBase) indexPipelined(parentCtx context.Context) error {
+	currentState, err := database.FetchState(ci.DB, ci.StateName)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(parentCtx)
+	defer cancel()
+
+	queue := make(chan prefetchResult, ci.Config.PrefetchDepth)
+	go ci.prefetch(ctx, ci.nextIndex(&currentState), queue)
+
+	for result := range queue {
+		if result.err != nil {
+			return result.err
+		}
+		err := ci.indexFetchedBatch(ctx, time.Now(), &currentState, result.batch)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Fetch container ranges starting at nextIndex and put them to queue. If there are no new
+// containers, an empty batch is queued (to update the state) and fetching pauses for Timeout.
+// The queue is closed after the first error or when ctx is cancelled.
+func (ci *ChainIndexerBase) prefetch(ctx context.Context, nextIndex uint64, queue chan<- prefetchResult) {
+	defer close(queue)
+
+	for {
+		batch, err := ci.fetchBatch(ctx, nextIndex)
+		select {
+		case queue <- prefetchResult{batch: batch, err: err}:
+		case <-ctx.Done():
+			return
+		}
+		if err != nil {
+			return
+		}
+
+		if len(batch.containers) > 0 {
+			nextIndex += uint64(len(batch.containers))
+			continue
+		}
+		select {
+		case <-time.After(ci.Config.Timeout):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
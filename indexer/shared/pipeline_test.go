@@ -0,0 +1,212 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"flare-indexer/database"
+	"flare-indexer/indexer/config"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/indexer"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+const pipelineTestStateName = "pipeline_test"
+
+// Indexer client serving containers 0..len(containers)-1, optionally failing the first range
+// request starting at failFrom
+type stubIndexerClient struct {
+	mu         sync.Mutex
+	containers []indexer.Container
+	failFrom   *uint64
+	failures   int
+}
+
+func newStubIndexerClient(n int) *stubIndexerClient {
+	c := &stubIndexerClient{}
+	for i := 0; i < n; i++ {
+		c.containers = append(c.containers, indexer.Container{ID: ids.ID{byte(i + 1)}, Bytes: []byte{byte(i)}})
+	}
+	return c
+}
+
+func (c *stubIndexerClient) GetContainerRange(ctx context.Context, from uint64, numToFetch int) ([]indexer.Container, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.failFrom != nil && *c.failFrom == from {
+		c.failFrom = nil
+		c.failures++
+		return nil, errors.New("fetch failed")
+	}
+	to := from + uint64(numToFetch)
+	if to > uint64(len(c.containers)) {
+		to = uint64(len(c.containers))
+	}
+	return append([]indexer.Container(nil), c.containers[from:to]...), nil
+}
+
+func (c *stubIndexerClient) GetLastAccepted(ctx context.Context) (indexer.Container, uint64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	last := uint64(len(c.containers) - 1)
+	return c.containers[last], last, nil
+}
+
+func (c *stubIndexerClient) GetContainerByIndex(ctx context.Context, index uint64) (indexer.Container, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.containers[index], nil
+}
+
+func (c *stubIndexerClient) GetIndex(ctx context.Context, id ids.ID) (uint64, error) {
+	return 0, errors.New("not implemented")
+}
+
+// Batch indexer recording the indexes of persisted containers
+type stubBatchIndexer struct {
+	batch     []uint64
+	batchIDs  map[uint64]string
+	persisted []uint64
+	ids       map[uint64]string
+}
+
+func (xi *stubBatchIndexer) Reset(containerLen int) {
+	xi.batch = nil
+	xi.batchIDs = make(map[uint64]string)
+}
+
+func (xi *stubBatchIndexer) AddContainer(ctx context.Context, index uint64, container indexer.Container) error {
+	xi.batch = append(xi.batch, index)
+	xi.batchIDs[index] = container.ID.String()
+	return nil
+}
+
+func (xi *stubBatchIndexer) ProcessBatch(ctx context.Context) error {
+	return nil
+}
+
+func (xi *stubBatchIndexer) PersistEntities(db *gorm.DB) error {
+	xi.persisted = append(xi.persisted, xi.batch...)
+	for index, id := range xi.batchIDs {
+		xi.ids[index] = id
+	}
+	return nil
+}
+
+func (xi *stubBatchIndexer) PersistedContainerID(db *gorm.DB, index uint64, container *indexer.Container) (string, error) {
+	return xi.ids[index], nil
+}
+
+func createPipelineTestIndexer(t *testing.T, client *stubIndexerClient) (*ChainIndexerBase, *stubBatchIndexer) {
+	cfg := database.TestDBConfig("flare_indexer_indexer")
+	db, err := database.ConnectAndInitializeTestDB(&cfg, true)
+	require.NoError(t, err)
+	require.NoError(t, database.CreateState(db, &database.State{Name: pipelineTestStateName}))
+
+	batchIndexer := &stubBatchIndexer{ids: make(map[uint64]string)}
+	return &ChainIndexerBase{
+		StateName:   pipelineTestStateName,
+		IndexerName: "Pipeline Test",
+		DB:          db,
+		Client:      client,
+		Config: config.IndexerConfig{
+			Enabled:       true,
+			Timeout:       10 * time.Millisecond,
+			BatchSize:     3,
+			PrefetchDepth: 2,
+		},
+		BatchIndexer: batchIndexer,
+	}, batchIndexer
+}
+
+// Run the pipeline until all containers of client are indexed, then cancel it and wait for it to return
+func runPipelineToEnd(t *testing.T, ci *ChainIndexerBase, client *stubIndexerClient) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		ci.runPipelined(ctx)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool {
+		state, err := database.FetchState(ci.DB, ci.StateName)
+		return err == nil && state.NextDBIndex == uint64(len(client.containers))
+	}, 5*time.Second, 5*time.Millisecond)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("pipeline did not stop after cancellation")
+	}
+}
+
+func expectedIndexes(n int) []uint64 {
+	indexes := make([]uint64, n)
+	for i := range indexes {
+		indexes[i] = uint64(i)
+	}
+	return indexes
+}
+
+func TestPipelineInOrder(t *testing.T) {
+	client := newStubIndexerClient(10)
+	ci, batchIndexer := createPipelineTestIndexer(t, client)
+
+	runPipelineToEnd(t, ci, client)
+
+	require.Equal(t, expectedIndexes(10), batchIndexer.persisted)
+	state, err := database.FetchState(ci.DB, ci.StateName)
+	require.NoError(t, err)
+	require.Equal(t, uint64(9), state.LastChainIndex)
+}
+
+func TestPipelineRestartAfterFetchError(t *testing.T) {
+	client := newStubIndexerClient(10)
+	failFrom := uint64(6)
+	client.failFrom = &failFrom
+	ci, batchIndexer := createPipelineTestIndexer(t, client)
+
+	runPipelineToEnd(t, ci, client)
+
+	require.Equal(t, 1, client.failures)
+	require.Equal(t, expectedIndexes(10), batchIndexer.persisted)
+}
+
+func TestPrefetchStopsOnCancel(t *testing.T) {
+	client := newStubIndexerClient(10)
+	ci, _ := createPipelineTestIndexer(t, client)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	queue := make(chan prefetchResult, ci.Config.PrefetchDepth)
+	done := make(chan struct{})
+	go func() {
+		ci.prefetch(ctx, 0, queue)
+		close(done)
+	}()
+
+	// The goroutine blocks on the full queue until it is cancelled
+	require.Eventually(t, func() bool { return len(queue) == cap(queue) }, 5*time.Second, 5*time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("prefetch did not stop after cancellation")
+	}
+
+	// Queued batches are in order, then the queue is closed
+	var from uint64
+	for result := range queue {
+		require.NoError(t, result.err)
+		require.Equal(t, from, result.batch.from)
+		from += uint64(len(result.batch.containers))
+	}
+	require.Equal(t, uint64(ci.Config.PrefetchDepth*ci.Config.BatchSize), from)
+}
@@ -1,8 +1,9 @@
 package xchain
 
 import (
+	"context"
 	"flare-indexer/database"
-	"flare-indexer/indexer/context"
+	indexerctx "flare-indexer/indexer/context"
 	"flare-indexer/indexer/shared"
 	"flare-indexer/logger"
 	"flare-indexer/utils"
@@ -13,6 +14,7 @@ import (
 	"github.com/ava-labs/avalanchego/indexer"
 	"github.com/ava-labs/avalanchego/snow/engine/avalanche/vertex"
 	"github.com/ava-labs/avalanchego/vms/avm/txs"
+	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
 	"github.com/ava-labs/avalanchego/wallet/chain/x/builder"
 	"gorm.io/gorm"
 )
@@ -25,10 +27,11 @@ type txBatchIndexer struct {
 	inOutIndexer *shared.InputOutputIndexer
 	newTxs       []*database.XChainTx
 	newVertices  []*database.XChainVtx
+	newAssets    []*database.XChainAsset
 }
 
 func NewXChainBatchIndexer(
-	ctx context.IndexerContext,
+	ctx indexerctx.IndexerContext,
 	client chain.IndexerClient,
 	txClient chain.IndexerClient,
 ) *txBatchIndexer {
@@ -45,78 +48,173 @@ func NewXChainBatchIndexer(
 func (xi *txBatchIndexer) Reset(containerLen int) {
 	xi.newVertices = make([]*database.XChainVtx, 0, containerLen)
 	xi.newTxs = make([]*database.XChainTx, 0, 5*containerLen) // approximate
+	xi.newAssets = nil
 	xi.inOutIndexer.Reset(containerLen)
 }
 
-func (xi *txBatchIndexer) AddContainer(index uint64, container indexer.Container) error {
+func (xi *txBatchIndexer) AddContainer(ctx context.Context, index uint64, container indexer.Container) error {
 	vtx, err := vertex.Parse(container.Bytes)
 	if err != nil {
 		return err
 	}
-	if len(vtx.ParentIDs()) > 1 {
+	// The stop vertex may have multiple parents, it contains no transactions
+	if len(vtx.ParentIDs()) > 1 && !vtx.StopVertex() {
 		return fmt.Errorf("only one vertex parent is expected, got %d for id %s at height %d",
 			len(vtx.ParentIDs()), vtx.ID().String(), vtx.Height())
 	}
 	for _, txBytes := range vtx.Txs() {
-		err = xi.addTransaction(vtx.Height(), txBytes)
+		tx, err := builder.Parser.ParseGenesisTx(txBytes)
+		if err != nil {
+			return err
+		}
+		err = xi.addTransaction(tx, &database.XChainTx{VtxHeight: vtx.Height()})
 		if err != nil {
 			return err
 		}
 	}
 
+	var parentID string
+	if len(vtx.ParentIDs()) > 0 {
+		parentID = vtx.ParentIDs()[0].String()
+	}
 	xi.newVertices = append(xi.newVertices, &database.XChainVtx{
-		VtxID:     vtx.ID().String(),
-		ParentID:  vtx.ParentIDs()[0].String(),
-		VtxIndex:  index,
-		Height:    vtx.Height(),
-		Timestamp: time.Unix(0, container.Timestamp),
+		VtxID:      vtx.ID().String(),
+		ParentID:   parentID,
+		VtxIndex:   index,
+		Height:     vtx.Height(),
+		Timestamp:  time.Unix(0, container.Timestamp),
+		StopVertex: vtx.StopVertex(),
 	})
 	return nil
 }
 
-func (xi *txBatchIndexer) addTransaction(vtxHeight uint64, txBytes []byte) error {
-	tx, err := builder.Parser.ParseGenesisTx(txBytes)
-	if err != nil {
-		return err
-	}
-
+// Add transaction tx, dbTx has the vertex or block height set
+func (xi *txBatchIndexer) addTransaction(tx *txs.Tx, dbTx *database.XChainTx) error {
 	switch unsignedTx := tx.Unsigned.(type) {
 	case *txs.BaseTx:
-		err := xi.addBaseTx(tx.ID().String(), vtxHeight, unsignedTx, database.XChainBaseTx, txBytes)
-		if err != nil {
-			return err
-		}
+		return xi.addBaseTx(tx, dbTx, unsignedTx, database.XChainBaseTx)
 	case *txs.ImportTx:
-		err := xi.addBaseTx(tx.ID().String(), vtxHeight, &unsignedTx.BaseTx, database.XChainImportTx, txBytes)
+		dbTx.ChainID = unsignedTx.SourceChain.String()
+		return xi.addBaseTx(tx, dbTx, &unsignedTx.BaseTx, database.XChainImportTx)
+	case *txs.ExportTx:
+		dbTx.ChainID = unsignedTx.DestinationChain.String()
+		return xi.addBaseTx(tx, dbTx, &unsignedTx.BaseTx, database.XChainExportTx)
+	case *txs.CreateAssetTx:
+		xi.newAssets = append(xi.newAssets, &database.XChainAsset{
+			AssetID:      tx.ID().String(),
+			Name:         unsignedTx.Name,
+			Symbol:       unsignedTx.Symbol,
+			Denomination: unsignedTx.Denomination,
+		})
+		return xi.addBaseTx(tx, dbTx, &unsignedTx.BaseTx, database.XChainCreateAssetTx)
+	case *txs.OperationTx:
+		err := xi.addBaseTx(tx, dbTx, &unsignedTx.BaseTx, database.XChainOperationTx)
 		if err != nil {
 			return err
 		}
+		xi.inOutIndexer.Add(nil, operationInputs(dbTx.TxID, unsignedTx))
+		return nil
 	default:
 		logger.Warn("Transaction with id '%s' is NOT indexed, type is %T", tx.ID().String(), unsignedTx)
 	}
 	return nil
 }
 
-func (xi *txBatchIndexer) ProcessBatch() error {
-	return xi.inOutIndexer.ProcessBatch()
+func (xi *txBatchIndexer) ProcessBatch(ctx context.Context) error {
+	return xi.inOutIndexer.ProcessBatch(ctx)
+}
+
+func (xi *txBatchIndexer) PersistedContainerID(db *gorm.DB, index uint64, container *indexer.Container) (string, error) {
+	return database.FetchXChainVtxIDAtIndex(db, index)
 }
 
 func (xi *txBatchIndexer) addBaseTx(
-	txID string,
-	VtxHeight uint64,
+	tx *txs.Tx,
+	dbTx *database.XChainTx,
 	baseTx *txs.BaseTx,
 	txType database.XChainTxType,
-	bytes []byte,
 ) error {
-	tx := &database.XChainTx{}
-	tx.TxID = txID
-	tx.VtxHeight = VtxHeight
-	tx.Type = txType
-	tx.Memo = string(baseTx.Memo)
-	tx.Bytes = bytes
-
-	xi.newTxs = append(xi.newTxs, tx)
-	return xi.inOutIndexer.AddNewFromBaseTx(tx.TxID, &baseTx.BaseTx, XChainInputOutputCreator)
+	dbTx.TxID = tx.ID().String()
+	dbTx.Type = txType
+	dbTx.Memo = string(baseTx.Memo)
+	dbTx.Bytes = tx.Bytes()
+
+	outs, err := outputsFromTx(tx)
+	if err != nil {
+		return err
+	}
+	xi.newTxs = append(xi.newTxs, dbTx)
+	xi.inOutIndexer.Add(outs, shared.InputsFromTxIns(dbTx.TxID, baseTx.Ins))
+	return nil
+}
+
+// Inputs spending outputs consumed by operations of tx, indexed after the inputs of its BaseTx
+func operationInputs(txID string, tx *txs.OperationTx) []shared.UpdatableInput {
+	var ins []shared.UpdatableInput
+	for _, op := range tx.Ops {
+		ins = append(ins, shared.InputsFromUTXOIDs(txID, op.UTXOIDs, len(tx.Ins)+len(ins))...)
+	}
+	return ins
+}
+
+// Outputs of tx, including initial states of CreateAssetTx, outputs of operations and outputs
+// exported by ExportTx. Only secp256k1fx transfer outputs are indexed, mint and NFT outputs
+// are skipped. Imported inputs are not indexed.
+func outputsFromTx(tx *txs.Tx) ([]shared.Output, error) {
+	txID := tx.ID().String()
+	var outs []shared.Output
+	for _, utxo := range tx.UTXOs() {
+		if _, ok := utxo.Out.(*secp256k1fx.TransferOutput); !ok {
+			continue
+		}
+		dbOuts, err := shared.CreateTransferableOutputs(txID, utxo.OutputIndex, utxo.AssetID(), utxo.Out)
+		if err != nil {
+			return nil, err
+		}
+		for _, dbOut := range dbOuts {
+			outs = append(outs, XChainInputOutputCreator.CreateOutput(dbOut))
+		}
+	}
+	if exportTx, ok := tx.Unsigned.(*txs.ExportTx); ok {
+		exportedOuts, err := shared.OutputsFromTxOuts(txID, exportTx.ExportedOuts, len(exportTx.Outs), XChainExportedOutputCreator)
+		if err != nil {
+			return nil, err
+		}
+		outs = append(outs, exportedOuts...)
+	}
+	return outs, nil
+}
+
+// Outputs of tx used to set addresses of inputs: indexed outputs and outputs which are not
+// indexed but have owners (mint and NFT outputs consumed by operations). Outputs which are not
+// indexed have only the address set.
+func ownedOutputsFromTx(tx *txs.Tx) ([]shared.Output, error) {
+	outs, err := outputsFromTx(tx)
+	if err != nil {
+		return nil, err
+	}
+	txID := tx.ID().String()
+	for _, utxo := range tx.UTXOs() {
+		if _, ok := utxo.Out.(*secp256k1fx.TransferOutput); ok {
+			continue
+		}
+		owned, ok := utxo.Out.(interface{ Addresses() [][]byte })
+		if !ok {
+			continue
+		}
+		for _, addr := range owned.Addresses() {
+			formattedAddr, err := chain.FormatAddressBytes(addr)
+			if err != nil {
+				return nil, err
+			}
+			outs = append(outs, XChainInputOutputCreator.CreateOutput(&database.TxOutput{
+				TxID:    txID,
+				Idx:     utxo.OutputIndex,
+				Address: formattedAddr,
+			}))
+		}
+	}
+	return outs, nil
 }
 
 // Persist all entities
@@ -132,5 +230,9 @@ func (i *txBatchIndexer) PersistEntities(db *gorm.DB) error {
 	if err != nil {
 		return err
 	}
+	err = database.CreateXChainAssets(db, i.newAssets)
+	if err != nil {
+		return err
+	}
 	return database.CreateXChainEntities(db, i.newVertices, i.newTxs, ins, outs)
 }
@@ -0,0 +1,159 @@
+package xchain
+
+import (
+	"flare-indexer/database"
+	"flare-indexer/indexer/shared"
+	"flare-indexer/utils/chain"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/vms/avm/txs"
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+	"github.com/ava-labs/avalanchego/vms/components/verify"
+	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+	"github.com/ava-labs/avalanchego/wallet/chain/x/builder"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestOutput(amount uint64) *secp256k1fx.TransferOutput {
+	return &secp256k1fx.TransferOutput{
+		Amt:          amount,
+		OutputOwners: secp256k1fx.OutputOwners{Threshold: 1, Addrs: []ids.ShortID{ids.GenerateTestShortID()}},
+	}
+}
+
+func newTestTransferableOutput(amount uint64) *avax.TransferableOutput {
+	return &avax.TransferableOutput{Asset: avax.Asset{ID: ids.GenerateTestID()}, Out: newTestOutput(amount)}
+}
+
+func TestExportTx(t *testing.T) {
+	chain.AddressHRP = "costwo"
+	destinationChain := ids.GenerateTestID()
+	tx := &txs.Tx{Unsigned: &txs.ExportTx{
+		BaseTx:           txs.BaseTx{BaseTx: avax.BaseTx{Outs: []*avax.TransferableOutput{newTestTransferableOutput(10)}}},
+		DestinationChain: destinationChain,
+		ExportedOuts:     []*avax.TransferableOutput{newTestTransferableOutput(20), newTestTransferableOutput(30)},
+	}}
+	require.NoError(t, tx.Initialize(builder.Parser.Codec()))
+
+	xi := newTestBlockBatchIndexer()
+	require.NoError(t, xi.addTransaction(tx, &database.XChainTx{}))
+
+	require.Len(t, xi.newTxs, 1)
+	require.Equal(t, database.XChainExportTx, xi.newTxs[0].Type)
+	require.Equal(t, destinationChain.String(), xi.newTxs[0].ChainID)
+	require.Equal(t, tx.Unsigned.(*txs.ExportTx).ExportedOuts[1].AssetID().String(),
+		xi.inOutIndexer.GetNewOuts()[2].(*database.XChainTxOutput).AssetID)
+
+	outs := xi.inOutIndexer.GetNewOuts()
+	require.Len(t, outs, 3)
+	for i, out := range outs {
+		dbOut := out.(*database.XChainTxOutput)
+		require.Equal(t, uint32(i), dbOut.Idx)
+		require.Equal(t, uint64(10*(i+1)), dbOut.Amount)
+		require.Equal(t, uint32(1), dbOut.Threshold)
+		require.NotEmpty(t, dbOut.OwnerGroup)
+		if i == 0 {
+			require.Equal(t, database.XChainDefaultOutput, dbOut.Type)
+		} else {
+			require.Equal(t, database.XChainExportedOutput, dbOut.Type)
+		}
+	}
+}
+
+func TestCreateAssetTx(t *testing.T) {
+	chain.AddressHRP = "costwo"
+	tx := &txs.Tx{Unsigned: &txs.CreateAssetTx{
+		BaseTx:       txs.BaseTx{BaseTx: avax.BaseTx{Outs: []*avax.TransferableOutput{newTestTransferableOutput(10)}}},
+		Name:         "Test asset",
+		Symbol:       "TST",
+		Denomination: 9,
+		States: []*txs.InitialState{{
+			Outs: []verify.State{
+				newTestOutput(100),
+				&secp256k1fx.MintOutput{OutputOwners: secp256k1fx.OutputOwners{Threshold: 1}},
+			},
+		}},
+	}}
+	require.NoError(t, tx.Initialize(builder.Parser.Codec()))
+
+	xi := newTestBlockBatchIndexer()
+	require.NoError(t, xi.addTransaction(tx, &database.XChainTx{}))
+
+	require.Len(t, xi.newTxs, 1)
+	require.Equal(t, database.XChainCreateAssetTx, xi.newTxs[0].Type)
+	require.Equal(t, []*database.XChainAsset{{
+		AssetID:      tx.ID().String(),
+		Name:         "Test asset",
+		Symbol:       "TST",
+		Denomination: 9,
+	}}, xi.newAssets)
+
+	// Mint output is not indexed
+	outs := xi.inOutIndexer.GetNewOuts()
+	require.Len(t, outs, 2)
+	require.Equal(t, uint32(1), outs[1].Index())
+	require.Equal(t, uint64(100), outs[1].(*database.XChainTxOutput).Amount)
+}
+
+func TestOperationTx(t *testing.T) {
+	chain.AddressHRP = "costwo"
+	assetID := ids.GenerateTestID()
+	owners := secp256k1fx.OutputOwners{Threshold: 1, Addrs: []ids.ShortID{ids.GenerateTestShortID()}}
+	createAssetTx := &txs.Tx{Unsigned: &txs.CreateAssetTx{
+		BaseTx: txs.BaseTx{BaseTx: avax.BaseTx{Outs: []*avax.TransferableOutput{newTestTransferableOutput(10)}}},
+		Name:   "Test asset",
+		Symbol: "TST",
+		States: []*txs.InitialState{{
+			Outs: []verify.State{&secp256k1fx.MintOutput{OutputOwners: owners}},
+		}},
+	}}
+	require.NoError(t, createAssetTx.Initialize(builder.Parser.Codec()))
+
+	tx := &txs.Tx{Unsigned: &txs.OperationTx{
+		BaseTx: txs.BaseTx{BaseTx: avax.BaseTx{Ins: []*avax.TransferableInput{{
+			UTXOID: avax.UTXOID{TxID: createAssetTx.ID(), OutputIndex: 0},
+			Asset:  avax.Asset{ID: assetID},
+			In:     &secp256k1fx.TransferInput{Amt: 10, Input: secp256k1fx.Input{SigIndices: []uint32{0}}},
+		}}}},
+		Ops: []*txs.Operation{{
+			Asset:   avax.Asset{ID: createAssetTx.ID()},
+			UTXOIDs: []*avax.UTXOID{{TxID: createAssetTx.ID(), OutputIndex: 1}},
+			Op: &secp256k1fx.MintOperation{
+				MintInput:      secp256k1fx.Input{SigIndices: []uint32{0}},
+				MintOutput:     secp256k1fx.MintOutput{OutputOwners: owners},
+				TransferOutput: *newTestOutput(100),
+			},
+		}},
+	}}
+	require.NoError(t, tx.Initialize(builder.Parser.Codec()))
+
+	xi := newTestBlockBatchIndexer()
+	require.NoError(t, xi.addTransaction(tx, &database.XChainTx{}))
+	require.Equal(t, database.XChainOperationTx, xi.newTxs[0].Type)
+
+	// Input of the operation follows the input of the base tx
+	ins := xi.inOutIndexer.GetIns()
+	require.Len(t, ins, 2)
+	require.Equal(t, createAssetTx.ID().String(), ins[1].OutTx())
+	require.Equal(t, uint32(1), ins[1].OutIndex())
+
+	// Consumed mint output is not indexed, but it sets the address of the input
+	outs, err := outputsFromTx(createAssetTx)
+	require.NoError(t, err)
+	require.Len(t, outs, 1)
+	owned, err := ownedOutputsFromTx(createAssetTx)
+	require.NoError(t, err)
+	outMap := shared.NewOutputMap()
+	for _, out := range owned {
+		outMap.Add(shared.NewIdIndexKeyFromOutput(out), out)
+	}
+	require.Empty(t, shared.NewInputList(ins).UpdateWithOutputs(outMap).ToSlice())
+
+	address, err := chain.FormatAddressBytes(owners.Addrs[0].Bytes())
+	require.NoError(t, err)
+	dbIns := ins[1].ToDbInputs()
+	require.Len(t, dbIns, 1)
+	require.Equal(t, uint32(1), dbIns[0].InIdx)
+	require.Equal(t, address, dbIns[0].Address)
+}
@@ -0,0 +1,145 @@
+package xchain
+
+import (
+	"context"
+	"flare-indexer/config"
+	"flare-indexer/database"
+	indexerctx "flare-indexer/indexer/context"
+	"flare-indexer/indexer/shared"
+	"flare-indexer/logger"
+	"flare-indexer/utils/chain"
+	"time"
+
+	"github.com/ava-labs/avalanchego/indexer"
+	"github.com/ava-labs/avalanchego/wallet/chain/x/builder"
+	"gorm.io/gorm"
+)
+
+const (
+	BlockStateName string = "x_chain_blocks"
+)
+
+// Indexer for X-chain blocks of the linearized chain. Implements ContainerBatchIndexer,
+// transactions are indexed in the same way as transactions in vertices.
+type blockBatchIndexer struct {
+	*txBatchIndexer
+
+	newBlocks []*database.XChainBlock
+}
+
+func NewXChainBlockBatchIndexer(
+	ctx indexerctx.IndexerContext,
+	client chain.IndexerClient,
+	txClient chain.IndexerClient,
+) *blockBatchIndexer {
+	return &blockBatchIndexer{
+		txBatchIndexer: NewXChainBatchIndexer(ctx, client, txClient),
+	}
+}
+
+func (xi *blockBatchIndexer) Reset(containerLen int) {
+	xi.txBatchIndexer.Reset(containerLen)
+	xi.newBlocks = make([]*database.XChainBlock, 0, containerLen)
+}
+
+func (xi *blockBatchIndexer) AddContainer(ctx context.Context, index uint64, container indexer.Container) error {
+	blk, err := builder.Parser.ParseBlock(container.Bytes)
+	if err != nil {
+		return err
+	}
+	height := blk.Height()
+	for _, tx := range blk.Txs() {
+		err = xi.addTransaction(tx, &database.XChainTx{BlockHeight: &height})
+		if err != nil {
+			return err
+		}
+	}
+
+	xi.newBlocks = append(xi.newBlocks, &database.XChainBlock{
+		BlockID:    blk.ID().String(),
+		ParentID:   blk.Parent().String(),
+		BlockIndex: index,
+		Height:     height,
+		BlockTime:  blk.Timestamp(),
+		Timestamp:  time.Unix(0, container.Timestamp),
+	})
+	return nil
+}
+
+func (xi *blockBatchIndexer) PersistedContainerID(db *gorm.DB, index uint64, container *indexer.Container) (string, error) {
+	return database.FetchXChainBlockIDAtIndex(db, index)
+}
+
+func (xi *blockBatchIndexer) PersistEntities(db *gorm.DB) error {
+	err := database.CreateXChainBlocks(db, xi.newBlocks)
+	if err != nil {
+		return err
+	}
+	return xi.txBatchIndexer.PersistEntities(db)
+}
+
+type xChainBlockIndexer struct {
+	shared.ChainIndexerBase
+
+	// Wait for the stop vertex to be indexed by the vertex indexer before indexing blocks
+	waitForLinearization bool
+}
+
+func CreateXChainBlockIndexer(ctx indexerctx.IndexerContext) (*xChainBlockIndexer, error) {
+	config := ctx.Config().XChainBlockIndexer
+	client, archive, err := shared.NewArchiveClient(config, newBlockClient(&ctx.Config().Chain))
+	if err != nil {
+		return nil, err
+	}
+	txClient := newTxClient(&ctx.Config().Chain)
+
+	idxr := xChainBlockIndexer{waitForLinearization: ctx.Config().XChainIndexer.Enabled}
+	idxr.StateName = BlockStateName
+	idxr.IndexerName = "X-chain Blocks"
+	idxr.Client = client
+	idxr.Archive = archive
+	idxr.DB = ctx.DB()
+	idxr.Config = config
+	idxr.InitMetrics(BlockStateName)
+
+	idxr.BatchIndexer = NewXChainBlockBatchIndexer(ctx, client, txClient)
+
+	return &idxr, nil
+}
+
+// Run the indexer until ctx is cancelled. If the vertex indexer is enabled, blocks are indexed
+// after it reaches the stop vertex, so that transactions in blocks are indexed after
+// transactions in vertices.
+func (xi *xChainBlockIndexer) Run(ctx context.Context) {
+	if xi.Config.Enabled && xi.waitForLinearization && !xi.waitForStopVertex(ctx) {
+		return
+	}
+	xi.ChainIndexerBase.Run(ctx)
+}
+
+// Returns false if ctx was cancelled before the stop vertex was indexed
+func (xi *xChainBlockIndexer) waitForStopVertex(ctx context.Context) bool {
+	ticker := time.NewTicker(xi.Config.Timeout)
+	defer ticker.Stop()
+	for {
+		linearized, err := database.FetchXChainLinearized(xi.DB)
+		if err != nil {
+			logger.Error("%s indexer error %v", xi.IndexerName, err)
+		} else if linearized {
+			return true
+		} else {
+			logger.Debug("%s indexer is waiting for the stop vertex to be indexed", xi.IndexerName)
+		}
+
+		select {
+		case <-ctx.Done():
+			logger.Info("%s indexer stopped", xi.IndexerName)
+			return false
+		case <-ticker.C:
+		}
+	}
+}
+
+func newBlockClient(cfg *config.ChainConfig) chain.IndexerClient {
+	return chain.NewIndexerClientFromConfig(cfg, "ext/index/X/block", "x_chain_block_client")
+}
@@ -0,0 +1,67 @@
+package xchain
+
+import (
+	"context"
+	"flare-indexer/database"
+	"flare-indexer/indexer/shared"
+	"flare-indexer/utils/chain"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/indexer"
+	"github.com/ava-labs/avalanchego/vms/avm/block"
+	"github.com/ava-labs/avalanchego/vms/avm/txs"
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+	"github.com/ava-labs/avalanchego/wallet/chain/x/builder"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBlockBatchIndexer() *blockBatchIndexer {
+	updater := &xChainInputUpdater{}
+	updater.InitCache()
+	return &blockBatchIndexer{
+		txBatchIndexer: &txBatchIndexer{inOutIndexer: shared.NewInputOutputIndexer(updater)},
+	}
+}
+
+func TestBlockBatchIndexer(t *testing.T) {
+	chain.AddressHRP = "costwo"
+	tx := &txs.Tx{Unsigned: &txs.BaseTx{BaseTx: avax.BaseTx{
+		Outs: []*avax.TransferableOutput{{
+			Asset: avax.Asset{ID: ids.GenerateTestID()},
+			Out: &secp256k1fx.TransferOutput{
+				Amt:          10,
+				OutputOwners: secp256k1fx.OutputOwners{Threshold: 1, Addrs: []ids.ShortID{ids.GenerateTestShortID()}},
+			},
+		}},
+	}}}
+	require.NoError(t, tx.Initialize(builder.Parser.Codec()))
+	parentID := ids.GenerateTestID()
+	blockTime := time.Unix(1700000000, 0)
+	blk, err := block.NewStandardBlock(parentID, 5, blockTime, []*txs.Tx{tx}, builder.Parser.Codec())
+	require.NoError(t, err)
+
+	xi := newTestBlockBatchIndexer()
+	xi.Reset(1)
+	err = xi.AddContainer(context.Background(), 3, indexer.Container{ID: blk.ID(), Bytes: blk.Bytes()})
+	require.NoError(t, err)
+
+	require.Len(t, xi.newBlocks, 1)
+	require.Equal(t, database.XChainBlock{
+		BlockID:    blk.ID().String(),
+		ParentID:   parentID.String(),
+		BlockIndex: 3,
+		Height:     5,
+		BlockTime:  blockTime,
+		Timestamp:  time.Unix(0, 0),
+	}, *xi.newBlocks[0])
+
+	require.Len(t, xi.newTxs, 1)
+	require.Equal(t, tx.ID().String(), xi.newTxs[0].TxID)
+	require.Equal(t, database.XChainBaseTx, xi.newTxs[0].Type)
+	require.Equal(t, uint64(5), *xi.newTxs[0].BlockHeight)
+	require.Equal(t, tx.Bytes(), xi.newTxs[0].Bytes)
+	require.Len(t, xi.inOutIndexer.GetNewOuts(), 1)
+}
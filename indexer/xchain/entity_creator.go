@@ -6,9 +6,14 @@ import (
 	"flare-indexer/utils"
 )
 
-var XChainInputOutputCreator = inputOutputCreator{}
+var (
+	XChainInputOutputCreator    = inputOutputCreator{outputType: database.XChainDefaultOutput}
+	XChainExportedOutputCreator = inputOutputCreator{outputType: database.XChainExportedOutput}
+)
 
-type inputOutputCreator struct{}
+type inputOutputCreator struct {
+	outputType database.XChainOutputType
+}
 
 func (ioc inputOutputCreator) CreateInputs(in shared.UpdatableInput) []*database.XChainTxInput {
 	return utils.Map(in.ToDbInputs(), func(dbIn *database.TxInput) *database.XChainTxInput {
@@ -20,6 +25,7 @@ func (ioc inputOutputCreator) CreateInputs(in shared.UpdatableInput) []*database
 
 func (ioc inputOutputCreator) CreateOutput(out *database.TxOutput) shared.Output {
 	return &database.XChainTxOutput{
+		Type:     ioc.outputType,
 		TxOutput: *out,
 	}
 }
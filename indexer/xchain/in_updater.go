@@ -1,13 +1,12 @@
 package xchain
 
 import (
+	"context"
 	"flare-indexer/database"
-	"flare-indexer/indexer/context"
+	indexerctx "flare-indexer/indexer/context"
 	"flare-indexer/indexer/shared"
 	"flare-indexer/utils/chain"
-	"fmt"
 
-	"github.com/ava-labs/avalanchego/vms/avm/txs"
 	"github.com/ava-labs/avalanchego/wallet/chain/x/builder"
 	mapset "github.com/deckarep/golang-set/v2"
 	"gorm.io/gorm"
@@ -20,7 +19,7 @@ type xChainInputUpdater struct {
 	client chain.IndexerClient
 }
 
-func newXChainInputUpdater(ctx context.IndexerContext, client chain.IndexerClient) *xChainInputUpdater {
+func newXChainInputUpdater(ctx indexerctx.IndexerContext, client chain.IndexerClient) *xChainInputUpdater {
 	ioUpdater := xChainInputUpdater{
 		db:     ctx.DB(),
 		client: client,
@@ -29,13 +28,13 @@ func newXChainInputUpdater(ctx context.IndexerContext, client chain.IndexerClien
 	return &ioUpdater
 }
 
-func (iu *xChainInputUpdater) UpdateInputs(inputs shared.InputList) (mapset.Set[string], error) {
+func (iu *xChainInputUpdater) UpdateInputs(ctx context.Context, inputs shared.InputList) (mapset.Set[string], error) {
 	missingTxIds := iu.UpdateInputsFromCache(inputs)
 	missingTxIds, err := iu.updateFromDB(inputs, missingTxIds)
 	if err != nil {
 		return nil, err
 	}
-	return iu.updateFromChain(inputs, missingTxIds)
+	return iu.updateFromChain(ctx, inputs, missingTxIds)
 }
 
 func (iu *xChainInputUpdater) updateFromDB(
@@ -54,12 +53,13 @@ func (iu *xChainInputUpdater) updateFromDB(
 }
 
 func (iu *xChainInputUpdater) updateFromChain(
+	ctx context.Context,
 	inputs shared.InputList,
 	missingTxIds mapset.Set[string],
 ) (mapset.Set[string], error) {
 	fetchedOuts := shared.NewOutputMap()
 	for txId := range missingTxIds.Iterator().C {
-		container, err := chain.FetchContainerFromIndexer(iu.client, txId)
+		container, err := chain.FetchContainerFromIndexer(ctx, iu.client, txId)
 		if err != nil {
 			return nil, err
 		}
@@ -72,15 +72,7 @@ func (iu *xChainInputUpdater) updateFromChain(
 			return nil, err
 		}
 
-		var outs []shared.Output
-		switch unsignedTx := tx.Unsigned.(type) {
-		case *txs.BaseTx:
-			outs, err = shared.OutputsFromTxOuts(txId, unsignedTx.Outs, 0, XChainInputOutputCreator /* TODO could be identity, it is not persisted */)
-		case *txs.ImportTx:
-			outs, err = shared.OutputsFromTxOuts(txId, unsignedTx.BaseTx.Outs, 0, XChainInputOutputCreator /* TODO could be identity it is not persisted */)
-		default:
-			return nil, fmt.Errorf("transaction with id %s has unsupported type %T", container.ID.String(), unsignedTx)
-		}
+		outs, err := ownedOutputsFromTx(tx)
 		if err != nil {
 			return nil, err
 		}
@@ -1,10 +1,10 @@
 package xchain
 
 import (
+	"context"
 	"flare-indexer/config"
-	"flare-indexer/indexer/context"
+	indexerctx "flare-indexer/indexer/context"
 	"flare-indexer/indexer/shared"
-	"flare-indexer/utils"
 	"flare-indexer/utils/chain"
 )
 
@@ -16,34 +16,36 @@ type xChainTxIndexer struct {
 	shared.ChainIndexerBase
 }
 
-func CreateXChainTxIndexer(ctx context.IndexerContext) *xChainTxIndexer {
+func CreateXChainTxIndexer(ctx indexerctx.IndexerContext) (*xChainTxIndexer, error) {
 	config := ctx.Config().XChainIndexer
-	client := newClient(&ctx.Config().Chain)
+	client, archive, err := shared.NewArchiveClient(config, newClient(&ctx.Config().Chain))
+	if err != nil {
+		return nil, err
+	}
 	txClient := newTxClient(&ctx.Config().Chain)
 
 	idxr := xChainTxIndexer{}
 	idxr.StateName = StateName
 	idxr.IndexerName = "X-chain Vertices"
 	idxr.Client = client
+	idxr.Archive = archive
 	idxr.DB = ctx.DB()
 	idxr.Config = config
 	idxr.InitMetrics(StateName)
 
 	idxr.BatchIndexer = NewXChainBatchIndexer(ctx, client, txClient)
 
-	return &idxr
+	return &idxr, nil
 }
 
-func (xi *xChainTxIndexer) Run() {
-	xi.ChainIndexerBase.Run()
+func (xi *xChainTxIndexer) Run(ctx context.Context) {
+	xi.ChainIndexerBase.Run(ctx)
 }
 
 func newClient(cfg *config.ChainConfig) chain.IndexerClient {
-	return chain.NewAvalancheIndexerClient(utils.JoinPaths(cfg.NodeURL, "ext/index/X/vtx"),
-		chain.ClientOptions(cfg.ApiKey)...)
+	return chain.NewIndexerClientFromConfig(cfg, "ext/index/X/vtx", "x_chain_vtx_client")
 }
 
 func newTxClient(cfg *config.ChainConfig) chain.IndexerClient {
-	return chain.NewAvalancheIndexerClient(utils.JoinPaths(cfg.NodeURL, "ext/index/X/tx"),
-		chain.ClientOptions(cfg.ApiKey)...)
+	return chain.NewIndexerClientFromConfig(cfg, "ext/index/X/tx", "x_chain_tx_client")
 }
@@ -5,18 +5,57 @@ import (
 	"flare-indexer/indexer/migrations"
 	"time"
 
+	"github.com/ava-labs/avalanchego/wallet/chain/x/builder"
 	"gorm.io/gorm"
 )
 
 func init() {
 	migrations.Container.Add("2023-01-27-00-00", "Create initial state for X-Chain transactions", createXChainTxState)
+	migrations.Container.Add("2026-10-18-03-00", "Create initial state for X-Chain blocks", createXChainBlockState)
+	migrations.Container.AddWithDown("2026-10-18-04-01", "Set asset ID, lock times and owners of indexed X-chain outputs", updateXChainOutputOwners, migrations.KeepValues)
 }
 
 func createXChainTxState(db *gorm.DB) error {
-	return database.CreateState(db, &database.State{
+	return database.CreateStateIfMissing(db, &database.State{
 		Name:           StateName,
 		NextDBIndex:    0,
 		LastChainIndex: 0,
 		Updated:        time.Now(),
 	})
 }
+
+func createXChainBlockState(db *gorm.DB) error {
+	return database.CreateStateIfMissing(db, &database.State{
+		Name:           BlockStateName,
+		NextDBIndex:    0,
+		LastChainIndex: 0,
+		Updated:        time.Now(),
+	})
+}
+
+// Set asset ID, lock times, threshold and owner group of indexed outputs from stored
+// transaction bytes. Each batch of transactions is updated in its own transaction, the
+// migration can be repeated if it fails.
+func updateXChainOutputOwners(db *gorm.DB) error {
+	var xTxs []*database.XChainTx
+	return db.Select("id", "bytes").
+		FindInBatches(&xTxs, 1000, func(batch *gorm.DB, _ int) error {
+			var dbOuts []*database.TxOutput
+			for _, xTx := range xTxs {
+				parsedTx, err := builder.Parser.ParseGenesisTx(xTx.Bytes)
+				if err != nil {
+					return err
+				}
+				outs, err := outputsFromTx(parsedTx)
+				if err != nil {
+					return err
+				}
+				for _, out := range outs {
+					dbOuts = append(dbOuts, &out.(*database.XChainTxOutput).TxOutput)
+				}
+			}
+			return db.Transaction(func(tx *gorm.DB) error {
+				return database.UpdateOutputOwners(tx, &database.XChainTxOutput{}, dbOuts)
+			})
+		}).Error
+}
@@ -0,0 +1,3 @@
+CREATE DATABASE flare_indexer_indexer OWNER indexeruser;
+CREATE DATABASE flare_indexer_indexer_2 OWNER indexeruser;
+CREATE DATABASE flare_indexer_services OWNER indexeruser;
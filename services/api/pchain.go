@@ -23,12 +23,19 @@ type ApiPChainTx struct {
 type ApiPChainTxInput struct {
 	Amount  uint64 `json:"amount"`
 	Address string `json:"address"`
+	Type    string `json:"type"`
 }
 
 type ApiPChainTxOutput struct {
-	Amount  uint64 `json:"amount"`
-	Address string `json:"address"`
-	Idx     uint32 `json:"index"`
+	Amount            uint64 `json:"amount"`
+	Address           string `json:"address"`
+	Idx               uint32 `json:"index"`
+	AssetID           string `json:"assetID"`
+	Locktime          uint64 `json:"locktime"`
+	Threshold         uint32 `json:"threshold"`
+	OwnerGroup        string `json:"ownerGroup"`
+	StakeableLocktime uint64 `json:"stakeableLocktime"`
+	Type              string `json:"type"`
 }
 
 func NewApiPChainTx(tx *database.PChainTx, inputs []database.PChainTxInput, outputs []database.PChainTxOutput) *ApiPChainTx {
@@ -53,6 +60,7 @@ func newApiPChainInputs(inputs []database.PChainTxInput) []ApiPChainTxInput {
 		result[i] = ApiPChainTxInput{
 			Amount:  in.Amount,
 			Address: in.Address,
+			Type:    string(in.Type),
 		}
 	}
 	return result
@@ -62,9 +70,15 @@ func newApiPChainOutputs(inputs []database.PChainTxOutput) []ApiPChainTxOutput {
 	result := make([]ApiPChainTxOutput, len(inputs))
 	for i, out := range inputs {
 		result[i] = ApiPChainTxOutput{
-			Amount:  out.Amount,
-			Address: out.Address,
-			Idx:     out.Idx,
+			Amount:            out.Amount,
+			Address:           out.Address,
+			Idx:               out.Idx,
+			AssetID:           out.AssetID,
+			Locktime:          out.Locktime,
+			Threshold:         out.Threshold,
+			OwnerGroup:        out.OwnerGroup,
+			StakeableLocktime: out.StakeableLocktime,
+			Type:              string(out.Type),
 		}
 	}
 	return result
@@ -34,6 +34,9 @@ func main() {
 	router := utils.NewSwaggerRouter(muxRouter, "Flare P-Chain Indexer", ServicesVersion)
 	routes.AddTransferRoutes(router, ctx)
 	routes.AddStakerRoutes(router, ctx)
+	routes.AddL1Routes(router, ctx)
+	routes.AddBalanceRoutes(router, ctx)
+	routes.AddRewardRoutes(router, ctx, epochs)
 	routes.AddTransactionRoutes(router, ctx, epochs)
 	routes.AddMirroringRoutes(router, ctx, epochs)
 
@@ -0,0 +1,156 @@
+package routes
+
+import (
+	"flare-indexer/database"
+	"flare-indexer/services/context"
+	"flare-indexer/services/utils"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Address state at a block height, or at the last block at or before the time if height is not
+// set. The last indexed block is used if neither is set.
+type GetAddressStateRequest struct {
+	Address string    `json:"address" validate:"required"`
+	Height  uint64    `json:"height"`
+	Time    time.Time `json:"time"`
+}
+
+type GetUTXOsRequest struct {
+	PaginatedRequest
+	GetAddressStateRequest
+}
+
+type UTXOResponse struct {
+	TxID          string  `json:"txID"`
+	Idx           uint32  `json:"idx"`
+	Amount        uint64  `json:"amount"`
+	Type          string  `json:"type"`
+	BlockHeight   uint64  `json:"blockHeight"`
+	UnstakeHeight *uint64 `json:"unstakeHeight"`
+
+	AssetID           string `json:"assetID"`
+	Locktime          uint64 `json:"locktime"`
+	Threshold         uint32 `json:"threshold"`
+	OwnerGroup        string `json:"ownerGroup"`
+	StakeableLocktime uint64 `json:"stakeableLocktime"`
+}
+
+type GetUTXOsResponse struct {
+	Height uint64         `json:"height"`
+	UTXOs  []UTXOResponse `json:"utxos"`
+}
+
+type GetBalanceResponse struct {
+	Height   uint64 `json:"height"`
+	Unlocked uint64 `json:"unlocked"`
+	Staked   uint64 `json:"staked"`
+	Locked   uint64 `json:"locked"`
+	Multisig uint64 `json:"multisig"`
+}
+
+type balanceRouteHandlers struct {
+	db *gorm.DB
+}
+
+func newBalanceRouteHandlers(ctx context.ServicesContext) *balanceRouteHandlers {
+	return &balanceRouteHandlers{
+		db: ctx.DB(),
+	}
+}
+
+func (rh *balanceRouteHandlers) requestHeight(request GetAddressStateRequest) (uint64, *utils.ErrorHandler) {
+	if request.Height > 0 {
+		block, err := database.FetchPChainBlock(rh.db, request.Height)
+		if err != nil {
+			return 0, utils.InternalServerErrorHandler(err)
+		}
+		if block == nil {
+			return 0, utils.HttpErrorHandler(http.StatusNotFound, "Block at the given height is not indexed")
+		}
+		return request.Height, nil
+	}
+	var height uint64
+	var found bool
+	var err error
+	if request.Time.IsZero() {
+		height, found, err = database.FetchPChainLastHeight(rh.db)
+	} else {
+		height, found, err = database.FetchPChainHeightAtTime(rh.db, request.Time)
+	}
+	if err != nil {
+		return 0, utils.InternalServerErrorHandler(err)
+	}
+	if !found {
+		return 0, utils.HttpErrorHandler(http.StatusNotFound, "No indexed block at the given time")
+	}
+	return height, nil
+}
+
+// List outputs of an address which are unspent at a block height
+func (rh *balanceRouteHandlers) listUTXOs() utils.RouteHandler {
+	handler := func(request GetUTXOsRequest) (GetUTXOsResponse, *utils.ErrorHandler) {
+		height, errHandler := rh.requestHeight(request.GetAddressStateRequest)
+		if errHandler != nil {
+			return GetUTXOsResponse{}, errHandler
+		}
+		utxos, err := database.FetchPChainUnspentUTXOs(rh.db, request.Address, height, request.Offset, request.Limit)
+		if err != nil {
+			return GetUTXOsResponse{}, utils.InternalServerErrorHandler(err)
+		}
+		response := GetUTXOsResponse{
+			Height: height,
+			UTXOs:  make([]UTXOResponse, len(utxos)),
+		}
+		for i, utxo := range utxos {
+			response.UTXOs[i] = UTXOResponse{
+				TxID:          utxo.TxID,
+				Idx:           utxo.Idx,
+				Amount:        utxo.Amount,
+				Type:          string(utxo.Type),
+				BlockHeight:   utxo.BlockHeight,
+				UnstakeHeight: utxo.UnstakeHeight,
+
+				AssetID:           utxo.AssetID,
+				Locktime:          utxo.Locktime,
+				Threshold:         utxo.Threshold,
+				OwnerGroup:        utxo.OwnerGroup,
+				StakeableLocktime: utxo.StakeableLocktime,
+			}
+		}
+		return response, nil
+	}
+	return utils.NewRouteHandler(handler, http.MethodPost, GetUTXOsRequest{}, GetUTXOsResponse{})
+}
+
+// Balance of an address at a block height
+func (rh *balanceRouteHandlers) getBalance() utils.RouteHandler {
+	handler := func(request GetAddressStateRequest) (GetBalanceResponse, *utils.ErrorHandler) {
+		height, errHandler := rh.requestHeight(request)
+		if errHandler != nil {
+			return GetBalanceResponse{}, errHandler
+		}
+		balance, err := database.FetchPChainBalance(rh.db, request.Address, height)
+		if err != nil {
+			return GetBalanceResponse{}, utils.InternalServerErrorHandler(err)
+		}
+		return GetBalanceResponse{
+			Height:   height,
+			Unlocked: balance.Unlocked,
+			Staked:   balance.Staked,
+			Locked:   balance.Locked,
+			Multisig: balance.Multisig,
+		}, nil
+	}
+	return utils.NewRouteHandler(handler, http.MethodPost, GetAddressStateRequest{}, GetBalanceResponse{})
+}
+
+func AddBalanceRoutes(router utils.Router, ctx context.ServicesContext) {
+	rh := newBalanceRouteHandlers(ctx)
+
+	subrouter := router.WithPrefix("/address", "Balances")
+	subrouter.AddRoute("/utxos", rh.listUTXOs())
+	subrouter.AddRoute("/balance", rh.getBalance())
+}
@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"flare-indexer/services/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetBalanceHeight(t *testing.T) {
+	rh := newBalanceRouteHandlers(testContext)
+	statusCode := func(request string) int {
+		r := httptest.NewRequest(http.MethodPost, "/", utils.JsonToReader(t, request))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		rh.getBalance().Handler(w, r)
+		return w.Result().StatusCode
+	}
+
+	require.Equal(t, http.StatusOK, statusCode(`{"address": "localflare1test", "height": 1}`))
+	require.Equal(t, http.StatusNotFound, statusCode(`{"address": "localflare1test", "height": 100000000}`))
+}
@@ -0,0 +1,91 @@
+package routes
+
+import (
+	"flare-indexer/database"
+	"flare-indexer/services/context"
+	"flare-indexer/services/utils"
+	"net/http"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type GetL1ValidatorsRequest struct {
+	SubnetID string    `json:"subnetId" validate:"required"`
+	Time     time.Time `json:"time"`
+}
+
+type L1OwnerResponse struct {
+	Threshold uint32   `json:"threshold"`
+	Addresses []string `json:"addresses"`
+}
+
+type GetL1ValidatorResponse struct {
+	ValidationID          string          `json:"validationID"`
+	NodeID                string          `json:"nodeID"`
+	TxID                  string          `json:"txID"`
+	StartTime             time.Time       `json:"startTime"`
+	Weight                uint64          `json:"weight"`
+	Balance               uint64          `json:"balance"`
+	BLSPublicKey          string          `json:"blsPublicKey"`
+	RemainingBalanceOwner L1OwnerResponse `json:"remainingBalanceOwner"`
+	DeactivationOwner     L1OwnerResponse `json:"deactivationOwner"`
+}
+
+type l1RouteHandlers struct {
+	db *gorm.DB
+}
+
+func newL1RouteHandlers(ctx context.ServicesContext) *l1RouteHandlers {
+	return &l1RouteHandlers{
+		db: ctx.DB(),
+	}
+}
+
+// List active validators of an L1 at the given time (now if not set)
+func (rh *l1RouteHandlers) listL1Validators() utils.RouteHandler {
+	handler := func(request GetL1ValidatorsRequest) ([]GetL1ValidatorResponse, *utils.ErrorHandler) {
+		t := request.Time
+		if t.IsZero() {
+			t = time.Now()
+		}
+		validators, err := database.FetchActivePChainL1Validators(rh.db, request.SubnetID, t)
+		if err != nil {
+			return nil, utils.InternalServerErrorHandler(err)
+		}
+		response := make([]GetL1ValidatorResponse, len(validators))
+		for i, v := range validators {
+			response[i] = GetL1ValidatorResponse{
+				ValidationID: v.ValidationID,
+				NodeID:       v.NodeID,
+				TxID:         v.TxID,
+				StartTime:    v.StartTime,
+				Weight:       v.CurrentWeight,
+				Balance:      v.TotalBalance,
+				BLSPublicKey: v.BLSPublicKey,
+				RemainingBalanceOwner: newL1OwnerResponse(
+					v.RemainingBalanceOwnerThreshold, v.RemainingBalanceOwnerAddresses),
+				DeactivationOwner: newL1OwnerResponse(
+					v.DeactivationOwnerThreshold, v.DeactivationOwnerAddresses),
+			}
+		}
+		return response, nil
+	}
+	return utils.NewRouteHandler(handler, http.MethodPost, GetL1ValidatorsRequest{}, []GetL1ValidatorResponse{})
+}
+
+func newL1OwnerResponse(threshold uint32, addresses string) L1OwnerResponse {
+	response := L1OwnerResponse{Threshold: threshold, Addresses: []string{}}
+	if len(addresses) > 0 {
+		response.Addresses = strings.Split(addresses, ",")
+	}
+	return response
+}
+
+func AddL1Routes(router utils.Router, ctx context.ServicesContext) {
+	rh := newL1RouteHandlers(ctx)
+
+	l1Subrouter := router.WithPrefix("/l1", "L1")
+	l1Subrouter.AddRoute("/validators/list", rh.listL1Validators())
+}
@@ -1,15 +1,18 @@
-//go:build integration
-// +build integration
-
 package routes
 
 import (
 	globalConfig "flare-indexer/config"
 	"flare-indexer/database"
+	indexerConfig "flare-indexer/indexer/config"
+	indexerctx "flare-indexer/indexer/context"
+	"flare-indexer/indexer/pchain"
 	"flare-indexer/services/config"
 	"flare-indexer/services/context"
 	"log"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 var (
@@ -19,6 +22,18 @@ var (
 func TestMain(m *testing.M) {
 	var err error
 	cfg := testConfig()
+	if cfg.DB.Dialect == database.SQLiteDialect {
+		// The database is shared by the indexer and services contexts
+		dir, err := os.MkdirTemp("", "flare_indexer_services")
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		cfg.DB.Database = filepath.Join(dir, "services.db")
+	}
+	if err = indexTestBlocks(cfg); err != nil {
+		log.Fatal(err)
+	}
 	testContext, err = context.BuildTestContext(cfg)
 	if err != nil {
 		log.Fatal(err)
@@ -33,14 +48,25 @@ func testConfig() *config.Config {
 			ChainAddressHRP: "localflare",
 			ChainID:         162,
 		},
-		DB: globalConfig.DBConfig{
-			Username:   database.MysqlTestUser,
-			Password:   database.MysqlTestPassword,
-			Host:       database.MysqlTestHost,
-			Port:       database.MysqlTestPort,
-			Database:   "flare_indexer_services",
-			LogQueries: true,
-		},
+		DB: database.TestDBConfig("flare_indexer_services"),
 	}
+	cfg.DB.LogQueries = true
 	return cfg
 }
+
+// Fill the test database with recorded blocks of the test network
+func indexTestBlocks(cfg *config.Config) error {
+	ctx, err := indexerctx.BuildTestContext(&indexerConfig.Config{
+		Chain: cfg.Chain,
+		PChainIndexer: indexerConfig.IndexerConfig{
+			Enabled:   true,
+			Timeout:   3000 * time.Millisecond,
+			BatchSize: 200,
+		},
+		DB: cfg.DB,
+	})
+	if err != nil {
+		return err
+	}
+	return pchain.IndexTestBlocks(ctx)
+}
@@ -32,17 +32,15 @@ var testMirroringData = map[string]database.PChainTxData{
 	},
 }
 
-func TestMain(m *testing.M) {
-	cfg := config.Config{
+func TestGetMirroringData(t *testing.T) {
+	// Test data has addresses of another network than the test database
+	globalConfig.GlobalConfigCallback.Call(config.Config{
 		Chain: globalConfig.ChainConfig{
 			ChainAddressHRP: "costwo",
 		},
-	}
-	globalConfig.GlobalConfigCallback.Call(cfg)
-	m.Run()
-}
+	})
+	t.Cleanup(func() { globalConfig.GlobalConfigCallback.Call(testContext.Config()) })
 
-func TestGetMirroringData(t *testing.T) {
 	mh := newMirroringTestRouteHandlers(testMirroringData)
 
 	r, err := http.NewRequest(http.MethodGet, "/tx_data/2NuEmDJopBVunGZym7pcYjfuWTPaoWuHSnSvxiqdFdvDY7TGqQ", nil)
@@ -1,10 +1,8 @@
-//go:build integration
-// +build integration
-
 package routes
 
 import (
 	"encoding/json"
+	"flare-indexer/database"
 	"flare-indexer/services/api"
 	"flare-indexer/services/utils"
 	"io"
@@ -13,6 +11,7 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/require"
 )
 
 type testData struct {
@@ -192,3 +191,50 @@ func verifyVerificationResponse(t *testing.T, actualBody io.Reader, expected str
 		expected,
 	)
 }
+
+func TestFindPChainTxInBlockHeight(t *testing.T) {
+	db := testContext.DB()
+	const height = 1000000
+	firstTxID, txID := "findTxFirst", "findTxSecond"
+	require.NoError(t, database.CreatePChainEntities(db,
+		[]*database.PChainBlock{{Height: height, BlockID: "findTxBlock"}},
+		[]*database.PChainTx{
+			{TxID: &firstTxID, BlockID: "findTxBlock", BlockHeight: height, Type: database.PChainBaseTx},
+			{TxID: &txID, BlockID: "findTxBlock", BlockHeight: height, Type: database.PChainAddDelegatorTx},
+		},
+		[]*database.PChainTxInput{
+			{TxInput: database.TxInput{TxID: firstTxID, InIdx: 1, Address: "second"}},
+			{TxInput: database.TxInput{TxID: firstTxID, InIdx: 0, Address: "first"}},
+			{TxInput: database.TxInput{TxID: txID, InIdx: 0, Address: "other"}},
+		},
+		nil,
+	))
+	t.Cleanup(func() {
+		db.Where("tx_id IN ?", []string{firstTxID, txID}).Delete(&database.PChainTxInput{})
+		db.Where("block_height = ?", height).Delete(&database.PChainTx{})
+		db.Where("height = ?", height).Delete(&database.PChainBlock{})
+	})
+
+	// Only the first transaction of the block is found
+	tx, blockExists, err := database.FindPChainTxInBlockHeight(db, txID, height)
+	require.NoError(t, err)
+	require.True(t, blockExists)
+	require.Nil(t, tx)
+
+	tx, blockExists, err = database.FindPChainTxInBlockHeight(db, firstTxID, height)
+	require.NoError(t, err)
+	require.True(t, blockExists)
+	require.NotNil(t, tx)
+	require.Equal(t, firstTxID, *tx.TxID)
+	require.Equal(t, "first", tx.InputAddress)
+
+	tx, blockExists, err = database.FindPChainTxInBlockHeight(db, txID, height-1)
+	require.NoError(t, err)
+	require.False(t, blockExists)
+	require.Nil(t, tx)
+
+	tx, blockExists, err = database.FindPChainTxInBlockHeight(db, "unknownTx", height)
+	require.NoError(t, err)
+	require.True(t, blockExists)
+	require.Nil(t, tx)
+}
@@ -0,0 +1,175 @@
+package routes
+
+import (
+	"flare-indexer/database"
+	"flare-indexer/services/context"
+	"flare-indexer/services/utils"
+	"flare-indexer/utils/staking"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type GetAddressRewardsRequest struct {
+	PaginatedRequest
+	Address string    `json:"address" validate:"required"`
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+}
+
+type GetStakeRewardResponse struct {
+	StakeTxID            string    `json:"stakeTxID"`
+	RewardTxID           string    `json:"rewardTxID"`
+	StakeTxType          string    `json:"stakeTxType"`
+	NodeID               string    `json:"nodeID"`
+	Amount               uint64    `json:"amount"`
+	StakerReward         uint64    `json:"stakerReward"`
+	StakerRewardAddress  string    `json:"stakerRewardAddress"`
+	DelegationFee        uint64    `json:"delegationFee"`
+	DelegationFeeAddress string    `json:"delegationFeeAddress"`
+	BlockHeight          uint64    `json:"blockHeight"`
+	Time                 time.Time `json:"time"`
+}
+
+type GetAddressRewardsResponse struct {
+	Total   uint64                   `json:"total"` // Total amount paid to the address, not paginated
+	Rewards []GetStakeRewardResponse `json:"rewards"`
+}
+
+type GetNodeRewardsResponse struct {
+	NodeID          string `json:"nodeID"`
+	ValidatorReward uint64 `json:"validatorReward"`
+	DelegatorReward uint64 `json:"delegatorReward"`
+	DelegationFee   uint64 `json:"delegationFee"`
+	Rewards         int    `json:"rewards"`
+}
+
+// Stakes whose rewards of type (STAKE or DELEGATION_FEE, both if empty) go to address
+type GetRewardsOwnerStakesRequest struct {
+	PaginatedRequest
+	Address string `json:"address" validate:"required"`
+	Type    string `json:"type" validate:"omitempty,oneof=STAKE DELEGATION_FEE"`
+}
+
+type GetRewardsOwnerStakeResponse struct {
+	TxID      string    `json:"txID"`
+	TxType    string    `json:"txType"`
+	NodeID    string    `json:"nodeID"`
+	StartTime time.Time `json:"startTime"`
+	EndTime   time.Time `json:"endTime"`
+	Weight    uint64    `json:"weight"`
+	OwnerType string    `json:"ownerType"`
+	Threshold uint32    `json:"threshold"`
+	Locktime  uint64    `json:"locktime"`
+}
+
+type rewardRouteHandlers struct {
+	db     *gorm.DB
+	epochs staking.EpochInfo
+}
+
+func newRewardRouteHandlers(ctx context.ServicesContext, epochs staking.EpochInfo) *rewardRouteHandlers {
+	return &rewardRouteHandlers{
+		db:     ctx.DB(),
+		epochs: epochs,
+	}
+}
+
+// List rewards paid to an address in [from, to) (not bounded if zero)
+func (rh *rewardRouteHandlers) listAddressRewards() utils.RouteHandler {
+	handler := func(request GetAddressRewardsRequest) (GetAddressRewardsResponse, *utils.ErrorHandler) {
+		total, err := database.FetchPChainAddressRewardTotal(rh.db, request.Address, request.From, request.To)
+		if err != nil {
+			return GetAddressRewardsResponse{}, utils.InternalServerErrorHandler(err)
+		}
+		rewards, err := database.FetchPChainAddressRewards(rh.db, request.Address, request.From, request.To,
+			request.Offset, request.Limit)
+		if err != nil {
+			return GetAddressRewardsResponse{}, utils.InternalServerErrorHandler(err)
+		}
+		response := GetAddressRewardsResponse{
+			Total:   total,
+			Rewards: make([]GetStakeRewardResponse, len(rewards)),
+		}
+		for i, r := range rewards {
+			response.Rewards[i] = GetStakeRewardResponse{
+				StakeTxID:            r.StakeTxID,
+				RewardTxID:           r.RewardTxID,
+				StakeTxType:          string(r.StakeTxType),
+				NodeID:               r.NodeID,
+				Amount:               r.Amount,
+				StakerReward:         r.StakerReward,
+				StakerRewardAddress:  r.StakerRewardAddress,
+				DelegationFee:        r.DelegationFee,
+				DelegationFeeAddress: r.DelegationFeeAddress,
+				BlockHeight:          r.BlockHeight,
+				Time:                 r.Time,
+			}
+		}
+		return response, nil
+	}
+	return utils.NewRouteHandler(handler, http.MethodPost, GetAddressRewardsRequest{}, GetAddressRewardsResponse{})
+}
+
+// List rewards per node paid in an epoch
+func (rh *rewardRouteHandlers) listNodeRewards() utils.RouteHandler {
+	handler := func(params map[string]string) ([]GetNodeRewardsResponse, *utils.ErrorHandler) {
+		epoch, err := strconv.ParseInt(params["epoch"], 10, 64)
+		if err != nil {
+			return nil, utils.HttpErrorHandler(http.StatusBadRequest, "Invalid epoch")
+		}
+
+		startTimestamp, endTimestamp := rh.epochs.GetTimeRange(epoch)
+		rewards, err := database.FetchPChainNodeRewards(rh.db, startTimestamp, endTimestamp)
+		if err != nil {
+			return nil, utils.InternalServerErrorHandler(err)
+		}
+		response := make([]GetNodeRewardsResponse, len(rewards))
+		for i, r := range rewards {
+			response[i] = GetNodeRewardsResponse(r)
+		}
+		return response, nil
+	}
+	return utils.NewParamRouteHandler(handler, http.MethodGet,
+		map[string]string{"epoch:[0-9]+": "Epoch"},
+		[]GetNodeRewardsResponse{},
+	)
+}
+
+// List stakes whose rewards go to an owner including an address
+func (rh *rewardRouteHandlers) listRewardsOwnerStakes() utils.RouteHandler {
+	handler := func(request GetRewardsOwnerStakesRequest) ([]GetRewardsOwnerStakeResponse, *utils.ErrorHandler) {
+		stakes, err := database.FetchPChainStakesByRewardsOwner(rh.db, request.Address,
+			database.PChainRewardsOwnerType(request.Type), request.Offset, request.Limit)
+		if err != nil {
+			return nil, utils.InternalServerErrorHandler(err)
+		}
+		response := make([]GetRewardsOwnerStakeResponse, len(stakes))
+		for i, s := range stakes {
+			response[i] = GetRewardsOwnerStakeResponse{
+				TxID:      *s.TxID,
+				TxType:    string(s.Type),
+				NodeID:    s.NodeID,
+				StartTime: *s.StartTime,
+				EndTime:   *s.EndTime,
+				Weight:    s.Weight,
+				OwnerType: string(s.OwnerType),
+				Threshold: s.Threshold,
+				Locktime:  s.Locktime,
+			}
+		}
+		return response, nil
+	}
+	return utils.NewRouteHandler(handler, http.MethodPost, GetRewardsOwnerStakesRequest{}, []GetRewardsOwnerStakeResponse{})
+}
+
+func AddRewardRoutes(router utils.Router, ctx context.ServicesContext, epochs staking.EpochInfo) {
+	rh := newRewardRouteHandlers(ctx, epochs)
+
+	subrouter := router.WithPrefix("/rewards", "Rewards")
+	subrouter.AddRoute("/address", rh.listAddressRewards())
+	subrouter.AddRoute("/nodes/{epoch:[0-9]+}", rh.listNodeRewards())
+	subrouter.AddRoute("/owner", rh.listRewardsOwnerStakes())
+}
@@ -5,6 +5,7 @@ import (
 	"flare-indexer/services/context"
 	"flare-indexer/services/utils"
 	"net/http"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -14,6 +15,28 @@ type GetTransferRequest struct {
 	Address string `json:"address"`
 }
 
+// Cross-chain transfers filtered by address, export or import transaction ID and status
+// (filters are not applied if empty)
+type GetCrossChainTransfersRequest struct {
+	PaginatedRequest
+	Address string `json:"address"`
+	TxID    string `json:"txID"`
+	Status  string `json:"status" validate:"omitempty,oneof=PENDING COMPLETED ORPHANED"`
+}
+
+type GetCrossChainTransferResponse struct {
+	ExportTxID       string     `json:"exportTxID"`
+	OutputIndex      uint32     `json:"outputIndex"`
+	SourceChain      string     `json:"sourceChain"`
+	DestinationChain string     `json:"destinationChain"`
+	Amount           uint64     `json:"amount"`
+	Address          string     `json:"address"`
+	ExportTime       *time.Time `json:"exportTime"`
+	ImportTxID       *string    `json:"importTxID"`
+	ImportTime       *time.Time `json:"importTime"`
+	Status           string     `json:"status"`
+}
+
 type transferRouteHandlers struct {
 	db *gorm.DB
 }
@@ -36,6 +59,34 @@ func (rh *transferRouteHandlers) listTransferTransactions(txType database.PChain
 	return utils.NewRouteHandler(handler, http.MethodPost, GetTransferRequest{}, TxIDsResponse{})
 }
 
+// List transfers between the P-chain and the X-chain or C-chain
+func (rh *transferRouteHandlers) listCrossChainTransfers() utils.RouteHandler {
+	handler := func(request GetCrossChainTransfersRequest) ([]GetCrossChainTransferResponse, *utils.ErrorHandler) {
+		transfers, err := database.FetchCrossChainTransfers(rh.db, request.Address, request.TxID,
+			database.CrossChainTransferStatus(request.Status), request.Offset, request.Limit)
+		if err != nil {
+			return nil, utils.InternalServerErrorHandler(err)
+		}
+		response := make([]GetCrossChainTransferResponse, len(transfers))
+		for i, t := range transfers {
+			response[i] = GetCrossChainTransferResponse{
+				ExportTxID:       t.ExportTxID,
+				OutputIndex:      t.OutIdx,
+				SourceChain:      t.SourceChain,
+				DestinationChain: t.DestinationChain,
+				Amount:           t.Amount,
+				Address:          t.Address,
+				ExportTime:       t.ExportTime,
+				ImportTxID:       t.ImportTxID,
+				ImportTime:       t.ImportTime,
+				Status:           string(t.Status),
+			}
+		}
+		return response, nil
+	}
+	return utils.NewRouteHandler(handler, http.MethodPost, GetCrossChainTransfersRequest{}, []GetCrossChainTransferResponse{})
+}
+
 func AddTransferRoutes(router utils.Router, ctx context.ServicesContext) {
 	vr := newTransferRouteHandlers(ctx)
 
@@ -44,4 +95,7 @@ func AddTransferRoutes(router utils.Router, ctx context.ServicesContext) {
 
 	exportSubrouter := router.WithPrefix("/exports", "Transfers")
 	exportSubrouter.AddRoute("/transactions", vr.listTransferTransactions(database.PChainExportTx))
+
+	transferSubrouter := router.WithPrefix("/transfers", "Transfers")
+	transferSubrouter.AddRoute("/cross-chain", vr.listCrossChainTransfers())
 }
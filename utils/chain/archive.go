@@ -0,0 +1,355 @@
+package chain
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"flare-indexer/logger"
+	"flare-indexer/utils"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/indexer"
+	"github.com/ava-labs/avalanchego/utils/formatting"
+)
+
+const (
+	DefaultArchiveSegmentSize uint64 = 10000
+
+	archiveFileSuffix = ".ndjson.gz"
+)
+
+// Writes containers to segment files in a directory. A segment file contains containers with
+// indexes [k * segmentSize, (k + 1) * segmentSize) as lines of JSON (the format of
+// ContainerRecording), the file is named by the index of its first container. Each write appends
+// a separate gzip member, so a file stays readable if the process stops during a write. The
+// incomplete member is removed before the segment is written again.
+type ContainerArchive struct {
+	dir         string
+	segmentSize uint64
+	repaired    map[uint64]bool // Segments checked for incomplete members by this process
+}
+
+func NewContainerArchive(dir string, segmentSize uint64) (*ContainerArchive, error) {
+	if segmentSize == 0 {
+		segmentSize = DefaultArchiveSegmentSize
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &ContainerArchive{dir: dir, segmentSize: segmentSize, repaired: make(map[uint64]bool)}, nil
+}
+
+// Write containers with indexes from, from + 1, ... Containers written again (e.g., if a batch
+// is repeated after an error) are stored twice, readers use the last copy.
+func (a *ContainerArchive) Write(from uint64, containers []indexer.Container) error {
+	for len(containers) > 0 {
+		segmentStart := from - from%a.segmentSize
+		n := utils.Min(uint64(len(containers)), segmentStart+a.segmentSize-from)
+		if err := a.writeSegment(segmentStart, from, containers[:n]); err != nil {
+			return err
+		}
+		from += n
+		containers = containers[n:]
+	}
+	return nil
+}
+
+func (a *ContainerArchive) writeSegment(segmentStart, from uint64, containers []indexer.Container) (err error) {
+	fileName := archiveFileName(a.dir, segmentStart)
+	if !a.repaired[segmentStart] {
+		if err := repairArchiveSegment(fileName); err != nil {
+			return err
+		}
+		a.repaired[segmentStart] = true
+	}
+	defer func() {
+		if err != nil {
+			// Data of the failed write may be incomplete
+			a.repaired[segmentStart] = false
+		}
+	}()
+
+	file, err := os.OpenFile(fileName, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	zw := gzip.NewWriter(file)
+	encoder := json.NewEncoder(zw)
+	for i := range containers {
+		if err := encoder.Encode(newContainerRecording(from+uint64(i), &containers[i])); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// Truncate the segment file after its last complete gzip member (members are written by
+// writeSegment, an incomplete one is left if the process stopped during a write)
+func repairArchiveSegment(fileName string) error {
+	file, err := os.OpenFile(fileName, os.O_RDWR, 0644)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	valid := completeMembersSize(file)
+	if valid == info.Size() {
+		return nil
+	}
+	logger.Warn("Removing incomplete data at the end of archive file %s (%d bytes)", fileName, info.Size()-valid)
+	if err := file.Truncate(valid); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// Size of the complete gzip members at the start of r
+func completeMembersSize(r io.Reader) int64 {
+	cr := &countingReader{r: bufio.NewReader(r)}
+	zr, err := gzip.NewReader(cr)
+	var valid int64
+	for err == nil {
+		zr.Multistream(false)
+		if _, err = io.Copy(io.Discard, zr); err != nil {
+			break
+		}
+		valid = cr.n
+		err = zr.Reset(cr)
+	}
+	return valid
+}
+
+// Counts the bytes read from r. Implements io.ByteReader, so that gzip.Reader does not read
+// beyond the end of a member.
+type countingReader struct {
+	r *bufio.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingReader) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		c.n++
+	}
+	return b, err
+}
+
+func archiveFileName(dir string, segmentStart uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("%020d%s", segmentStart, archiveFileSuffix))
+}
+
+func newContainerRecording(index uint64, c *indexer.Container) *ContainerRecording {
+	bytes, _ := formatting.Encode(formatting.Hex, c.Bytes)
+	return &ContainerRecording{
+		Id:        c.ID.String(),
+		Bytes:     bytes,
+		Timestamp: TimestampToTime(c.Timestamp),
+		Index:     strconv.FormatUint(index, 10),
+	}
+}
+
+// Implements IndexerClient by reading containers from archive segment files written by
+// ContainerArchive. Segments are loaded on demand, the last loaded segment is kept in memory.
+type FileIndexerClient struct {
+	dir string
+
+	mu            sync.Mutex
+	segmentStarts []uint64 // Sorted
+	loadedStart   uint64
+	loaded        map[uint64]*indexer.Container
+	idToIndex     map[ids.ID]uint64 // Built on the first call of GetIndex
+}
+
+func NewFileIndexerClient(dir string) (*FileIndexerClient, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var starts []uint64
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, archiveFileSuffix) {
+			continue
+		}
+		start, err := strconv.ParseUint(strings.TrimSuffix(name, archiveFileSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		starts = append(starts, start)
+	}
+	if len(starts) == 0 {
+		return nil, fmt.Errorf("no archive segments found in %s", dir)
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+	return &FileIndexerClient{dir: dir, segmentStarts: starts}, nil
+}
+
+func (c *FileIndexerClient) GetLastAccepted(ctx context.Context) (indexer.Container, uint64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	containers, err := c.segment(c.segmentStarts[len(c.segmentStarts)-1])
+	if err != nil {
+		return indexer.Container{}, 0, err
+	}
+	var last uint64
+	var found bool
+	for index := range containers {
+		if !found || index > last {
+			last, found = index, true
+		}
+	}
+	if !found {
+		return indexer.Container{}, 0, fmt.Errorf("last archive segment is empty")
+	}
+	return *containers[last], last, nil
+}
+
+func (c *FileIndexerClient) GetContainerByIndex(ctx context.Context, index uint64) (indexer.Container, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	container, err := c.container(index)
+	if err != nil {
+		return indexer.Container{}, err
+	}
+	return *container, nil
+}
+
+func (c *FileIndexerClient) GetContainerRange(ctx context.Context, from uint64, numToFetch int) ([]indexer.Container, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	first, err := c.container(from)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]indexer.Container, 0, numToFetch)
+	result = append(result, *first)
+	for i := from + 1; i < from+uint64(numToFetch); i++ {
+		container, err := c.container(i)
+		if err != nil {
+			break
+		}
+		result = append(result, *container)
+	}
+	return result, nil
+}
+
+func (c *FileIndexerClient) GetIndex(ctx context.Context, id ids.ID) (uint64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.idToIndex == nil {
+		idToIndex := make(map[ids.ID]uint64)
+		for _, start := range c.segmentStarts {
+			containers, err := c.segment(start)
+			if err != nil {
+				return 0, err
+			}
+			for index, container := range containers {
+				idToIndex[container.ID] = index
+			}
+		}
+		c.idToIndex = idToIndex
+	}
+	if index, ok := c.idToIndex[id]; ok {
+		return index, nil
+	}
+	return 0, fmt.Errorf("container with id %v not found", id)
+}
+
+// Must be called with c.mu locked
+func (c *FileIndexerClient) container(index uint64) (*indexer.Container, error) {
+	i := sort.Search(len(c.segmentStarts), func(i int) bool { return c.segmentStarts[i] > index }) - 1
+	if i >= 0 {
+		containers, err := c.segment(c.segmentStarts[i])
+		if err != nil {
+			return nil, err
+		}
+		if container, ok := containers[index]; ok {
+			return container, nil
+		}
+	}
+	return nil, fmt.Errorf("container with index %d not found", index)
+}
+
+// Must be called with c.mu locked
+func (c *FileIndexerClient) segment(start uint64) (map[uint64]*indexer.Container, error) {
+	if c.loaded != nil && c.loadedStart == start {
+		return c.loaded, nil
+	}
+	containers, err := readArchiveSegment(archiveFileName(c.dir, start))
+	if err != nil {
+		return nil, err
+	}
+	c.loaded = containers
+	c.loadedStart = start
+	return containers, nil
+}
+
+// Read all containers of a segment file. An incomplete last write (truncated gzip member)
+// is ignored.
+func readArchiveSegment(fileName string) (map[uint64]*indexer.Container, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	containers := make(map[uint64]*indexer.Container)
+	reader := bufio.NewReader(zr)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF && len(line) == 0 {
+			break
+		}
+		if err != nil {
+			logger.Warn("Ignoring incomplete data at the end of archive file %s: %v", fileName, err)
+			break
+		}
+		var r ContainerRecording
+		if err := json.Unmarshal(line, &r); err != nil {
+			return nil, fmt.Errorf("invalid record in archive file %s: %w", fileName, err)
+		}
+		container, index, err := r.toContainer()
+		if err != nil {
+			return nil, err
+		}
+		containers[index] = container
+	}
+	return containers, nil
+}
@@ -0,0 +1,101 @@
+package chain
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/indexer"
+	"github.com/stretchr/testify/require"
+)
+
+func testContainers(from uint64, n int) []indexer.Container {
+	containers := make([]indexer.Container, n)
+	for i := range containers {
+		index := from + uint64(i)
+		containers[i] = indexer.Container{
+			ID:        ids.ID{byte(index), byte(index >> 8)},
+			Bytes:     []byte{byte(index), 1, 2, 3},
+			Timestamp: int64(1700000000000000000 + index),
+		}
+	}
+	return containers
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	archive, err := NewContainerArchive(dir, 10)
+	require.NoError(t, err)
+
+	// Batches crossing segment boundaries, the second one is written twice
+	require.NoError(t, archive.Write(0, testContainers(0, 8)))
+	require.NoError(t, archive.Write(8, testContainers(8, 15)))
+	require.NoError(t, archive.Write(8, testContainers(8, 15)))
+
+	client, err := NewFileIndexerClient(dir)
+	require.NoError(t, err)
+	require.Len(t, client.segmentStarts, 3)
+	ctx := context.Background()
+
+	_, last, err := client.GetLastAccepted(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(22), last)
+
+	expected := testContainers(0, 23)
+	containers, err := client.GetContainerRange(ctx, 5, 10)
+	require.NoError(t, err)
+	require.Len(t, containers, 10)
+	for i, c := range containers {
+		require.Equal(t, expected[5+i].ID, c.ID)
+		require.Equal(t, expected[5+i].Bytes, c.Bytes)
+		require.Equal(t, expected[5+i].Timestamp, c.Timestamp)
+	}
+
+	// Range is cut at the last archived container
+	containers, err = client.GetContainerRange(ctx, 20, 10)
+	require.NoError(t, err)
+	require.Len(t, containers, 3)
+
+	container, err := client.GetContainerByIndex(ctx, 17)
+	require.NoError(t, err)
+	require.Equal(t, expected[17].ID, container.ID)
+
+	index, err := client.GetIndex(ctx, expected[12].ID)
+	require.NoError(t, err)
+	require.Equal(t, uint64(12), index)
+
+	_, err = client.GetContainerByIndex(ctx, 23)
+	require.Error(t, err)
+}
+
+func TestArchiveAppendAfterCrash(t *testing.T) {
+	dir := t.TempDir()
+	archive, err := NewContainerArchive(dir, 10)
+	require.NoError(t, err)
+	require.NoError(t, archive.Write(0, testContainers(0, 3)))
+
+	// Simulate a crash during the second write: only a part of its gzip member is written
+	fileName := archiveFileName(dir, 0)
+	before, err := os.ReadFile(fileName)
+	require.NoError(t, err)
+	require.NoError(t, archive.Write(3, testContainers(3, 3)))
+	after, err := os.ReadFile(fileName)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(fileName, after[:len(before)+(len(after)-len(before))/2], 0644))
+
+	// Restarted process repeats the batch and continues
+	archive, err = NewContainerArchive(dir, 10)
+	require.NoError(t, err)
+	require.NoError(t, archive.Write(3, testContainers(3, 3)))
+	require.NoError(t, archive.Write(6, testContainers(6, 2)))
+
+	client, err := NewFileIndexerClient(dir)
+	require.NoError(t, err)
+	_, last, err := client.GetLastAccepted(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint64(7), last)
+	containers, err := client.GetContainerRange(context.Background(), 0, 10)
+	require.NoError(t, err)
+	require.Len(t, containers, 8)
+}
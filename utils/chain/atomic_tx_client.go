@@ -0,0 +1,107 @@
+package chain
+
+import (
+	"context"
+	"flare-indexer/config"
+	"flare-indexer/utils"
+	"strings"
+
+	"github.com/ava-labs/avalanchego/api"
+	"github.com/ava-labs/avalanchego/api/info"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/formatting"
+)
+
+const (
+	XChainGetTxMethod       = "avm.getTx"
+	CChainGetAtomicTxMethod = "avax.getAtomicTx"
+)
+
+// Client fetching transactions exporting outputs to the P-chain, i.e., transactions of the
+// X-chain or atomic transactions of the C-chain
+type AtomicTxClient interface {
+	GetAtomicTx(ctx context.Context, id ids.ID) (*api.GetTxReply, error)
+}
+
+type AvalancheAtomicTxClient struct {
+	AvalancheRPCClient
+	method string
+}
+
+// Create a client calling method (XChainGetTxMethod or CChainGetAtomicTxMethod) at endpoint
+func NewAvalancheAtomicTxClient(endpoint string, method string) *AvalancheAtomicTxClient {
+	return &AvalancheAtomicTxClient{
+		AvalancheRPCClient: *NewAvalancheRPCClient(endpoint),
+		method:             method,
+	}
+}
+
+func (c *AvalancheAtomicTxClient) GetAtomicTx(ctx context.Context, id ids.ID) (*api.GetTxReply, error) {
+	params := api.GetTxArgs{
+		TxID:     id,
+		Encoding: formatting.Hex,
+	}
+	response, err := c.call(ctx, c.method, params)
+	if err != nil {
+		return nil, err
+	}
+
+	reply := &api.GetTxReply{}
+	err = response.GetObject(reply)
+	if err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+// Implements AtomicTxClient using a set of nodes with failover
+type FailoverAtomicTxClient struct {
+	pool *failoverPool[AtomicTxClient]
+}
+
+func NewFailoverAtomicTxClient(nodes []FailoverNode[AtomicTxClient], options FailoverOptions) *FailoverAtomicTxClient {
+	return &FailoverAtomicTxClient{pool: newFailoverPool(nodes, options)}
+}
+
+func (c *FailoverAtomicTxClient) GetAtomicTx(ctx context.Context, id ids.ID) (reply *api.GetTxReply, err error) {
+	err = c.pool.do(ctx, func(client AtomicTxClient) (err error) {
+		reply, err = client.GetAtomicTx(ctx, id)
+		return
+	})
+	return
+}
+
+// Create an atomic tx client for route (e.g., "ext/bc/X") and method of the configured nodes,
+// with failover if more than one node is configured. Nodes are checked using the indexer at
+// healthRoute.
+func NewAtomicTxClientFromConfig(cfg *config.ChainConfig, route string, method string, healthRoute string, metricsNamespace string) AtomicTxClient {
+	nodeConfigs := cfg.NodeList()
+	if len(nodeConfigs) == 1 {
+		return NewAvalancheAtomicTxClient(utils.JoinPaths(nodeConfigs[0].URL, route+RPCClientOptions(nodeConfigs[0].ApiKey)), method)
+	}
+	nodes := make([]FailoverNode[AtomicTxClient], len(nodeConfigs))
+	for i, n := range nodeConfigs {
+		nodes[i] = FailoverNode[AtomicTxClient]{
+			Name:   n.URL,
+			Client: NewAvalancheAtomicTxClient(utils.JoinPaths(n.URL, route+RPCClientOptions(n.ApiKey)), method),
+			Health: NewAvalancheIndexerClient(utils.JoinPaths(n.URL, healthRoute), ClientOptions(n.ApiKey)...),
+		}
+	}
+	return NewFailoverAtomicTxClient(nodes, failoverOptions(cfg, metricsNamespace))
+}
+
+// Returns the ID of the chain with alias (e.g., "X" or "C") from the info API of the first
+// configured node
+func FetchBlockchainID(ctx context.Context, cfg *config.ChainConfig, alias string) (ids.ID, error) {
+	node := cfg.NodeList()[0]
+	client := info.NewClient(strings.TrimSuffix(node.URL, "/"))
+	var id ids.ID
+	err := doRequest(ctx, func() (err error) {
+		callCtx, cancelCtx := context.WithTimeout(ctx, ClientRequestTimeout)
+		defer cancelCtx()
+
+		id, err = client.GetBlockchainID(callCtx, alias, ClientOptions(node.ApiKey)...)
+		return
+	})
+	return id, err
+}
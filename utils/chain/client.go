@@ -15,29 +15,52 @@ const (
 )
 
 // Get range of indexed objects by calling "index.getContainerRange"
-func FetchContainerRangeFromIndexer(client IndexerClient, from uint64, numToFetch int) ([]indexer.Container, error) {
-	ctx, cancelCtx := context.WithTimeout(context.Background(), IndexerTimeout)
+func FetchContainerRangeFromIndexer(ctx context.Context, client IndexerClient, from uint64, numToFetch int) ([]indexer.Container, error) {
+	ctx, cancelCtx := context.WithTimeout(ctx, IndexerTimeout)
 	defer cancelCtx()
 
 	return client.GetContainerRange(ctx, from, numToFetch)
 }
 
 // Get last accepted container by calling "index.getLastAccepted"
-func FetchLastAcceptedContainer(client IndexerClient) (indexer.Container, uint64, error) {
-	ctx, cancelCtx := context.WithTimeout(context.Background(), IndexerTimeout)
+func FetchLastAcceptedContainer(ctx context.Context, client IndexerClient) (indexer.Container, uint64, error) {
+	ctx, cancelCtx := context.WithTimeout(ctx, IndexerTimeout)
 	defer cancelCtx()
 
 	return client.GetLastAccepted(ctx)
 }
 
+// Implemented by clients that send requests to one of several nodes
+type bestNodeClient interface {
+	bestLastAccepted(ctx context.Context) (index uint64, ok bool, err error)
+}
+
+// Check all nodes of client, make the node with the highest last accepted index active and
+// return its last accepted index. A node that lags behind reports a lower last accepted index
+// than the one already indexed, so the index should be re-checked before it is considered
+// a rewind of the chain. Returns ok == false if client uses a single node.
+func FetchBestLastAcceptedIndex(ctx context.Context, client IndexerClient) (index uint64, ok bool, err error) {
+	bestClient, isBest := client.(bestNodeClient)
+	if !isBest {
+		return 0, false, nil
+	}
+	ctx, cancelCtx := context.WithTimeout(ctx, IndexerTimeout)
+	defer cancelCtx()
+
+	return bestClient.bestLastAccepted(ctx)
+}
+
 // Get object by its id by calling "index.getIndex" and "index.getContainerByIndex" successively.
 // Returns nil, nil if getIndex failed with an error.
-func FetchContainerFromIndexer(client IndexerClient, id string) (*indexer.Container, error) {
-	ctx, cancelCtx := context.WithTimeout(context.Background(), IndexerTimeout)
+func FetchContainerFromIndexer(ctx context.Context, client IndexerClient, id string) (*indexer.Container, error) {
+	ctx, cancelCtx := context.WithTimeout(ctx, IndexerTimeout)
 	defer cancelCtx()
 
 	txID, _ := ids.FromString(id)
 	index, err := client.GetIndex(ctx, txID)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
 	if err != nil {
 		// This can happen since some transactions (genesis) are not indexed
 		// so we don't panic here with an error
@@ -0,0 +1,484 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"flare-indexer/config"
+	"flare-indexer/logger"
+	"flare-indexer/utils"
+	"sync"
+	"time"
+
+	"github.com/ava-labs/avalanchego/api"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/indexer"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	DefaultNodeCheckInterval = 30 * time.Second
+	nodeCheckTimeout         = 10 * time.Second
+)
+
+// Node used by a failover client
+type FailoverNode[T any] struct {
+	Name   string // Node URL (without API key), used in logs and metrics
+	Client T
+
+	// Client used to check the last accepted index of the node
+	Health IndexerClient
+}
+
+type FailoverOptions struct {
+	// Interval of checking the last accepted index of all nodes
+	CheckInterval time.Duration
+	// Switch to the best node if the current one is more than MaxLag containers behind it
+	MaxLag uint64
+	// Namespace of per-node metrics, no metrics are exported if empty
+	MetricsNamespace string
+}
+
+// Set of nodes with one active node. Requests are sent to the active node, another node
+// becomes active if the request fails or if the active node lags behind the best node.
+type failoverPool[T any] struct {
+	nodes   []FailoverNode[T]
+	options FailoverOptions
+	metrics *failoverMetrics
+
+	mu           sync.Mutex
+	current      int
+	healthy      []bool
+	lastAccepted []uint64
+	lastCheck    time.Time
+}
+
+func newFailoverPool[T any](nodes []FailoverNode[T], options FailoverOptions) *failoverPool[T] {
+	if options.CheckInterval <= 0 {
+		options.CheckInterval = DefaultNodeCheckInterval
+	}
+	p := &failoverPool[T]{
+		nodes:        nodes,
+		options:      options,
+		metrics:      getFailoverMetrics(options.MetricsNamespace),
+		healthy:      make([]bool, len(nodes)),
+		lastAccepted: make([]uint64, len(nodes)),
+	}
+	for i := range p.healthy {
+		p.healthy[i] = true
+	}
+	p.metrics.setActive(p.nodeNames(), p.current)
+	return p
+}
+
+// Call f with the client of the active node. If it fails with a retryable error, the next node
+// becomes active and f is called again, at most once for each node. Other errors (e.g., a
+// transaction was not found) and errors caused by ctx are returned immediately.
+func (p *failoverPool[T]) do(ctx context.Context, f func(client T) error) error {
+	_, err := p.call(ctx, f)
+	return err
+}
+
+// Same as do, also returns the node that handled the request
+func (p *failoverPool[T]) call(ctx context.Context, f func(client T) error) (int, error) {
+	p.checkIfDue(ctx)
+
+	var err error
+	for range p.nodes {
+		i := p.active()
+		node := &p.nodes[i]
+		err = f(node.Client)
+		p.metrics.request(node.Name, err)
+		if err == nil || ctx.Err() != nil || !IsRetryableError(err) {
+			return i, err
+		}
+		logger.Warn("Request to node %s failed: %v", node.Name, err)
+		p.markFailed(i)
+	}
+	return -1, err
+}
+
+// Call f with the client of the active node without failover
+func (p *failoverPool[T]) once(ctx context.Context, f func(client T) error) error {
+	p.checkIfDue(ctx)
+
+	node := &p.nodes[p.active()]
+	err := f(node.Client)
+	p.metrics.request(node.Name, err)
+	return err
+}
+
+func (p *failoverPool[T]) active() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.current
+}
+
+func (p *failoverPool[T]) markFailed(i int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.healthy[i] = false
+	if p.current != i {
+		return
+	}
+	next := p.best(i)
+	if next < 0 {
+		next = (i + 1) % len(p.nodes)
+	}
+	p.switchTo(next, "request failed")
+}
+
+// Record the last accepted index reported by the active node i
+func (p *failoverPool[T]) reportLastAccepted(i int, index uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.healthy[i] = true
+	p.lastAccepted[i] = index
+	p.metrics.setLastAccepted(p.nodes[i].Name, index)
+	p.switchIfLagging()
+}
+
+// Check all nodes if the check interval has elapsed since the last check
+func (p *failoverPool[T]) checkIfDue(ctx context.Context) {
+	p.mu.Lock()
+	due := time.Since(p.lastCheck) >= p.options.CheckInterval
+	if due {
+		p.lastCheck = time.Now()
+	}
+	p.mu.Unlock()
+
+	if due && len(p.nodes) > 1 {
+		p.check(ctx)
+	}
+}
+
+// Query the last accepted index of all nodes and switch to the best node if the active one
+// is not healthy or lags behind
+func (p *failoverPool[T]) check(ctx context.Context) {
+	healthy := make([]bool, len(p.nodes))
+	lastAccepted := make([]uint64, len(p.nodes))
+	for i, node := range p.nodes {
+		if node.Health == nil {
+			healthy[i] = true
+			continue
+		}
+		checkCtx, cancel := context.WithTimeout(ctx, nodeCheckTimeout)
+		_, index, err := node.Health.GetLastAccepted(checkCtx)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			logger.Warn("Health check of node %s failed: %v", node.Name, err)
+			continue
+		}
+		healthy[i] = true
+		lastAccepted[i] = index
+		p.metrics.setLastAccepted(node.Name, index)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.healthy = healthy
+	p.lastAccepted = lastAccepted
+	if !p.healthy[p.current] {
+		if next := p.best(-1); next >= 0 {
+			p.switchTo(next, "health check failed")
+		}
+		return
+	}
+	p.switchIfLagging()
+}
+
+// Check all nodes and switch to the healthy node with the highest last accepted index.
+// Returns its last accepted index.
+func (p *failoverPool[T]) checkBest(ctx context.Context) (uint64, error) {
+	p.check(ctx)
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	best := p.best(-1)
+	if best < 0 {
+		return 0, errors.New("no healthy node")
+	}
+	p.switchTo(best, "best node")
+	return p.lastAccepted[best], nil
+}
+
+// Must be called with p.mu locked
+func (p *failoverPool[T]) switchIfLagging() {
+	best := p.best(-1)
+	if best < 0 || best == p.current {
+		return
+	}
+	if p.lastAccepted[p.current]+p.options.MaxLag < p.lastAccepted[best] {
+		p.switchTo(best, "node lags behind")
+	}
+}
+
+// Return the healthy node with the highest last accepted index, other than skip,
+// -1 if there is none. Must be called with p.mu locked.
+func (p *failoverPool[T]) best(skip int) int {
+	best := -1
+	for i := range p.nodes {
+		if i == skip || !p.healthy[i] {
+			continue
+		}
+		if best < 0 || p.lastAccepted[i] > p.lastAccepted[best] {
+			best = i
+		}
+	}
+	return best
+}
+
+// Must be called with p.mu locked
+func (p *failoverPool[T]) switchTo(i int, reason string) {
+	if i == p.current {
+		return
+	}
+	logger.Warn("Switching from node %s to node %s: %s", p.nodes[p.current].Name, p.nodes[i].Name, reason)
+	p.current = i
+	p.metrics.setActive(p.nodeNames(), i)
+}
+
+func (p *failoverPool[T]) nodeNames() []string {
+	names := make([]string, len(p.nodes))
+	for i, node := range p.nodes {
+		names[i] = node.Name
+	}
+	return names
+}
+
+// Implements IndexerClient using a set of nodes with failover
+type FailoverIndexerClient struct {
+	pool *failoverPool[IndexerClient]
+}
+
+func NewFailoverIndexerClient(nodes []FailoverNode[IndexerClient], options FailoverOptions) *FailoverIndexerClient {
+	return &FailoverIndexerClient{pool: newFailoverPool(nodes, options)}
+}
+
+func (c *FailoverIndexerClient) GetLastAccepted(ctx context.Context) (container indexer.Container, index uint64, err error) {
+	node, err := c.pool.call(ctx, func(client IndexerClient) (err error) {
+		container, index, err = client.GetLastAccepted(ctx)
+		return
+	})
+	if err == nil {
+		c.pool.reportLastAccepted(node, index)
+	}
+	return
+}
+
+func (c *FailoverIndexerClient) bestLastAccepted(ctx context.Context) (uint64, bool, error) {
+	if len(c.pool.nodes) < 2 {
+		return 0, false, nil
+	}
+	index, err := c.pool.checkBest(ctx)
+	return index, err == nil, err
+}
+
+func (c *FailoverIndexerClient) GetContainerByIndex(ctx context.Context, index uint64) (container indexer.Container, err error) {
+	err = c.pool.do(ctx, func(client IndexerClient) (err error) {
+		container, err = client.GetContainerByIndex(ctx, index)
+		return
+	})
+	return
+}
+
+func (c *FailoverIndexerClient) GetContainerRange(ctx context.Context, from uint64, numToFetch int) (containers []indexer.Container, err error) {
+	err = c.pool.do(ctx, func(client IndexerClient) (err error) {
+		containers, err = client.GetContainerRange(ctx, from, numToFetch)
+		return
+	})
+	return
+}
+
+// Errors of getIndex are expected for containers that are not indexed (e.g., genesis
+// transactions), so they do not cause switching to another node
+func (c *FailoverIndexerClient) GetIndex(ctx context.Context, id ids.ID) (index uint64, err error) {
+	err = c.pool.once(ctx, func(client IndexerClient) (err error) {
+		index, err = client.GetIndex(ctx, id)
+		return
+	})
+	return
+}
+
+// Implements RPCClient using a set of nodes with failover
+type FailoverRPCClient struct {
+	pool *failoverPool[RPCClient]
+}
+
+func NewFailoverRPCClient(nodes []FailoverNode[RPCClient], options FailoverOptions) *FailoverRPCClient {
+	return &FailoverRPCClient{pool: newFailoverPool(nodes, options)}
+}
+
+func (c *FailoverRPCClient) GetRewardUTXOs(ctx context.Context, id ids.ID) (reply *GetRewardUTXOsReply, err error) {
+	err = c.pool.do(ctx, func(client RPCClient) (err error) {
+		reply, err = client.GetRewardUTXOs(ctx, id)
+		return
+	})
+	return
+}
+
+func (c *FailoverRPCClient) GetTx(ctx context.Context, id ids.ID) (reply *api.GetTxReply, err error) {
+	err = c.pool.do(ctx, func(client RPCClient) (err error) {
+		reply, err = client.GetTx(ctx, id)
+		return
+	})
+	return
+}
+
+// Create an indexer client for route (e.g., "ext/index/P/block") of the configured nodes,
+// with failover if more than one node is configured
+func NewIndexerClientFromConfig(cfg *config.ChainConfig, route string, metricsNamespace string) IndexerClient {
+	client := newIndexerClientFromConfig(cfg, route, metricsNamespace)
+	if len(cfg.RecordDir) > 0 {
+		return NewRecordingIndexerClient(client, mustRecorder(cfg.RecordDir, metricsNamespace))
+	}
+	return client
+}
+
+func newIndexerClientFromConfig(cfg *config.ChainConfig, route string, metricsNamespace string) IndexerClient {
+	nodeConfigs := cfg.NodeList()
+	if len(nodeConfigs) == 1 {
+		return NewAvalancheIndexerClient(utils.JoinPaths(nodeConfigs[0].URL, route), ClientOptions(nodeConfigs[0].ApiKey)...)
+	}
+	nodes := make([]FailoverNode[IndexerClient], len(nodeConfigs))
+	for i, n := range nodeConfigs {
+		client := NewAvalancheIndexerClient(utils.JoinPaths(n.URL, route), ClientOptions(n.ApiKey)...)
+		nodes[i] = FailoverNode[IndexerClient]{Name: n.URL, Client: client, Health: client}
+	}
+	return NewFailoverIndexerClient(nodes, failoverOptions(cfg, metricsNamespace))
+}
+
+// Create an RPC client for route (e.g., "ext/bc/P") of the configured nodes, with failover if
+// more than one node is configured. Nodes are checked using the indexer at healthRoute.
+func NewRPCClientFromConfig(cfg *config.ChainConfig, route string, healthRoute string, metricsNamespace string) RPCClient {
+	client := newRPCClientFromConfig(cfg, route, healthRoute, metricsNamespace)
+	if len(cfg.RecordDir) > 0 {
+		return NewRecordingRPCClient(client, mustRecorder(cfg.RecordDir, metricsNamespace))
+	}
+	return client
+}
+
+func newRPCClientFromConfig(cfg *config.ChainConfig, route string, healthRoute string, metricsNamespace string) RPCClient {
+	nodeConfigs := cfg.NodeList()
+	if len(nodeConfigs) == 1 {
+		return NewAvalancheRPCClient(utils.JoinPaths(nodeConfigs[0].URL, route+RPCClientOptions(nodeConfigs[0].ApiKey)))
+	}
+	nodes := make([]FailoverNode[RPCClient], len(nodeConfigs))
+	for i, n := range nodeConfigs {
+		nodes[i] = FailoverNode[RPCClient]{
+			Name:   n.URL,
+			Client: NewAvalancheRPCClient(utils.JoinPaths(n.URL, route+RPCClientOptions(n.ApiKey))),
+			Health: NewAvalancheIndexerClient(utils.JoinPaths(n.URL, healthRoute), ClientOptions(n.ApiKey)...),
+		}
+	}
+	return NewFailoverRPCClient(nodes, failoverOptions(cfg, metricsNamespace))
+}
+
+// Create the uptime client of the first configured node (connection status of validators is
+// node-specific)
+func NewUptimeClientFromConfig(cfg *config.ChainConfig) UptimeClient {
+	node := cfg.NodeList()[0]
+	client := NewAvalancheUptimeClient(utils.JoinPaths(node.URL, "ext/bc/P"+RPCClientOptions(node.ApiKey)))
+	if len(cfg.RecordDir) > 0 {
+		return NewRecordingUptimeClient(client, mustRecorder(cfg.RecordDir, "uptime_client"))
+	}
+	return client
+}
+
+func mustRecorder(dir string, name string) *Recorder {
+	recorder, err := RecorderFor(dir, name)
+	if err != nil {
+		logger.Fatal("Cannot create recording file: %v", err)
+	}
+	return recorder
+}
+
+func failoverOptions(cfg *config.ChainConfig, metricsNamespace string) FailoverOptions {
+	return FailoverOptions{
+		CheckInterval:    cfg.NodeCheckInterval,
+		MaxLag:           cfg.MaxNodeLag,
+		MetricsNamespace: metricsNamespace,
+	}
+}
+
+// Per-node metrics of failover clients
+type failoverMetrics struct {
+	requests          *prometheus.CounterVec
+	lastAcceptedIndex *prometheus.GaugeVec
+	activeNode        *prometheus.GaugeVec
+}
+
+var (
+	failoverMetricsMu          sync.Mutex
+	failoverMetricsByNamespace = make(map[string]*failoverMetrics)
+)
+
+// Metrics are shared by all clients with the same namespace (they can be registered only once)
+func getFailoverMetrics(namespace string) *failoverMetrics {
+	if len(namespace) == 0 {
+		return nil
+	}
+	failoverMetricsMu.Lock()
+	defer failoverMetricsMu.Unlock()
+
+	if m, ok := failoverMetricsByNamespace[namespace]; ok {
+		return m
+	}
+	m := &failoverMetrics{
+		requests: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "node_requests_total",
+			Help:      "Number of requests sent to the node by result (ok, error)",
+		}, []string{"node", "result"}),
+		lastAcceptedIndex: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "node_last_accepted_index",
+			Help:      "Last accepted index reported by the node",
+		}, []string{"node"}),
+		activeNode: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "node_active",
+			Help:      "1 if requests are sent to the node, 0 otherwise",
+		}, []string{"node"}),
+	}
+	failoverMetricsByNamespace[namespace] = m
+	return m
+}
+
+func (m *failoverMetrics) request(node string, err error) {
+	if m == nil {
+		return
+	}
+	if err == nil {
+		m.requests.WithLabelValues(node, "ok").Inc()
+	} else {
+		m.requests.WithLabelValues(node, "error").Inc()
+	}
+}
+
+func (m *failoverMetrics) setLastAccepted(node string, index uint64) {
+	if m != nil {
+		m.lastAcceptedIndex.WithLabelValues(node).Set(float64(index))
+	}
+}
+
+func (m *failoverMetrics) setActive(nodes []string, current int) {
+	if m == nil {
+		return
+	}
+	for i, node := range nodes {
+		if i == current {
+			m.activeNode.WithLabelValues(node).Set(1)
+		} else {
+			m.activeNode.WithLabelValues(node).Set(0)
+		}
+	}
+}
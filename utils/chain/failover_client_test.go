@@ -0,0 +1,145 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/indexer"
+	"github.com/gorilla/rpc/v2/json2"
+	"github.com/stretchr/testify/require"
+	"github.com/ybbus/jsonrpc/v3"
+)
+
+type fakeIndexerClient struct {
+	lastIndex uint64
+	err       error
+	indexErr  error // error of GetIndex
+	calls     int
+}
+
+func (c *fakeIndexerClient) GetLastAccepted(ctx context.Context) (indexer.Container, uint64, error) {
+	c.calls++
+	return indexer.Container{}, c.lastIndex, c.err
+}
+
+func (c *fakeIndexerClient) GetContainerByIndex(ctx context.Context, index uint64) (indexer.Container, error) {
+	c.calls++
+	return indexer.Container{}, c.err
+}
+
+func (c *fakeIndexerClient) GetContainerRange(ctx context.Context, from uint64, numToFetch int) ([]indexer.Container, error) {
+	c.calls++
+	return nil, c.err
+}
+
+func (c *fakeIndexerClient) GetIndex(ctx context.Context, id ids.ID) (uint64, error) {
+	c.calls++
+	return 0, c.indexErr
+}
+
+func newFakeFailoverClient(clients ...*fakeIndexerClient) *FailoverIndexerClient {
+	nodes := make([]FailoverNode[IndexerClient], len(clients))
+	for i, c := range clients {
+		nodes[i] = FailoverNode[IndexerClient]{Name: string(rune('a' + i)), Client: c, Health: c}
+	}
+	return NewFailoverIndexerClient(nodes, FailoverOptions{CheckInterval: time.Hour, MaxLag: 5})
+}
+
+func TestFailoverOnError(t *testing.T) {
+	a := &fakeIndexerClient{lastIndex: 100}
+	b := &fakeIndexerClient{lastIndex: 100}
+	client := newFakeFailoverClient(a, b)
+	ctx := context.Background()
+
+	_, err := client.GetContainerByIndex(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, 0, client.pool.active())
+
+	a.err = errors.New("dial tcp 127.0.0.1:9650: connect: connection refused")
+	_, err = client.GetContainerByIndex(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, 1, client.pool.active())
+
+	b.err = errors.New("dial tcp 127.0.0.1:9650: connect: connection refused")
+	_, err = client.GetContainerByIndex(ctx, 1)
+	require.Error(t, err)
+}
+
+func TestFailoverOnLag(t *testing.T) {
+	a := &fakeIndexerClient{lastIndex: 100}
+	b := &fakeIndexerClient{lastIndex: 103}
+	client := newFakeFailoverClient(a, b)
+	ctx := context.Background()
+
+	// Lag within limit
+	_, index, err := client.GetLastAccepted(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(100), index)
+	require.Equal(t, 0, client.pool.active())
+
+	// Lag above limit, switch after the health check
+	b.lastIndex = 110
+	client.pool.check(ctx)
+	require.Equal(t, 1, client.pool.active())
+
+	_, index, err = client.GetLastAccepted(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(110), index)
+}
+
+func TestFailoverGetIndexDoesNotSwitch(t *testing.T) {
+	a := &fakeIndexerClient{lastIndex: 100, indexErr: errors.New("not found")}
+	b := &fakeIndexerClient{lastIndex: 100}
+	client := newFakeFailoverClient(a, b)
+
+	_, err := client.GetIndex(context.Background(), ids.Empty)
+	require.Error(t, err)
+	require.Equal(t, 0, client.pool.active())
+	require.Equal(t, 1, b.calls) // only the health check
+}
+
+func TestFailoverNotOnNonRetryableError(t *testing.T) {
+	a := &fakeIndexerClient{lastIndex: 100}
+	b := &fakeIndexerClient{lastIndex: 100}
+	client := newFakeFailoverClient(a, b)
+	ctx := context.Background()
+	client.pool.checkIfDue(ctx)
+
+	a.err = &jsonrpc.RPCError{Code: int(json2.E_SERVER), Message: "not found"}
+	_, err := client.GetContainerByIndex(ctx, 1)
+	require.Error(t, err)
+	require.Equal(t, 0, client.pool.active())
+	require.Equal(t, 1, b.calls) // only the health check
+}
+
+func TestFetchBestLastAcceptedIndex(t *testing.T) {
+	a := &fakeIndexerClient{lastIndex: 100}
+	b := &fakeIndexerClient{lastIndex: 100}
+	client := newFakeFailoverClient(a, b)
+	ctx := context.Background()
+
+	// a fails, b becomes active and falls behind
+	a.err = errors.New("dial tcp 127.0.0.1:9650: connect: connection refused")
+	_, err := client.GetContainerByIndex(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, 1, client.pool.active())
+	a.err = nil
+	b.lastIndex = 0
+
+	_, index, err := client.GetLastAccepted(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), index)
+
+	index, ok, err := FetchBestLastAcceptedIndex(ctx, client)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, uint64(100), index)
+	require.Equal(t, 0, client.pool.active())
+
+	_, ok, err = FetchBestLastAcceptedIndex(ctx, a)
+	require.NoError(t, err)
+	require.False(t, ok)
+}
@@ -0,0 +1,56 @@
+package chain
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/ava-labs/avalanchego/genesis"
+	"github.com/ava-labs/avalanchego/utils/constants"
+)
+
+// Genesis configs of Flare networks (genesis/<hrp>.json), in the JSON format of the node
+//
+//go:embed genesis
+var flareGenesisFiles embed.FS
+
+// Genesis config of the network with the address HRP. The config is read from file if it is
+// not empty, otherwise the config embedded for the network is used (the Flare genesis files or
+// the config embedded in avalanchego). Returns nil if there is no embedded config for the
+// network.
+func GenesisConfig(hrp string, file string) (*genesis.Config, error) {
+	if len(file) > 0 {
+		return genesis.GetConfigFile(file)
+	}
+	config, err := flareGenesisConfig(hrp)
+	if config != nil || err != nil {
+		return config, err
+	}
+	networkID, ok := constants.NetworkHRPToNetworkID[hrp]
+	if !ok {
+		return nil, nil
+	}
+	return genesis.GetConfig(networkID), nil
+}
+
+// Genesis config embedded for the Flare network with the address HRP, nil if there is none
+func flareGenesisConfig(hrp string) (*genesis.Config, error) {
+	content, err := flareGenesisFiles.ReadFile("genesis/" + hrp + ".json")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var unparsed genesis.UnparsedConfig
+	if err := json.Unmarshal(content, &unparsed); err != nil {
+		return nil, fmt.Errorf("invalid genesis of network %s: %w", hrp, err)
+	}
+	config, err := unparsed.Parse()
+	if err != nil {
+		return nil, fmt.Errorf("invalid genesis of network %s: %w", hrp, err)
+	}
+	return &config, nil
+}
@@ -0,0 +1,24 @@
+package chain
+
+import (
+	"testing"
+
+	"github.com/ava-labs/avalanchego/genesis"
+	"github.com/stretchr/testify/require"
+)
+
+// Address HRPs of Flare networks, each one must have an embedded genesis file
+var flareNetworkHRPs = []string{"flare", "costwo", "songbird", "coston"}
+
+func TestFlareGenesisConfig(t *testing.T) {
+	for _, hrp := range flareNetworkHRPs {
+		t.Run(hrp, func(t *testing.T) {
+			cfg, err := GenesisConfig(hrp, "")
+			require.NoError(t, err)
+			require.NotNil(t, cfg, "genesis/%s.json is not embedded", hrp)
+
+			_, _, err = genesis.FromConfig(cfg)
+			require.NoError(t, err)
+		})
+	}
+}
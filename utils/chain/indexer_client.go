@@ -32,20 +32,36 @@ func NewAvalancheIndexerClient(uri string, opts ...rpc.Option) *AvalancheIndexer
 	return &AvalancheIndexerClient{client: client, rpcOptions: opts}
 }
 
-func (ic *AvalancheIndexerClient) GetLastAccepted(ctx context.Context) (indexer.Container, uint64, error) {
-	return ic.client.GetLastAccepted(ctx, ic.rpcOptions...)
-}
-
-func (ic *AvalancheIndexerClient) GetContainerByIndex(ctx context.Context, index uint64) (indexer.Container, error) {
-	return ic.client.GetContainerByIndex(ctx, index, ic.rpcOptions...)
-}
-
-func (ic *AvalancheIndexerClient) GetContainerRange(ctx context.Context, from uint64, numToFetch int) ([]indexer.Container, error) {
-	return ic.client.GetContainerRange(ctx, from, numToFetch, ic.rpcOptions...)
-}
-
-func (ic *AvalancheIndexerClient) GetIndex(ctx context.Context, id ids.ID) (uint64, error) {
-	return ic.client.GetIndex(ctx, id, ic.rpcOptions...)
+func (ic *AvalancheIndexerClient) GetLastAccepted(ctx context.Context) (container indexer.Container, index uint64, err error) {
+	err = doRequest(ctx, func() (err error) {
+		container, index, err = ic.client.GetLastAccepted(ctx, ic.rpcOptions...)
+		return
+	})
+	return
+}
+
+func (ic *AvalancheIndexerClient) GetContainerByIndex(ctx context.Context, index uint64) (container indexer.Container, err error) {
+	err = doRequest(ctx, func() (err error) {
+		container, err = ic.client.GetContainerByIndex(ctx, index, ic.rpcOptions...)
+		return
+	})
+	return
+}
+
+func (ic *AvalancheIndexerClient) GetContainerRange(ctx context.Context, from uint64, numToFetch int) (containers []indexer.Container, err error) {
+	err = doRequest(ctx, func() (err error) {
+		containers, err = ic.client.GetContainerRange(ctx, from, numToFetch, ic.rpcOptions...)
+		return
+	})
+	return
+}
+
+func (ic *AvalancheIndexerClient) GetIndex(ctx context.Context, id ids.ID) (index uint64, err error) {
+	err = doRequest(ctx, func() (err error) {
+		index, err = ic.client.GetIndex(ctx, id, ic.rpcOptions...)
+		return
+	})
+	return
 }
 
 //
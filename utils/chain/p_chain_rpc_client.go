@@ -30,8 +30,8 @@ type GetRewardUTXOsReply struct {
 }
 
 type RPCClient interface {
-	GetRewardUTXOs(id ids.ID) (*GetRewardUTXOsReply, error)
-	GetTx(id ids.ID) (*api.GetTxReply, error)
+	GetRewardUTXOs(ctx context.Context, id ids.ID) (*GetRewardUTXOsReply, error)
+	GetTx(ctx context.Context, id ids.ID) (*api.GetTxReply, error)
 }
 
 type AvalancheRPCClient struct {
@@ -44,15 +44,12 @@ func NewAvalancheRPCClient(endpoint string) *AvalancheRPCClient {
 	}
 }
 
-func (c *AvalancheRPCClient) GetRewardUTXOs(id ids.ID) (*GetRewardUTXOsReply, error) {
+func (c *AvalancheRPCClient) GetRewardUTXOs(ctx context.Context, id ids.ID) (*GetRewardUTXOsReply, error) {
 	params := api.GetTxArgs{
 		TxID:     id,
 		Encoding: formatting.Hex,
 	}
-	ctx, cancelCtx := context.WithTimeout(context.Background(), ClientRequestTimeout)
-	defer cancelCtx()
-
-	response, err := c.client.Call(ctx, "platform.getRewardUTXOs", params)
+	response, err := c.call(ctx, "platform.getRewardUTXOs", params)
 	if err != nil {
 		return nil, err
 	}
@@ -65,15 +62,12 @@ func (c *AvalancheRPCClient) GetRewardUTXOs(id ids.ID) (*GetRewardUTXOsReply, er
 	return reply, nil
 }
 
-func (c *AvalancheRPCClient) GetTx(id ids.ID) (*api.GetTxReply, error) {
+func (c *AvalancheRPCClient) GetTx(ctx context.Context, id ids.ID) (*api.GetTxReply, error) {
 	params := api.GetTxArgs{
 		TxID:     id,
 		Encoding: formatting.Hex,
 	}
-	ctx, cancelCtx := context.WithTimeout(context.Background(), ClientRequestTimeout)
-	defer cancelCtx()
-
-	response, err := c.client.Call(ctx, "platform.getTx", params)
+	response, err := c.call(ctx, "platform.getTx", params)
 	if err != nil {
 		return nil, err
 	}
@@ -86,6 +80,22 @@ func (c *AvalancheRPCClient) GetTx(id ids.ID) (*api.GetTxReply, error) {
 	return reply, nil
 }
 
+// Call method with the shared request policy, each attempt has its own timeout.
+// JSON-RPC errors are returned as errors.
+func (c *AvalancheRPCClient) call(ctx context.Context, method string, params interface{}) (response *jsonrpc.RPCResponse, err error) {
+	err = doRequest(ctx, func() error {
+		callCtx, cancelCtx := context.WithTimeout(ctx, ClientRequestTimeout)
+		defer cancelCtx()
+
+		response, err = c.client.Call(callCtx, method, params)
+		if err == nil && response.Error != nil {
+			err = response.Error
+		}
+		return err
+	})
+	return
+}
+
 //
 // Implement RPCClient interface using recorded data
 //
@@ -127,14 +137,14 @@ func NewRecordedRPCClient(fileName string) (*RecordedRPCClient, error) {
 	return &RecordedRPCClient{txIDToRecording: txIDToRecording}, nil
 }
 
-func (c *RecordedRPCClient) GetRewardUTXOs(id ids.ID) (*GetRewardUTXOsReply, error) {
+func (c *RecordedRPCClient) GetRewardUTXOs(ctx context.Context, id ids.ID) (*GetRewardUTXOsReply, error) {
 	if reply, ok := c.txIDToRecording[id.String()]; ok {
 		return reply.toGetRewardUTXOsReply(), nil
 	}
 	return nil, fmt.Errorf("no recording for tx %v", id)
 }
 
-func (c *RecordedRPCClient) GetTx(id ids.ID) (*api.GetTxReply, error) {
+func (c *RecordedRPCClient) GetTx(ctx context.Context, id ids.ID) (*api.GetTxReply, error) {
 	if reply, ok := c.txIDToRecording[id.String()]; ok {
 		return reply.toGetTxReply(), nil
 	}
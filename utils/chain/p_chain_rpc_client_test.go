@@ -1,6 +1,7 @@
 package chain
 
 import (
+	"context"
 	"testing"
 
 	"github.com/ava-labs/avalanchego/ids"
@@ -12,6 +13,7 @@ func TestRPCClient(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	ctx := context.Background()
 	id1, _ := ids.FromString("22ewQXuJw8PKQPiqJxwDezQszrNT2GbLyh4oCpCyVCSjAaDp2o")
 	id2, _ := ids.FromString("oUpTu8TbYSWviCxV5mxuh2Wk9xSHRVrPXVKfPmFESPsRRdh2X")
 	id3, _ := ids.FromString("2VhbseqzJLTZ1wxBWzWqvgshmAqx8LshT2p8HJP7P6zwz4iZTg")
@@ -20,22 +22,22 @@ func TestRPCClient(t *testing.T) {
 		t.Fatal("Wrong ID")
 	}
 
-	_, err = client.GetTx(id1)
+	_, err = client.GetTx(ctx, id1)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	_, err = client.GetTx(id2)
+	_, err = client.GetTx(ctx, id2)
 	if err == nil {
 		t.Fatal("Expected error")
 	}
 
-	_, err = client.GetTx(id3)
+	_, err = client.GetTx(ctx, id3)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	utxos1, err := client.GetRewardUTXOs(id1)
+	utxos1, err := client.GetRewardUTXOs(ctx, id1)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -43,7 +45,7 @@ func TestRPCClient(t *testing.T) {
 		t.Fatal("Expected 0 utxos")
 	}
 
-	utxos3, err := client.GetRewardUTXOs(id3)
+	utxos3, err := client.GetRewardUTXOs(ctx, id3)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -0,0 +1,361 @@
+package chain
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flare-indexer/database"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ava-labs/avalanchego/api"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/indexer"
+)
+
+const recordingFileSuffix = ".ndjson"
+
+// Request and response (or error) of a single call of a client method, a line of a
+// recording file
+type Interaction struct {
+	Method   string          `json:"method"`
+	Request  json.RawMessage `json:"request,omitempty"`
+	Response json.RawMessage `json:"response,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+type lastAcceptedResponse struct {
+	Container indexer.Container `json:"container"`
+	Index     uint64            `json:"index"`
+}
+
+type validatorStatusResponse struct {
+	Validators []*ValidatorStatus           `json:"validators"`
+	Status     database.UptimeCronjobStatus `json:"status"`
+}
+
+type indexRequest struct {
+	Index uint64 `json:"index"`
+}
+
+type rangeRequest struct {
+	From       uint64 `json:"from"`
+	NumToFetch int    `json:"numToFetch"`
+}
+
+type idRequest struct {
+	ID ids.ID `json:"id"`
+}
+
+//
+// Recording of interactions
+//
+
+var (
+	recordersMu sync.Mutex
+	recorders   = make(map[string]*Recorder)
+)
+
+// Appends interactions to a recording file. Clients recording to the same file share a
+// recorder.
+type Recorder struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// Get the recorder of file name.ndjson in dir, the file is created or appended to
+func RecorderFor(dir string, name string) (*Recorder, error) {
+	fileName := filepath.Join(dir, name+recordingFileSuffix)
+
+	recordersMu.Lock()
+	defer recordersMu.Unlock()
+
+	if r, ok := recorders[fileName]; ok {
+		return r, nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(fileName, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	r := &Recorder{file: file}
+	recorders[fileName] = r
+	return r, nil
+}
+
+// Write an interaction as a single line. Recording errors are returned to the caller
+// so that a recording is never silently incomplete.
+func (r *Recorder) Record(method string, request interface{}, response interface{}, callErr error) error {
+	interaction := Interaction{Method: method}
+	var err error
+	if request != nil {
+		if interaction.Request, err = json.Marshal(request); err != nil {
+			return err
+		}
+	}
+	if callErr != nil {
+		interaction.Error = callErr.Error()
+	} else if response != nil {
+		if interaction.Response, err = json.Marshal(response); err != nil {
+			return err
+		}
+	}
+	line, err := json.Marshal(&interaction)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err = r.file.Write(append(line, '\n'))
+	return err
+}
+
+func record[T any](r *Recorder, method string, request interface{}, response T, callErr error) (T, error) {
+	if err := r.Record(method, request, response, callErr); err != nil {
+		return response, fmt.Errorf("cannot record %s: %w", method, err)
+	}
+	return response, callErr
+}
+
+// IndexerClient that records all interactions of the wrapped client
+type RecordingIndexerClient struct {
+	client   IndexerClient
+	recorder *Recorder
+}
+
+func NewRecordingIndexerClient(client IndexerClient, recorder *Recorder) *RecordingIndexerClient {
+	return &RecordingIndexerClient{client: client, recorder: recorder}
+}
+
+func (c *RecordingIndexerClient) GetLastAccepted(ctx context.Context) (indexer.Container, uint64, error) {
+	container, index, err := c.client.GetLastAccepted(ctx)
+	_, err = record(c.recorder, "GetLastAccepted", nil, &lastAcceptedResponse{container, index}, err)
+	return container, index, err
+}
+
+func (c *RecordingIndexerClient) GetContainerByIndex(ctx context.Context, index uint64) (indexer.Container, error) {
+	container, err := c.client.GetContainerByIndex(ctx, index)
+	return record(c.recorder, "GetContainerByIndex", &indexRequest{index}, container, err)
+}
+
+func (c *RecordingIndexerClient) GetContainerRange(ctx context.Context, from uint64, numToFetch int) ([]indexer.Container, error) {
+	containers, err := c.client.GetContainerRange(ctx, from, numToFetch)
+	return record(c.recorder, "GetContainerRange", &rangeRequest{from, numToFetch}, containers, err)
+}
+
+func (c *RecordingIndexerClient) bestLastAccepted(ctx context.Context) (uint64, bool, error) {
+	return FetchBestLastAcceptedIndex(ctx, c.client)
+}
+
+func (c *RecordingIndexerClient) GetIndex(ctx context.Context, id ids.ID) (uint64, error) {
+	index, err := c.client.GetIndex(ctx, id)
+	return record(c.recorder, "GetIndex", &idRequest{id}, index, err)
+}
+
+// RPCClient that records all interactions of the wrapped client
+type RecordingRPCClient struct {
+	client   RPCClient
+	recorder *Recorder
+}
+
+func NewRecordingRPCClient(client RPCClient, recorder *Recorder) *RecordingRPCClient {
+	return &RecordingRPCClient{client: client, recorder: recorder}
+}
+
+func (c *RecordingRPCClient) GetRewardUTXOs(ctx context.Context, id ids.ID) (*GetRewardUTXOsReply, error) {
+	reply, err := c.client.GetRewardUTXOs(ctx, id)
+	return record(c.recorder, "GetRewardUTXOs", &idRequest{id}, reply, err)
+}
+
+func (c *RecordingRPCClient) GetTx(ctx context.Context, id ids.ID) (*api.GetTxReply, error) {
+	reply, err := c.client.GetTx(ctx, id)
+	return record(c.recorder, "GetTx", &idRequest{id}, reply, err)
+}
+
+// UptimeClient that records all interactions of the wrapped client, including the
+// current time, since the uptime cronjob depends on it
+type RecordingUptimeClient struct {
+	client   UptimeClient
+	recorder *Recorder
+}
+
+func NewRecordingUptimeClient(client UptimeClient, recorder *Recorder) *RecordingUptimeClient {
+	return &RecordingUptimeClient{client: client, recorder: recorder}
+}
+
+func (c *RecordingUptimeClient) GetValidatorStatus(ctx context.Context) ([]*ValidatorStatus, database.UptimeCronjobStatus, error) {
+	validators, status, err := c.client.GetValidatorStatus(ctx)
+	_, err = record(c.recorder, "GetValidatorStatus", nil, &validatorStatusResponse{validators, status}, err)
+	return validators, status, err
+}
+
+func (c *RecordingUptimeClient) Now() time.Time {
+	now := c.client.Now()
+	// Now cannot fail, a recording error shows up as a missing interaction on replay
+	c.recorder.Record("Now", nil, now, nil)
+	return now
+}
+
+//
+// Replay of recorded interactions
+//
+
+// Serves recorded responses. Calls are matched by method and request; if the same call was
+// recorded several times (e.g., GetLastAccepted), the responses are returned in the recorded
+// order and the last one is repeated.
+type Replayer struct {
+	mu           sync.Mutex
+	interactions map[string][]*Interaction
+}
+
+func NewReplayer(fileName string) (*Replayer, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	interactions := make(map[string][]*Interaction)
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for decoder.More() {
+		var i Interaction
+		if err := decoder.Decode(&i); err != nil {
+			return nil, fmt.Errorf("invalid interaction in %s: %w", fileName, err)
+		}
+		key := interactionKey(i.Method, i.Request)
+		interactions[key] = append(interactions[key], &i)
+	}
+	return &Replayer{interactions: interactions}, nil
+}
+
+func interactionKey(method string, request json.RawMessage) string {
+	return method + " " + string(request)
+}
+
+// Find the next recorded interaction of method with request and decode its response into
+// response
+func (r *Replayer) Replay(method string, request interface{}, response interface{}) error {
+	var requestJson json.RawMessage
+	if request != nil {
+		var err error
+		if requestJson, err = json.Marshal(request); err != nil {
+			return err
+		}
+	}
+	key := interactionKey(method, requestJson)
+
+	r.mu.Lock()
+	queue := r.interactions[key]
+	if len(queue) == 0 {
+		r.mu.Unlock()
+		return fmt.Errorf("no recorded interaction %s", key)
+	}
+	i := queue[0]
+	if len(queue) > 1 {
+		r.interactions[key] = queue[1:]
+	}
+	r.mu.Unlock()
+
+	if len(i.Error) > 0 {
+		return errors.New(i.Error)
+	}
+	return json.Unmarshal(i.Response, response)
+}
+
+// IndexerClient replaying interactions recorded by RecordingIndexerClient
+type ReplayIndexerClient struct {
+	replayer *Replayer
+}
+
+func NewReplayIndexerClient(fileName string) (*ReplayIndexerClient, error) {
+	replayer, err := NewReplayer(fileName)
+	if err != nil {
+		return nil, err
+	}
+	return &ReplayIndexerClient{replayer: replayer}, nil
+}
+
+func (c *ReplayIndexerClient) GetLastAccepted(ctx context.Context) (indexer.Container, uint64, error) {
+	var response lastAcceptedResponse
+	err := c.replayer.Replay("GetLastAccepted", nil, &response)
+	return response.Container, response.Index, err
+}
+
+func (c *ReplayIndexerClient) GetContainerByIndex(ctx context.Context, index uint64) (container indexer.Container, err error) {
+	err = c.replayer.Replay("GetContainerByIndex", &indexRequest{index}, &container)
+	return
+}
+
+func (c *ReplayIndexerClient) GetContainerRange(ctx context.Context, from uint64, numToFetch int) (containers []indexer.Container, err error) {
+	err = c.replayer.Replay("GetContainerRange", &rangeRequest{from, numToFetch}, &containers)
+	return
+}
+
+func (c *ReplayIndexerClient) GetIndex(ctx context.Context, id ids.ID) (index uint64, err error) {
+	err = c.replayer.Replay("GetIndex", &idRequest{id}, &index)
+	return
+}
+
+// RPCClient replaying interactions recorded by RecordingRPCClient
+type ReplayRPCClient struct {
+	replayer *Replayer
+}
+
+func NewReplayRPCClient(fileName string) (*ReplayRPCClient, error) {
+	replayer, err := NewReplayer(fileName)
+	if err != nil {
+		return nil, err
+	}
+	return &ReplayRPCClient{replayer: replayer}, nil
+}
+
+func (c *ReplayRPCClient) GetRewardUTXOs(ctx context.Context, id ids.ID) (*GetRewardUTXOsReply, error) {
+	reply := &GetRewardUTXOsReply{}
+	if err := c.replayer.Replay("GetRewardUTXOs", &idRequest{id}, reply); err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+func (c *ReplayRPCClient) GetTx(ctx context.Context, id ids.ID) (*api.GetTxReply, error) {
+	reply := &api.GetTxReply{}
+	if err := c.replayer.Replay("GetTx", &idRequest{id}, reply); err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+// UptimeClient replaying interactions recorded by RecordingUptimeClient. Now returns the
+// recorded times in order, the last one when the recording is exhausted.
+type ReplayUptimeClient struct {
+	replayer *Replayer
+}
+
+func NewReplayUptimeClient(fileName string) (*ReplayUptimeClient, error) {
+	replayer, err := NewReplayer(fileName)
+	if err != nil {
+		return nil, err
+	}
+	return &ReplayUptimeClient{replayer: replayer}, nil
+}
+
+func (c *ReplayUptimeClient) GetValidatorStatus(ctx context.Context) ([]*ValidatorStatus, database.UptimeCronjobStatus, error) {
+	var response validatorStatusResponse
+	if err := c.replayer.Replay("GetValidatorStatus", nil, &response); err != nil {
+		return nil, database.UptimeCronjobStatusServiceError, err
+	}
+	return response.Validators, response.Status, nil
+}
+
+func (c *ReplayUptimeClient) Now() time.Time {
+	var now time.Time
+	if err := c.replayer.Replay("Now", nil, &now); err != nil {
+		return time.Time{}
+	}
+	return now
+}
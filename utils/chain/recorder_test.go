@@ -0,0 +1,108 @@
+package chain
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordAndReplayIndexerClient(t *testing.T) {
+	source, err := PChainTestClient()
+	require.NoError(t, err)
+	dir := t.TempDir()
+	recorder, err := RecorderFor(dir, "indexer")
+	require.NoError(t, err)
+	client := NewRecordingIndexerClient(source, recorder)
+	ctx := context.Background()
+
+	expectedLast, expectedIndex, err := client.GetLastAccepted(ctx)
+	require.NoError(t, err)
+	expectedRange, err := client.GetContainerRange(ctx, 1, 5)
+	require.NoError(t, err)
+	expectedID := expectedRange[2].ID
+	_, err = client.GetIndex(ctx, ids.Empty)
+	require.Error(t, err)
+
+	replay, err := NewReplayIndexerClient(filepath.Join(dir, "indexer.ndjson"))
+	require.NoError(t, err)
+
+	last, index, err := replay.GetLastAccepted(ctx)
+	require.NoError(t, err)
+	require.Equal(t, expectedIndex, index)
+	require.Equal(t, expectedLast, last)
+
+	containers, err := replay.GetContainerRange(ctx, 1, 5)
+	require.NoError(t, err)
+	require.Equal(t, expectedRange, containers)
+	require.Equal(t, expectedID, containers[2].ID)
+
+	_, err = replay.GetIndex(ctx, ids.Empty)
+	require.Error(t, err)
+
+	// Not recorded
+	_, err = replay.GetContainerByIndex(ctx, 1)
+	require.Error(t, err)
+}
+
+func TestRecordAndReplayRPCClient(t *testing.T) {
+	source, err := PChainTestRPCClient()
+	require.NoError(t, err)
+	dir := t.TempDir()
+	recorder, err := RecorderFor(dir, "rpc")
+	require.NoError(t, err)
+	client := NewRecordingRPCClient(source, recorder)
+	ctx := context.Background()
+
+	id, err := ids.FromString("1AJu1m7G9vw9mqT81KGRYj1nBKX9e8oEgEtHp9XGNRNtpQx57")
+	require.NoError(t, err)
+	expectedTx, err := client.GetTx(ctx, id)
+	require.NoError(t, err)
+	expectedUTXOs, err := client.GetRewardUTXOs(ctx, id)
+	require.NoError(t, err)
+
+	replay, err := NewReplayRPCClient(filepath.Join(dir, "rpc.ndjson"))
+	require.NoError(t, err)
+
+	tx, err := replay.GetTx(ctx, id)
+	require.NoError(t, err)
+	require.JSONEq(t, string(expectedTx.Tx), string(tx.Tx))
+	utxos, err := replay.GetRewardUTXOs(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, expectedUTXOs, utxos)
+}
+
+func TestRecordAndReplayUptimeClient(t *testing.T) {
+	source, err := UptimeTestClient()
+	require.NoError(t, err)
+	dir := t.TempDir()
+	recorder, err := RecorderFor(dir, "uptime")
+	require.NoError(t, err)
+	client := NewRecordingUptimeClient(source, recorder)
+	ctx := context.Background()
+
+	times := []int64{1675350000, 1676629054}
+	var expected [][]*ValidatorStatus
+	for _, now := range times {
+		source.SetNowUnix(now)
+		client.Now()
+		validators, _, err := client.GetValidatorStatus(ctx)
+		require.NoError(t, err)
+		expected = append(expected, validators)
+	}
+
+	replay, err := NewReplayUptimeClient(filepath.Join(dir, "uptime.ndjson"))
+	require.NoError(t, err)
+	for i, now := range times {
+		require.Equal(t, now, replay.Now().Unix())
+		validators, _, err := replay.GetValidatorStatus(ctx)
+		require.NoError(t, err)
+		require.ElementsMatch(t, expected[i], validators)
+	}
+
+	// The last recorded time is repeated
+	require.Equal(t, time.Unix(times[1], 0).Unix(), replay.Now().Unix())
+}
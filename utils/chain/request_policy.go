@@ -0,0 +1,167 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"flare-indexer/config"
+	"flare-indexer/logger"
+	"io"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/gorilla/rpc/v2/json2"
+	"github.com/ybbus/jsonrpc/v3"
+	"golang.org/x/time/rate"
+)
+
+// Retrying and rate limiting of requests to nodes. A single policy is shared by all clients
+// in the process, it is set from the chain configuration.
+type RequestPolicy struct {
+	maxRetries  int
+	backoffBase time.Duration
+	backoffMax  time.Duration
+	limiter     *rate.Limiter // nil if there is no limit
+}
+
+var requestPolicy atomic.Pointer[RequestPolicy]
+
+func init() {
+	SetRequestPolicy(NewRequestPolicy(config.RequestConfig{}))
+	config.GlobalConfigCallback.AddCallback(func(config config.GlobalConfig) {
+		SetRequestPolicy(NewRequestPolicy(config.ChainConfig().Requests))
+	})
+}
+
+func NewRequestPolicy(cfg config.RequestConfig) *RequestPolicy {
+	p := &RequestPolicy{
+		maxRetries:  cfg.MaxRetries,
+		backoffBase: cfg.BackoffBase,
+		backoffMax:  cfg.BackoffMax,
+	}
+	if p.backoffMax < p.backoffBase {
+		p.backoffMax = p.backoffBase
+	}
+	if cfg.RateLimit > 0 {
+		burst := cfg.RateBurst
+		if burst <= 0 {
+			burst = 1
+		}
+		p.limiter = rate.NewLimiter(rate.Limit(cfg.RateLimit), burst)
+	}
+	return p
+}
+
+func SetRequestPolicy(p *RequestPolicy) {
+	requestPolicy.Store(p)
+}
+
+// Send a request using the shared request policy
+func doRequest(ctx context.Context, request func() error) error {
+	return requestPolicy.Load().Do(ctx, request)
+}
+
+// Call request after waiting for the rate limiter. Retryable errors are retried at most
+// maxRetries times with exponential backoff and jitter. Returns the last error of the request,
+// or the context error if ctx is done while waiting.
+func (p *RequestPolicy) Do(ctx context.Context, request func() error) error {
+	for attempt := 0; ; attempt++ {
+		if p.limiter != nil {
+			if err := p.limiter.Wait(ctx); err != nil {
+				return err
+			}
+		}
+		err := request()
+		if err == nil || attempt >= p.maxRetries || ctx.Err() != nil || !IsRetryableError(err) {
+			return err
+		}
+
+		delay := p.backoff(attempt)
+		logger.Debug("Request failed, retrying in %v: %v", delay, err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+// Delay before retry number attempt+1: half of the exponential delay is fixed,
+// the other half is random
+func (p *RequestPolicy) backoff(attempt int) time.Duration {
+	delay := p.backoffMax
+	if attempt < 32 && p.backoffBase<<attempt < p.backoffMax {
+		delay = p.backoffBase << attempt
+	}
+	if delay <= 1 {
+		return delay
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)))
+}
+
+// Only transient errors are retryable: timeouts, reset or refused connections, HTTP statuses
+// 408, 429 and 5xx, and JSON-RPC internal errors. All other errors (e.g., a transaction was not
+// found, a response could not be decoded) are returned to the caller without retrying.
+func IsRetryableError(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	var httpErr *jsonrpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return isRetryableStatus(httpErr.Code)
+	}
+	var rpcErr *jsonrpc.RPCError
+	if errors.As(err, &rpcErr) {
+		return rpcErr.Code == int(json2.E_INTERNAL)
+	}
+	var json2Err *json2.Error
+	if errors.As(err, &json2Err) {
+		return json2Err.Code == json2.E_INTERNAL
+	}
+
+	// Avalanche indexer client reports HTTP status codes only in the message
+	msg := err.Error()
+	const statusPrefix = "received status code: "
+	if strings.HasPrefix(msg, statusPrefix) {
+		if code, convErr := strconv.Atoi(strings.TrimPrefix(msg, statusPrefix)); convErr == nil {
+			return isRetryableStatus(code)
+		}
+	}
+
+	// jsonrpc client formats transport errors as strings
+	for _, transient := range transientErrorMessages {
+		if strings.Contains(msg, transient) {
+			return true
+		}
+	}
+	return false
+}
+
+// Messages of transport errors that lost their type when formatted by the jsonrpc client
+var transientErrorMessages = []string{
+	"connection reset by peer",
+	"connection refused",
+	"i/o timeout",
+	"Client.Timeout exceeded",
+	"context deadline exceeded",
+	"unexpected EOF",
+	": EOF",
+}
+
+func isRetryableStatus(code int) bool {
+	return code == 408 || code == 429 || code >= 500
+}
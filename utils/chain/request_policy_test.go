@@ -0,0 +1,90 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"flare-indexer/config"
+	"fmt"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gorilla/rpc/v2/json2"
+	"github.com/stretchr/testify/require"
+	"github.com/ybbus/jsonrpc/v3"
+)
+
+func TestIsRetryableError(t *testing.T) {
+	require.True(t, IsRetryableError(context.DeadlineExceeded))
+	require.False(t, IsRetryableError(context.Canceled))
+	require.True(t, IsRetryableError(errors.New("received status code: 503")))
+	require.True(t, IsRetryableError(errors.New("received status code: 429")))
+	require.False(t, IsRetryableError(errors.New("received status code: 404")))
+	require.True(t, IsRetryableError(&jsonrpc.RPCError{Code: int(json2.E_INTERNAL)}))
+	require.False(t, IsRetryableError(&jsonrpc.RPCError{Code: int(json2.E_SERVER), Message: "not found"}))
+	require.False(t, IsRetryableError(fmt.Errorf("failed to decode client response: %w",
+		&json2.Error{Code: json2.E_SERVER, Message: "not found"})))
+
+	// Transport errors formatted as strings by the jsonrpc client
+	require.True(t, IsRetryableError(errors.New("rpc call platform.getTx() on http://node: read tcp: connection reset by peer")))
+	require.True(t, IsRetryableError(errors.New("rpc call platform.getTx() on http://node: dial tcp: i/o timeout")))
+	require.True(t, IsRetryableError(fmt.Errorf("read: %w", syscall.ECONNRESET)))
+	require.True(t, IsRetryableError(&jsonrpc.HTTPError{Code: 502}))
+	require.False(t, IsRetryableError(&jsonrpc.HTTPError{Code: 400}))
+
+	// Unknown errors are not retried
+	require.False(t, IsRetryableError(errors.New("invalid address")))
+	require.False(t, IsRetryableError(errors.New("rpc call platform.getTx() on http://node: unsupported protocol scheme")))
+}
+
+func TestRequestPolicyRetries(t *testing.T) {
+	p := NewRequestPolicy(config.RequestConfig{
+		MaxRetries:  2,
+		BackoffBase: time.Millisecond,
+		BackoffMax:  2 * time.Millisecond,
+	})
+	ctx := context.Background()
+
+	calls := 0
+	err := p.Do(ctx, func() error {
+		calls++
+		return errors.New("received status code: 502")
+	})
+	require.Error(t, err)
+	require.Equal(t, 3, calls)
+
+	calls = 0
+	err = p.Do(ctx, func() error {
+		calls++
+		return &jsonrpc.RPCError{Code: int(json2.E_SERVER), Message: "not found"}
+	})
+	require.Error(t, err)
+	require.Equal(t, 1, calls)
+
+	calls = 0
+	err = p.Do(ctx, func() error {
+		calls++
+		if calls < 2 {
+			return context.DeadlineExceeded
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, calls)
+}
+
+func TestRequestPolicyBackoff(t *testing.T) {
+	p := NewRequestPolicy(config.RequestConfig{
+		BackoffBase: 100 * time.Millisecond,
+		BackoffMax:  time.Second,
+	})
+	for attempt := 0; attempt < 40; attempt++ {
+		delay := p.backoff(attempt)
+		expected := time.Second
+		if attempt < 4 {
+			expected = 100 * time.Millisecond << attempt
+		}
+		require.GreaterOrEqual(t, delay, expected/2)
+		require.LessOrEqual(t, delay, expected)
+	}
+}
@@ -23,7 +23,7 @@ type ValidatorStatus struct {
 }
 
 type UptimeClient interface {
-	GetValidatorStatus() ([]*ValidatorStatus, database.UptimeCronjobStatus, error)
+	GetValidatorStatus(ctx context.Context) ([]*ValidatorStatus, database.UptimeCronjobStatus, error)
 	Now() time.Time
 }
 
@@ -37,8 +37,8 @@ func NewAvalancheUptimeClient(endpoint string) UptimeClient {
 	}
 }
 
-func (c *AvalancheUptimeClient) GetValidatorStatus() ([]*ValidatorStatus, database.UptimeCronjobStatus, error) {
-	validators, status, err := CallPChainGetConnectedValidators(c.client)
+func (c *AvalancheUptimeClient) GetValidatorStatus(ctx context.Context) ([]*ValidatorStatus, database.UptimeCronjobStatus, error) {
+	validators, status, err := CallPChainGetConnectedValidators(ctx, c.client)
 	if err != nil {
 		return nil, status, err
 	}
@@ -62,11 +62,19 @@ type permissionedValidators struct {
 
 // Get connected validators from P-Chain, returns nil on error
 // Status is 0 if success, -1 on timeout, -2 on other error
-// Error is nil on succes or when rpc call fails in this case status is < 0
-func CallPChainGetConnectedValidators(client jsonrpc.RPCClient) ([]*api.PermissionedValidator, database.UptimeCronjobStatus, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), ConnectionTimeout)
-	defer cancel()
-	response, err := client.Call(ctx, "platform.getCurrentValidators")
+// Error is nil on succes or when rpc call fails in this case status is < 0.
+// Error is also returned if the parent context is cancelled.
+func CallPChainGetConnectedValidators(parentCtx context.Context, client jsonrpc.RPCClient) ([]*api.PermissionedValidator, database.UptimeCronjobStatus, error) {
+	var response *jsonrpc.RPCResponse
+	err := doRequest(parentCtx, func() (err error) {
+		ctx, cancel := context.WithTimeout(parentCtx, ConnectionTimeout)
+		defer cancel()
+		response, err = client.Call(ctx, "platform.getCurrentValidators")
+		return
+	})
+	if parentCtx.Err() != nil {
+		return nil, database.UptimeCronjobStatusTimeout, parentCtx.Err()
+	}
 
 	switch err.(type) {
 	case nil:
@@ -104,7 +112,7 @@ func NewRecordedUptimeClient(fileName string, startNow time.Time) (*RecordedUpti
 	}, nil
 }
 
-func (c *RecordedUptimeClient) GetValidatorStatus() ([]*ValidatorStatus, database.UptimeCronjobStatus, error) {
+func (c *RecordedUptimeClient) GetValidatorStatus(ctx context.Context) ([]*ValidatorStatus, database.UptimeCronjobStatus, error) {
 	now := c.Time.Now().Unix()
 	validatorMap := make(map[string]*ValidatorStatus)
 	for _, data := range c.data {
@@ -4,6 +4,7 @@
 package chain
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -18,7 +19,7 @@ func TestUptimeClient(t *testing.T) {
 
 	// List all validators at 2023-02-02 14:00:00 UTC
 	client.SetNow(time.Date(2023, time.February, 2, 14, 0, 0, 0, time.UTC))
-	validators, _, err := client.GetValidatorStatus()
+	validators, _, err := client.GetValidatorStatus(context.Background())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -28,7 +29,7 @@ func TestUptimeClient(t *testing.T) {
 
 	// Check if "NodeID-MFrZFVCXPv5iCn6M9K6XduxGTYp891xXZ" is not connected at 1676629054
 	client.SetNowUnix(1676629054)
-	validators, _, err = client.GetValidatorStatus()
+	validators, _, err = client.GetValidatorStatus(context.Background())
 	if err != nil {
 		t.Fatal(err)
 	}